@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/consul/testutil"
+	"github.com/hashicorp/consul/testutil/retry"
 	"github.com/hashicorp/serf/serf"
 )
 
@@ -28,6 +29,25 @@ func TestAgent_Self(t *testing.T) {
 	}
 }
 
+func TestAgent_Metrics(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	agent := c.Agent()
+
+	// The agent emits runtime metrics as soon as it starts.
+	retry.Run(t, func(r *retry.R) {
+		metrics, err := agent.Metrics()
+		if err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if len(metrics.Gauges) == 0 {
+			r.Fatalf("bad: %#v", metrics)
+		}
+	})
+}
+
 func TestAgent_Reload(t *testing.T) {
 	t.Parallel()
 
@@ -663,6 +683,46 @@ func TestAgent_Monitor(t *testing.T) {
 	}
 }
 
+func TestAgent_UpdateToken(t *testing.T) {
+	t.Parallel()
+	c, s := makeACLClient(t)
+	defer s.Stop()
+
+	agent := c.Agent()
+	if _, err := agent.UpdateACLToken("root", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := agent.UpdateACLAgentToken("root", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := agent.UpdateACLReplicationToken("root", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestAgent_MonitorJSON(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	agent := c.Agent()
+
+	logCh, err := agent.MonitorJSON("info", nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Wait for the first log message and validate it
+	select {
+	case log := <-logCh:
+		if !strings.Contains(log, `"@level":"info"`) {
+			t.Fatalf("bad: %q", log)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("failed to get a log message")
+	}
+}
+
 func TestServiceMaintenance(t *testing.T) {
 	t.Parallel()
 	c, s := makeClient(t)
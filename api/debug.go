@@ -0,0 +1,56 @@
+package api
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+// Debug can be used to query the /debug/pprof endpoints to gather
+// profiling information about the target agent. The agent must have
+// enable_debug set for these endpoints to be available.
+type Debug struct {
+	c *Client
+}
+
+// Debug returns a handle that exposes the internal debug endpoints.
+func (c *Client) Debug() *Debug {
+	return &Debug{c}
+}
+
+// Heap returns a pprof heap dump
+func (d *Debug) Heap() ([]byte, error) {
+	return d.profile("heap", nil)
+}
+
+// Goroutine returns a pprof goroutine profile
+func (d *Debug) Goroutine() ([]byte, error) {
+	return d.profile("goroutine", nil)
+}
+
+// Profile returns a pprof CPU profile taken over the given number of
+// seconds
+func (d *Debug) Profile(seconds int) ([]byte, error) {
+	return d.profile("profile", map[string]string{
+		"seconds": strconv.Itoa(seconds),
+	})
+}
+
+// profile fetches the named profile from the pprof endpoints.
+func (d *Debug) profile(name string, params map[string]string) ([]byte, error) {
+	r := d.c.newRequest("GET", "/debug/pprof/"+name)
+	for k, v := range params {
+		r.params.Set(k, v)
+	}
+	_, resp, err := requireOK(d.c.doRequest(r))
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %s", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding body: %s", err)
+	}
+	return body, nil
+}
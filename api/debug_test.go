@@ -0,0 +1,58 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/hashicorp/consul/testutil"
+)
+
+func TestDebug_Heap(t *testing.T) {
+	t.Parallel()
+	c, s := makeClientWithConfig(t, nil, func(conf *testutil.TestServerConfig) {
+		conf.EnableDebug = true
+	})
+	defer s.Stop()
+
+	debug := c.Debug()
+	raw, err := debug.Heap()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(raw) <= 0 {
+		t.Fatalf("no response: %#v", raw)
+	}
+
+	raw, err = debug.Goroutine()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(raw) <= 0 {
+		t.Fatalf("no response: %#v", raw)
+	}
+}
+
+func TestDebug_Profile(t *testing.T) {
+	t.Parallel()
+	c, s := makeClientWithConfig(t, nil, func(conf *testutil.TestServerConfig) {
+		conf.EnableDebug = true
+	})
+	defer s.Stop()
+
+	raw, err := c.Debug().Profile(1)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(raw) <= 0 {
+		t.Fatalf("no response: %#v", raw)
+	}
+}
+
+func TestDebug_Disabled(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	if _, err := c.Debug().Heap(); err == nil {
+		t.Fatalf("should have failed")
+	}
+}
@@ -20,6 +20,19 @@ type UserEvent struct {
 	TagFilter     string
 	Version       int
 	LTime         uint64
+	Durable       bool
+
+	// PayloadKey and PayloadChecksum are set when the payload was too
+	// large to gossip and was stored in the KV store instead.
+	PayloadKey      string
+	PayloadChecksum string
+}
+
+// DurableUserEvent is a durable user event recorded by the servers, along
+// with the nodes that have acknowledged it.
+type DurableUserEvent struct {
+	UserEvent
+	Acks []string
 }
 
 // Event returns a handle to the event endpoints
@@ -42,6 +55,9 @@ func (e *Event) Fire(params *UserEvent, q *WriteOptions) (string, *WriteMeta, er
 	if params.TagFilter != "" {
 		r.params.Set("tag", params.TagFilter)
 	}
+	if params.Durable {
+		r.params.Set("durable", "")
+	}
 	if params.Payload != nil {
 		r.body = bytes.NewReader(params.Payload)
 	}
@@ -87,6 +103,34 @@ func (e *Event) List(name string, q *QueryOptions) ([]*UserEvent, *QueryMeta, er
 	return entries, qm, nil
 }
 
+// ListDurable is used to get the durable events recorded by the servers that
+// are still within their retention window, along with the nodes that have
+// acknowledged each one. This list can be optionally filtered by the name.
+// Unlike List, this is a regular blocking query.
+func (e *Event) ListDurable(name string, q *QueryOptions) ([]*DurableUserEvent, *QueryMeta, error) {
+	r := e.c.newRequest("GET", "/v1/event/list")
+	r.setQueryOptions(q)
+	r.params.Set("durable", "")
+	if name != "" {
+		r.params.Set("name", name)
+	}
+	rtt, resp, err := requireOK(e.c.doRequest(r))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	qm := &QueryMeta{}
+	parseQueryMeta(resp, qm)
+	qm.RequestTime = rtt
+
+	var entries []*DurableUserEvent
+	if err := decodeBody(resp, &entries); err != nil {
+		return nil, nil, err
+	}
+	return entries, qm, nil
+}
+
 // IDToIndex is a bit of a hack. This simulates the index generation to
 // convert an event ID into a WaitIndex.
 func (e *Event) IDToIndex(uuid string) uint64 {
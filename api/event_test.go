@@ -48,3 +48,36 @@ func TestEvent_FireList(t *testing.T) {
 		t.Fatalf("Bad: %#v", qm)
 	}
 }
+
+func TestEvent_FireListDurable(t *testing.T) {
+	t.Parallel()
+	c, s := makeClient(t)
+	defer s.Stop()
+
+	event := c.Event()
+
+	params := &UserEvent{Name: "foo", Durable: true}
+	id, _, err := event.Fire(params, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	retry.Run(t, func(r *retry.R) {
+		events, qm, err := event.ListDurable("foo", nil)
+		if err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if len(events) != 1 {
+			r.Fatalf("bad: %#v", events)
+		}
+		if events[0].ID != id || !events[0].Durable {
+			r.Fatalf("bad: %#v", events[0])
+		}
+		if len(events[0].Acks) != 1 {
+			r.Fatalf("bad: %#v", events[0].Acks)
+		}
+		if qm.LastIndex == 0 {
+			r.Fatalf("bad: %#v", qm)
+		}
+	})
+}
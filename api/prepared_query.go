@@ -1,7 +1,7 @@
 package api
 
-// QueryDatacenterOptions sets options about how we fail over if there are no
-// healthy nodes in the local datacenter.
+// QueryDatacenterOptions sets options about how we fail over if there are not
+// enough healthy nodes in the local datacenter.
 type QueryDatacenterOptions struct {
 	// NearestN is set to the number of remote datacenters to try, based on
 	// network coordinates.
@@ -11,6 +11,27 @@ type QueryDatacenterOptions struct {
 	// never try a datacenter multiple times, so those are subtracted from
 	// this list before proceeding.
 	Datacenters []string
+
+	// Targets is an explicit, ordered list of places to fail over to. Each
+	// target can name a datacenter, an alternative service, or both, so
+	// this can't be combined with NearestN or Datacenters.
+	Targets []QueryFailoverTarget
+
+	// MinHealthy is the number of healthy nodes a result needs before we
+	// stop failing over. If this is 0 then we only fail over when there
+	// are no healthy nodes at all.
+	MinHealthy int
+}
+
+// QueryFailoverTarget is a single place to try when failing over.
+type QueryFailoverTarget struct {
+	// Datacenter is the datacenter to query. If this is blank then the
+	// local datacenter is used.
+	Datacenter string
+
+	// Service is an alternative service to query. If this is blank then
+	// the query's service is used.
+	Service string
 }
 
 // QueryDNSOptions controls settings when query results are served over DNS.
@@ -30,8 +51,8 @@ type ServiceQuery struct {
 	// the agent which initiated the request by default.
 	Near string
 
-	// Failover controls what we do if there are no healthy nodes in the
-	// local datacenter.
+	// Failover controls what we do if there are not enough healthy nodes
+	// in the local datacenter.
 	Failover QueryDatacenterOptions
 
 	// If OnlyPassing is true then we will only include nodes with passing
@@ -114,6 +135,34 @@ type PreparedQueryExecuteResponse struct {
 	Failovers int
 }
 
+// QueryTargetExplanation records what happened when a prepared query tried a
+// given target during failover.
+type QueryTargetExplanation struct {
+	// Datacenter and Service identify the target that was tried.
+	Datacenter string
+	Service    string
+
+	// Healthy is the number of healthy nodes the target returned.
+	Healthy int
+
+	// Chosen is set for the target whose results were returned.
+	Chosen bool
+
+	// Reason is a human-readable description of why the target was or
+	// wasn't chosen.
+	Reason string
+}
+
+// PreparedQueryExplainResponse has the results of explaining a query.
+type PreparedQueryExplainResponse struct {
+	// Query has the fully-rendered query.
+	Query PreparedQueryDefinition
+
+	// Targets has the local datacenter followed by any failover targets
+	// that were tried when the query was executed for this explanation.
+	Targets []QueryTargetExplanation
+}
+
 // PreparedQuery can be used to query the prepared query endpoints.
 type PreparedQuery struct {
 	c *Client
@@ -196,3 +245,15 @@ func (c *PreparedQuery) Execute(queryIDOrName string, q *QueryOptions) (*Prepare
 	}
 	return out, qm, nil
 }
+
+// Explain is used to see how a prepared query would be executed, including
+// the fully-rendered query if it's a template. You can explain using a query
+// ID or name.
+func (c *PreparedQuery) Explain(queryIDOrName string, q *QueryOptions) (*PreparedQueryExplainResponse, *QueryMeta, error) {
+	var out *PreparedQueryExplainResponse
+	qm, err := c.c.query("/v1/query/"+queryIDOrName+"/explain", &out, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	return out, qm, nil
+}
@@ -116,6 +116,16 @@ func TestPreparedQuery(t *testing.T) {
 		t.Fatalf("bad datacenter: %v", results)
 	}
 
+	// Explain by name.
+	explain, _, err := query.Explain("my-query", nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if explain.Query.ID != def.ID || len(explain.Targets) != 1 ||
+		!explain.Targets[0].Chosen || explain.Targets[0].Healthy != 1 {
+		t.Fatalf("bad: %v", explain)
+	}
+
 	// Delete it.
 	_, err = query.Delete(def.ID, nil)
 	if err != nil {
@@ -0,0 +1,44 @@
+package command
+
+import (
+	"strings"
+
+	"github.com/hashicorp/consul/command/base"
+	"github.com/mitchellh/cli"
+)
+
+// ACLCommand is a Command implementation that just shows help for
+// the subcommands nested below it.
+type ACLCommand struct {
+	base.Command
+}
+
+func (c *ACLCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *ACLCommand) Help() string {
+	helpText := `
+Usage: consul acl <subcommand> [options] [args]
+
+  This command has subcommands for managing the ACL tokens used by a
+  running Consul agent. Here are some simple examples, and more detailed
+  examples are available in the subcommands or the documentation.
+
+  Set the token the agent uses for its own internal operations:
+
+      $ consul acl set-agent-token agent 4b0c9d3a-...
+
+  Set the token used for requests that don't supply one:
+
+      $ consul acl set-agent-token default 8f2e1a7c-...
+
+  For more examples, ask for subcommand help or view the documentation.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLCommand) Synopsis() string {
+	return "Interact with the agent's ACL tokens"
+}
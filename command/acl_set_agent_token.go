@@ -0,0 +1,89 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+)
+
+// ACLSetAgentTokenCommand is a Command implementation that updates one of
+// the ACL tokens used by a running agent.
+type ACLSetAgentTokenCommand struct {
+	base.Command
+}
+
+func (c *ACLSetAgentTokenCommand) Help() string {
+	helpText := `
+Usage: consul acl set-agent-token [options] TYPE TOKEN
+
+  Updates one of the ACL tokens used by a running Consul agent, without
+  restarting it. TYPE is one of:
+
+    default      The token used for requests that don't supply one. This is
+                 the "acl_token" configuration option.
+
+    agent        The token used for the agent's own internal operations. This
+                 is the "acl_agent_token" configuration option.
+
+    replication  The token used by servers to replicate ACLs from the ACL
+                 datacenter. This is the "acl_replication_token"
+                 configuration option.
+
+  The new token only lasts until the agent restarts, unless the agent has
+  "acl_enable_token_persistence" set, in which case it is saved in the
+  agent's data directory. This requires "agent" write permissions.
+
+` + c.Command.Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *ACLSetAgentTokenCommand) Run(args []string) int {
+	f := c.Command.NewFlagSet(c)
+	if err := c.Command.Parse(args); err != nil {
+		return 1
+	}
+
+	args = f.Args()
+	if len(args) != 2 {
+		c.UI.Error("A token type and a token must be specified.")
+		c.UI.Error("")
+		c.UI.Error(c.Help())
+		return 1
+	}
+	tokenType, token := args[0], args[1]
+
+	client, err := c.Command.HTTPClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	agent := client.Agent()
+	var update func(string, *api.WriteOptions) (*api.WriteMeta, error)
+	switch tokenType {
+	case "default":
+		update = agent.UpdateACLToken
+	case "agent":
+		update = agent.UpdateACLAgentToken
+	case "replication":
+		update = agent.UpdateACLReplicationToken
+	default:
+		c.UI.Error(fmt.Sprintf("Unknown token type %q", tokenType))
+		return 1
+	}
+
+	if _, err := update(token, nil); err != nil {
+		c.UI.Error(fmt.Sprintf("Error updating token: %s", err))
+		return 1
+	}
+
+	c.UI.Output(fmt.Sprintf("Updated agent's %s ACL token", tokenType))
+	return 0
+}
+
+func (c *ACLSetAgentTokenCommand) Synopsis() string {
+	return "Updates one of the agent's ACL tokens"
+}
@@ -0,0 +1,58 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/command/base"
+	"github.com/mitchellh/cli"
+)
+
+func testACLSetAgentTokenCommand(t *testing.T) (*cli.MockUi, *ACLSetAgentTokenCommand) {
+	ui := new(cli.MockUi)
+	return ui, &ACLSetAgentTokenCommand{
+		Command: base.Command{
+			UI:    ui,
+			Flags: base.FlagSetClientHTTP,
+		},
+	}
+}
+
+func TestACLSetAgentTokenCommand_implements(t *testing.T) {
+	var _ cli.Command = &ACLSetAgentTokenCommand{}
+}
+
+func TestACLSetAgentTokenCommand_BadArgs(t *testing.T) {
+	ui, c := testACLSetAgentTokenCommand(t)
+	if code := c.Run([]string{"agent"}); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "must be specified") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
+func TestACLSetAgentTokenCommand_Run(t *testing.T) {
+	a1 := testAgent(t)
+	defer a1.Shutdown()
+
+	for _, tokenType := range []string{"default", "agent", "replication"} {
+		ui, c := testACLSetAgentTokenCommand(t)
+		args := []string{"-http-addr=" + a1.httpAddr, tokenType, "secret"}
+		if code := c.Run(args); code != 0 {
+			t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+		}
+		if !strings.Contains(ui.OutputWriter.String(), "Updated agent's "+tokenType) {
+			t.Fatalf("bad: %s", ui.OutputWriter.String())
+		}
+	}
+
+	ui, c := testACLSetAgentTokenCommand(t)
+	args := []string{"-http-addr=" + a1.httpAddr, "nope", "secret"}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Unknown token type") {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// Path to save ACL tokens set through the agent's token endpoint
+	tokensPath = "acl-tokens.json"
+
+	// The kinds of ACL token that can be updated at runtime
+	tokenKindUser        = "acl_token"
+	tokenKindAgent       = "acl_agent_token"
+	tokenKindReplication = "acl_replication_token"
+)
+
+// persistedTokens is used to save the ACL tokens that were set through the
+// agent's token endpoint, so they survive a restart.
+type persistedTokens struct {
+	ACLToken            string `json:"acl_token,omitempty"`
+	ACLAgentToken       string `json:"acl_agent_token,omitempty"`
+	ACLReplicationToken string `json:"acl_replication_token,omitempty"`
+}
+
+// tokenPersistenceEnabled returns true if tokens set at runtime should be
+// saved to the data dir.
+func (a *Agent) tokenPersistenceEnabled() bool {
+	return a.config.ACLEnableTokenPersistence && a.config.DataDir != ""
+}
+
+// readPersistedTokens reads the saved ACL tokens from the data dir. A
+// missing file isn't an error.
+func (a *Agent) readPersistedTokens() (*persistedTokens, error) {
+	var tokens persistedTokens
+	buf, err := ioutil.ReadFile(filepath.Join(a.config.DataDir, tokensPath))
+	if os.IsNotExist(err) {
+		return &tokens, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, &tokens); err != nil {
+		return nil, fmt.Errorf("failed decoding persisted tokens: %v", err)
+	}
+	return &tokens, nil
+}
+
+// loadPersistedTokens loads any saved ACL tokens into the token store. They
+// take precedence over the tokens in the configuration.
+func (a *Agent) loadPersistedTokens() error {
+	if !a.tokenPersistenceEnabled() {
+		return nil
+	}
+
+	tokens, err := a.readPersistedTokens()
+	if err != nil {
+		return err
+	}
+	if tokens.ACLToken != "" {
+		a.tokens.UpdateUserToken(tokens.ACLToken)
+	}
+	if tokens.ACLAgentToken != "" {
+		a.tokens.UpdateAgentToken(tokens.ACLAgentToken)
+	}
+	if tokens.ACLReplicationToken != "" {
+		a.tokens.UpdateACLReplicationToken(tokens.ACLReplicationToken)
+	}
+	return nil
+}
+
+// updateToken replaces one of the agent's ACL tokens at runtime, saving it
+// to the data dir if token persistence is enabled.
+func (a *Agent) updateToken(kind, token string) error {
+	a.tokensLock.Lock()
+	defer a.tokensLock.Unlock()
+
+	var tokens *persistedTokens
+	if a.tokenPersistenceEnabled() {
+		var err error
+		if tokens, err = a.readPersistedTokens(); err != nil {
+			return err
+		}
+	} else {
+		tokens = new(persistedTokens)
+	}
+
+	var update func(string)
+	switch kind {
+	case tokenKindUser:
+		tokens.ACLToken = token
+		update = a.tokens.UpdateUserToken
+	case tokenKindAgent:
+		tokens.ACLAgentToken = token
+		update = a.tokens.UpdateAgentToken
+	case tokenKindReplication:
+		tokens.ACLReplicationToken = token
+		update = a.tokens.UpdateACLReplicationToken
+	default:
+		return fmt.Errorf("unknown token kind %q", kind)
+	}
+
+	// Save the token before using it, so a failed write doesn't leave us
+	// running with a token that would be lost on restart.
+	if a.tokenPersistenceEnabled() {
+		encoded, err := json.Marshal(tokens)
+		if err != nil {
+			return err
+		}
+		if err := writeFileAtomic(filepath.Join(a.config.DataDir, tokensPath), encoded); err != nil {
+			return fmt.Errorf("failed persisting tokens: %v", err)
+		}
+	}
+	update(token)
+
+	// Kick off anti-entropy so anything that failed to sync with the old
+	// token gets another try.
+	a.state.changeMade()
+	return nil
+}
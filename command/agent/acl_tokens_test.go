@@ -0,0 +1,70 @@
+package agent
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAgent_updateToken_Persistence(t *testing.T) {
+	conf := nextConfig()
+	conf.ACLToken = "config-user"
+	conf.ACLAgentToken = "config-agent"
+	conf.ACLEnableTokenPersistence = true
+	dir, a := makeAgent(t, conf)
+	defer os.RemoveAll(dir)
+
+	if err := a.updateToken(tokenKindAgent, "agent"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := a.updateToken(tokenKindReplication, "replication"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := a.updateToken("nope", "nope"); err == nil {
+		t.Fatalf("should have failed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, tokensPath)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	a.Shutdown()
+
+	// A new agent using the same data dir should pick up the saved tokens
+	// over the ones in its config, but keep config tokens that weren't
+	// updated.
+	conf2 := nextConfig()
+	conf2.ACLToken = "config-user"
+	conf2.ACLAgentToken = "config-agent"
+	conf2.ACLEnableTokenPersistence = true
+	conf2.DataDir = dir
+	a2, err := Create(conf2, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer a2.Shutdown()
+
+	if got, want := a2.tokens.UserToken(), "config-user"; got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if got, want := a2.tokens.AgentToken(), "agent"; got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if got, want := a2.tokens.ACLReplicationToken(), "replication"; got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+}
+
+func TestAgent_updateToken_NoPersistence(t *testing.T) {
+	dir, a := makeAgent(t, nextConfig())
+	defer os.RemoveAll(dir)
+	defer a.Shutdown()
+
+	if err := a.updateToken(tokenKindUser, "user"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got, want := a.tokens.UserToken(), "user"; got != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, tokensPath)); !os.IsNotExist(err) {
+		t.Fatalf("err: %v", err)
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/consul"
 	"github.com/hashicorp/consul/consul/state"
@@ -25,6 +26,8 @@ import (
 	"github.com/hashicorp/consul/ipaddr"
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/consul/logger"
+	"github.com/hashicorp/consul/snapshot"
+	"github.com/hashicorp/consul/token"
 	"github.com/hashicorp/consul/types"
 	"github.com/hashicorp/go-sockaddr/template"
 	"github.com/hashicorp/go-uuid"
@@ -61,6 +64,7 @@ type clientServer interface {
 	LANMembers() []serf.Member
 	LocalMember() serf.Member
 	JoinLAN(addrs []string) (n int, err error)
+	ReloadTLS() error
 	RemoveFailedNode(node string) error
 	RPC(method string, args interface{}, reply interface{}) error
 	SnapshotRPC(args *structs.SnapshotRequest, in io.Reader, out io.Writer, replyFn consul.SnapshotReplyFn) error
@@ -86,6 +90,10 @@ type Agent struct {
 	// Used for streaming logs to
 	logWriter *logger.LogWriter
 
+	// metricsSink holds recent metrics in memory so they can be served
+	// by the HTTP API. This is nil if metrics aren't available.
+	metricsSink *metrics.InmemSink
+
 	// delegate is either a *consul.Server or *consul.Client
 	// depending on the configuration
 	delegate clientServer
@@ -133,6 +141,9 @@ type Agent struct {
 
 	reloadCh chan chan error
 
+	// queryCache holds recent prepared query results, if it's enabled.
+	queryCache *preparedQueryCache
+
 	shutdown     bool
 	shutdownCh   chan struct{}
 	shutdownLock sync.Mutex
@@ -141,6 +152,15 @@ type Agent struct {
 	// agent methods use this, so use with care and never override
 	// outside of a unit test.
 	endpoints map[string]string
+
+	// tokens holds ACL tokens initially from the configuration, but can
+	// be updated at runtime, so should always be used instead of going to
+	// the configuration directly.
+	tokens *token.Store
+
+	// tokensLock serializes updates to the tokens made through the agent's
+	// token endpoint, along with their persisted copies.
+	tokensLock sync.Mutex
 }
 
 // Create is used to create a new Agent. Returns
@@ -175,6 +195,7 @@ func Create(config *Config, logOutput io.Writer, logWriter *logger.LogWriter, re
 		reloadCh:       reloadCh,
 		shutdownCh:     make(chan struct{}),
 		endpoints:      make(map[string]string),
+		tokens:         new(token.Store),
 	}
 	if err := agent.resolveTmplAddrs(); err != nil {
 		return nil, err
@@ -193,8 +214,16 @@ func Create(config *Config, logOutput io.Writer, logWriter *logger.LogWriter, re
 		return nil, fmt.Errorf("Failed to setup node ID: %v", err)
 	}
 
+	// Set up the initial state of the token store based on the config.
+	agent.tokens.UpdateUserToken(config.ACLToken)
+	agent.tokens.UpdateAgentToken(config.ACLAgentToken)
+	agent.tokens.UpdateACLReplicationToken(config.ACLReplicationToken)
+	if err := agent.loadPersistedTokens(); err != nil {
+		return nil, fmt.Errorf("Failed to load persisted ACL tokens: %v", err)
+	}
+
 	// Initialize the local state.
-	agent.state.Init(config, agent.logger)
+	agent.state.Init(config, agent.tokens, agent.logger)
 
 	// Setup either the client or the server.
 	if config.Server {
@@ -209,7 +238,7 @@ func Create(config *Config, logOutput io.Writer, logWriter *logger.LogWriter, re
 			Tags:    []string{},
 		}
 
-		agent.state.AddService(&consulService, agent.config.GetTokenForAgent())
+		agent.state.AddService(&consulService, agent.tokens.AgentToken())
 	} else {
 		err = agent.setupClient()
 		agent.state.SetIface(agent.delegate)
@@ -229,6 +258,12 @@ func Create(config *Config, logOutput io.Writer, logWriter *logger.LogWriter, re
 		return nil, err
 	}
 
+	// Set up the prepared query result cache, if it's enabled.
+	if config.PreparedQueryCacheTTL > 0 {
+		agent.queryCache = newPreparedQueryCache(config.PreparedQueryCacheTTL,
+			agent.RPC, agent.logger, agent.shutdownCh)
+	}
+
 	// Start watching for critical services to deregister, based on their
 	// checks.
 	go agent.reapServices()
@@ -236,6 +271,9 @@ func Create(config *Config, logOutput io.Writer, logWriter *logger.LogWriter, re
 	// Start handling events.
 	go agent.handleEvents()
 
+	// Catch up on any durable events we missed while we were down.
+	go agent.catchUpDurableEvents()
+
 	// Start sending network coordinate to the server.
 	if !config.DisableCoordinates {
 		go agent.sendCoordinate()
@@ -268,6 +306,9 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 	if a.config.Performance.RaftMultiplier > 0 {
 		base.ScaleRaft(a.config.Performance.RaftMultiplier)
 	}
+	if a.config.Performance.FollowerReads {
+		base.FollowerReads = true
+	}
 
 	// Override with our config
 	if a.config.Datacenter != "" {
@@ -421,9 +462,19 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 	if a.config.ACLEnforceVersion8 != nil {
 		base.ACLEnforceVersion8 = *a.config.ACLEnforceVersion8
 	}
+	if a.config.DurableEventRetention != 0 {
+		base.DurableEventRetention = a.config.DurableEventRetention
+	}
 	if a.config.SessionTTLMinRaw != "" {
 		base.SessionTTLMin = a.config.SessionTTLMin
 	}
+	if a.config.SnapshotEncryptionKeyFile != "" {
+		key, err := snapshot.ReadKeyFile(a.config.SnapshotEncryptionKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		base.SnapshotEncryptionKey = key
+	}
 	if a.config.Autopilot.CleanupDeadServers != nil {
 		base.AutopilotConfig.CleanupDeadServers = *a.config.Autopilot.CleanupDeadServers
 	}
@@ -436,8 +487,8 @@ func (a *Agent) consulConfig() (*consul.Config, error) {
 	if a.config.Autopilot.ServerStabilizationTime != nil {
 		base.AutopilotConfig.ServerStabilizationTime = *a.config.Autopilot.ServerStabilizationTime
 	}
-	if a.config.NonVotingServer {
-		base.NonVoter = a.config.NonVotingServer
+	if a.config.ReadReplica || a.config.NonVotingServer {
+		base.NonVoter = true
 	}
 	if a.config.Autopilot.RedundancyZoneTag != "" {
 		base.AutopilotConfig.RedundancyZoneTag = a.config.Autopilot.RedundancyZoneTag
@@ -613,7 +664,7 @@ func (a *Agent) setupServer() error {
 	if err := a.setupKeyrings(config); err != nil {
 		return fmt.Errorf("Failed to configure keyring: %v", err)
 	}
-	server, err := consul.NewServer(config)
+	server, err := consul.NewServerTokens(config, a.tokens)
 	if err != nil {
 		return fmt.Errorf("Failed to start Consul server: %v", err)
 	}
@@ -808,6 +859,11 @@ func (a *Agent) SnapshotRPC(args *structs.SnapshotRequest, in io.Reader, out io.
 	return a.delegate.SnapshotRPC(args, in, out, replyFn)
 }
 
+// ReloadTLS re-reads the TLS material used for RPC connections.
+func (a *Agent) ReloadTLS() error {
+	return a.delegate.ReloadTLS()
+}
+
 // Leave is used to prepare the agent for a graceful shutdown
 func (a *Agent) Leave() error {
 	return a.delegate.Leave()
@@ -964,7 +1020,7 @@ func (a *Agent) sendCoordinate() {
 				Datacenter:   a.config.Datacenter,
 				Node:         a.config.NodeName,
 				Coord:        c,
-				WriteRequest: structs.WriteRequest{Token: a.config.GetTokenForAgent()},
+				WriteRequest: structs.WriteRequest{Token: a.tokens.AgentToken()},
 			}
 			var reply struct{}
 			if err := a.RPC("Coordinate.Update", &req, &reply); err != nil {
@@ -45,8 +45,12 @@ func (s *HTTPServer) AgentSelf(resp http.ResponseWriter, req *http.Request) (int
 		return nil, errPermissionDenied
 	}
 
+	// Hide any credentials given to the retry join providers.
+	config := *s.agent.config
+	config.RetryJoin = redactRetryJoin(config.RetryJoin)
+
 	return Self{
-		Config: s.agent.config,
+		Config: &config,
 		Coord:  c,
 		Member: s.agent.LocalMember(),
 		Stats:  s.agent.Stats(),
@@ -54,6 +58,45 @@ func (s *HTTPServer) AgentSelf(resp http.ResponseWriter, req *http.Request) (int
 	}, nil
 }
 
+// AgentMetrics returns the agent's recent in-memory metrics, as JSON or in
+// the Prometheus text format with ?format=prometheus.
+func (s *HTTPServer) AgentMetrics(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Only GET supported.
+	if req.Method != "GET" {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return nil, nil
+	}
+
+	// Fetch the ACL token, if any, and enforce agent policy.
+	var token string
+	s.parseToken(req, &token)
+	acl, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if acl != nil && !acl.AgentRead(s.agent.config.NodeName) {
+		return nil, errPermissionDenied
+	}
+
+	if s.agent.metricsSink == nil {
+		return nil, fmt.Errorf("Metrics are not available")
+	}
+	summary := summarizeMetrics(s.agent.metricsSink)
+
+	switch format := req.URL.Query().Get("format"); format {
+	case "":
+		return summary, nil
+	case "prometheus":
+		resp.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		resp.Write(encodePrometheus(summary))
+		return nil, nil
+	default:
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "Unsupported format %q", format)
+		return nil, nil
+	}
+}
+
 func (s *HTTPServer) AgentReload(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	if req.Method != "PUT" {
 		resp.WriteHeader(http.StatusMethodNotAllowed)
@@ -88,6 +131,47 @@ func (s *HTTPServer) AgentReload(resp http.ResponseWriter, req *http.Request) (i
 	}
 }
 
+// AgentToken updates one of the agent's ACL tokens at runtime.
+func (s *HTTPServer) AgentToken(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	if req.Method != "PUT" {
+		resp.WriteHeader(http.StatusMethodNotAllowed)
+		return nil, nil
+	}
+
+	// Fetch the ACL token, if any, and enforce agent policy.
+	var token string
+	s.parseToken(req, &token)
+	acl, err := s.agent.resolveToken(token)
+	if err != nil {
+		return nil, err
+	}
+	if acl != nil && !acl.AgentWrite(s.agent.config.NodeName) {
+		return nil, errPermissionDenied
+	}
+
+	kind := strings.TrimPrefix(req.URL.Path, "/v1/agent/token/")
+	switch kind {
+	case tokenKindUser, tokenKindAgent, tokenKindReplication:
+	default:
+		resp.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(resp, "Token %q is unknown", kind)
+		return nil, nil
+	}
+
+	var args api.AgentToken
+	if err := decodeBody(req, &args, nil); err != nil {
+		resp.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(resp, "Request decode failed: %v", err)
+		return nil, nil
+	}
+
+	if err := s.agent.updateToken(kind, args.Token); err != nil {
+		return nil, err
+	}
+	s.agent.logger.Printf("[INFO] agent: Updated agent's ACL token %q", kind)
+	return nil, nil
+}
+
 func (s *HTTPServer) AgentServices(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
 	// Fetch the ACL token, if any.
 	var token string
@@ -637,6 +721,9 @@ func (s *HTTPServer) AgentMonitor(resp http.ResponseWriter, req *http.Request) (
 	// Upper case the level since that's required by the filter.
 	logLevel = strings.ToUpper(logLevel)
 
+	// Check if the logs should be formatted as JSON.
+	_, logJSON := req.URL.Query()["logjson"]
+
 	// Create a level filter and flusher.
 	filter := logger.LevelFilter()
 	filter.MinLevel = logutils.LogLevel(logLevel)
@@ -670,6 +757,9 @@ func (s *HTTPServer) AgentMonitor(resp http.ResponseWriter, req *http.Request) (
 			}
 			return nil, nil
 		case log := <-handler.logCh:
+			if logJSON {
+				log = string(logger.FormatJSON([]byte(log)))
+			}
 			fmt.Fprintln(resp, log)
 			flusher.Flush()
 		}
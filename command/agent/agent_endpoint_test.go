@@ -13,10 +13,12 @@ import (
 	"testing"
 	"time"
 
+	"github.com/armon/go-metrics"
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/base"
 	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/consul/logger"
+	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/consul/testutil"
 	"github.com/hashicorp/consul/testutil/retry"
 	"github.com/hashicorp/consul/types"
@@ -172,6 +174,7 @@ func TestAgent_Self(t *testing.T) {
 	}
 	dir, srv := makeHTTPServerWithConfig(t, func(conf *Config) {
 		conf.Meta = meta
+		conf.RetryJoin = []string{"provider=aws tag_key=a tag_value=b secret_access_key=sekrit"}
 	})
 	defer os.RemoveAll(dir)
 	defer srv.Shutdown()
@@ -211,6 +214,11 @@ func TestAgent_Self(t *testing.T) {
 	if bytes.Contains(bytes.ToLower(raw), []byte("token")) {
 		t.Fatalf("bad: %s", raw)
 	}
+
+	// Credentials given to retry join providers are hidden.
+	if bytes.Contains(raw, []byte("sekrit")) || !bytes.Contains(raw, []byte("secret_access_key=hidden")) {
+		t.Fatalf("bad: %s", raw)
+	}
 }
 
 func TestAgent_Self_ACLDeny(t *testing.T) {
@@ -242,6 +250,111 @@ func TestAgent_Self_ACLDeny(t *testing.T) {
 	})
 }
 
+func TestAgent_Metrics(t *testing.T) {
+	dir, srv := makeHTTPServer(t)
+	defer os.RemoveAll(dir)
+	defer srv.Shutdown()
+	defer srv.agent.Shutdown()
+
+	// Metrics aren't available without a sink.
+	req, _ := http.NewRequest("GET", "/v1/agent/metrics", nil)
+	if _, err := srv.AgentMetrics(httptest.NewRecorder(), req); err == nil {
+		t.Fatalf("expected an error")
+	}
+
+	sink := metrics.NewInmemSink(10*time.Second, time.Minute)
+	sink.SetGauge([]string{"consul", "test", "gauge"}, 42)
+	sink.IncrCounter([]string{"consul", "test", "counter"}, 3)
+	sink.AddSample([]string{"consul", "test", "sample"}, 1.5)
+	srv.agent.metricsSink = sink
+
+	t.Run("json", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/metrics", nil)
+		obj, err := srv.AgentMetrics(httptest.NewRecorder(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		summary := obj.(*MetricsSummary)
+		if len(summary.Gauges) != 1 || summary.Gauges[0].Name != "consul.test.gauge" ||
+			summary.Gauges[0].Value != 42 {
+			t.Fatalf("bad: %#v", summary.Gauges)
+		}
+		if len(summary.Counters) != 1 || summary.Counters[0].Sum != 3 {
+			t.Fatalf("bad: %#v", summary.Counters)
+		}
+		if len(summary.Samples) != 1 || summary.Samples[0].Mean != 1.5 {
+			t.Fatalf("bad: %#v", summary.Samples)
+		}
+	})
+
+	t.Run("prometheus", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/metrics?format=prometheus", nil)
+		resp := httptest.NewRecorder()
+		obj, err := srv.AgentMetrics(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if obj != nil {
+			t.Fatalf("bad: %#v", obj)
+		}
+		if ct := resp.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain") {
+			t.Fatalf("bad: %q", ct)
+		}
+		body := resp.Body.String()
+		for _, line := range []string{
+			"# TYPE consul_test_gauge gauge",
+			"consul_test_gauge 42",
+			"consul_test_counter_sum 3",
+			"consul_test_sample_mean 1.5",
+		} {
+			if !strings.Contains(body, line+"\n") {
+				t.Fatalf("missing %q in %s", line, body)
+			}
+		}
+	})
+
+	t.Run("bad format", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/metrics?format=nope", nil)
+		resp := httptest.NewRecorder()
+		if _, err := srv.AgentMetrics(resp, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if resp.Code != http.StatusBadRequest {
+			t.Fatalf("bad: %d", resp.Code)
+		}
+	})
+}
+
+func TestAgent_Metrics_ACLDeny(t *testing.T) {
+	dir, srv := makeHTTPServerWithACLs(t)
+	defer os.RemoveAll(dir)
+	defer srv.Shutdown()
+	defer srv.agent.Shutdown()
+	srv.agent.metricsSink = metrics.NewInmemSink(10*time.Second, time.Minute)
+
+	t.Run("no token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/metrics", nil)
+		if _, err := srv.AgentMetrics(nil, req); !isPermissionDenied(err) {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	t.Run("agent master token", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/metrics?token=towel", nil)
+		if _, err := srv.AgentMetrics(nil, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	t.Run("read-only token", func(t *testing.T) {
+		ro := makeReadOnlyAgentACL(t, srv)
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/v1/agent/metrics?token=%s", ro), nil)
+		if _, err := srv.AgentMetrics(nil, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
 func TestAgent_Reload(t *testing.T) {
 	conf := nextConfig()
 	tmpDir := testutil.TempDir(t, "consul")
@@ -337,6 +450,151 @@ func TestAgent_Reload_ACLDeny(t *testing.T) {
 	// repeating again here.
 }
 
+func TestAgent_Token(t *testing.T) {
+	dir, srv := makeHTTPServerWithACLs(t)
+	defer os.RemoveAll(dir)
+	defer srv.Shutdown()
+	defer srv.agent.Shutdown()
+
+	b := func(token string) io.Reader {
+		return jsonReader(&api.AgentToken{Token: token})
+	}
+
+	tests := []struct {
+		name  string
+		kind  string
+		check func(t *testing.T)
+	}{
+		{"user", "acl_token", func(t *testing.T) {
+			if got, want := srv.agent.tokens.UserToken(), "user"; got != want {
+				t.Fatalf("got %q want %q", got, want)
+			}
+		}},
+		{"agent", "acl_agent_token", func(t *testing.T) {
+			if got, want := srv.agent.tokens.AgentToken(), "agent"; got != want {
+				t.Fatalf("got %q want %q", got, want)
+			}
+		}},
+		{"replication", "acl_replication_token", func(t *testing.T) {
+			if got, want := srv.agent.tokens.ACLReplicationToken(), "replication"; got != want {
+				t.Fatalf("got %q want %q", got, want)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := fmt.Sprintf("/v1/agent/token/%s?token=root", tt.kind)
+			req, _ := http.NewRequest("PUT", url, b(tt.name))
+			resp := httptest.NewRecorder()
+			if _, err := srv.AgentToken(resp, req); err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			if got, want := resp.Code, 200; got != want {
+				t.Fatalf("got %d want %d", got, want)
+			}
+			tt.check(t)
+		})
+	}
+
+	t.Run("unknown kind", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/v1/agent/token/nope?token=root", b("nope"))
+		resp := httptest.NewRecorder()
+		if _, err := srv.AgentToken(resp, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if got, want := resp.Code, 404; got != want {
+			t.Fatalf("got %d want %d", got, want)
+		}
+	})
+
+	t.Run("bad method", func(t *testing.T) {
+		req, _ := http.NewRequest("GET", "/v1/agent/token/acl_token?token=root", nil)
+		resp := httptest.NewRecorder()
+		if _, err := srv.AgentToken(resp, req); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if got, want := resp.Code, 405; got != want {
+			t.Fatalf("got %d want %d", got, want)
+		}
+	})
+}
+
+func TestAgent_Token_ACLDeny(t *testing.T) {
+	dir, srv := makeHTTPServerWithACLs(t)
+	defer os.RemoveAll(dir)
+	defer srv.Shutdown()
+	defer srv.agent.Shutdown()
+
+	t.Run("no token", func(t *testing.T) {
+		req, _ := http.NewRequest("PUT", "/v1/agent/token/acl_token", jsonReader(&api.AgentToken{Token: "x"}))
+		if _, err := srv.AgentToken(nil, req); !isPermissionDenied(err) {
+			t.Fatalf("err: %v", err)
+		}
+	})
+
+	t.Run("read-only token", func(t *testing.T) {
+		ro := makeReadOnlyAgentACL(t, srv)
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/v1/agent/token/acl_token?token=%s", ro), jsonReader(&api.AgentToken{Token: "x"}))
+		if _, err := srv.AgentToken(nil, req); !isPermissionDenied(err) {
+			t.Fatalf("err: %v", err)
+		}
+	})
+}
+
+func TestAgent_Token_ACLReplication(t *testing.T) {
+	dir1, srv1 := makeHTTPServerWithACLs(t)
+	defer os.RemoveAll(dir1)
+	defer srv1.Shutdown()
+	defer srv1.agent.Shutdown()
+
+	// Start a server in another datacenter without a replication token.
+	dir2, srv2 := makeHTTPServerWithConfig(t, func(c *Config) {
+		c.Datacenter = "dc2"
+		c.ACLDatacenter = "dc1"
+		c.ACLDefaultPolicy = "deny"
+		c.ACLAgentMasterToken = "towel"
+		c.ConsulConfig.ACLReplicationInterval = 10 * time.Millisecond
+	})
+	defer os.RemoveAll(dir2)
+	defer srv2.Shutdown()
+	defer srv2.agent.Shutdown()
+	testrpc.WaitForLeader(t, srv2.agent.RPC, "dc2")
+
+	addr := fmt.Sprintf("127.0.0.1:%d", srv1.agent.config.Ports.SerfWan)
+	if _, err := srv2.agent.JoinWAN([]string{addr}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	status := func() structs.ACLReplicationStatus {
+		req, _ := http.NewRequest("GET", "/v1/acl/replication", nil)
+		obj, err := srv2.ACLReplicationStatus(httptest.NewRecorder(), req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return obj.(structs.ACLReplicationStatus)
+	}
+	if s := status(); s.Enabled || s.Running {
+		t.Fatalf("bad: %#v", s)
+	}
+
+	// Setting the token should start replication.
+	req, _ := http.NewRequest("PUT", "/v1/agent/token/acl_replication_token?token=towel",
+		jsonReader(&api.AgentToken{Token: "root"}))
+	resp := httptest.NewRecorder()
+	if _, err := srv2.AgentToken(resp, req); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got, want := resp.Code, 200; got != want {
+		t.Fatalf("got %d want %d", got, want)
+	}
+	retry.Run(t, func(r *retry.R) {
+		s := status()
+		if !s.Enabled || !s.Running || s.ReplicatedIndex == 0 || s.SourceDatacenter != "dc1" {
+			r.Fatalf("bad: %#v", s)
+		}
+	})
+}
+
 func TestAgent_Members(t *testing.T) {
 	dir, srv := makeHTTPServer(t)
 	defer os.RemoveAll(dir)
@@ -1654,6 +1912,31 @@ func TestAgent_Monitor(t *testing.T) {
 			r.Fatalf("got %q and did not find %q", got, want)
 		}
 	})
+
+	// Stream logs as JSON
+	retry.Run(t, func(r *retry.R) {
+		req, _ = http.NewRequest("GET", "/v1/agent/monitor?loglevel=debug&logjson", nil)
+		resp = newClosableRecorder()
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := srv.AgentMonitor(resp, req)
+			errCh <- err
+		}()
+
+		resp.Close()
+		if err := <-errCh; err != nil {
+			r.Fatalf("err: %s", err)
+		}
+
+		got := resp.Body.Bytes()
+		want := []byte(`"@message":"Initial configuration (index=1)`)
+		if !bytes.Contains(got, want) {
+			r.Fatalf("got %q and did not find %q", got, want)
+		}
+		if !bytes.Contains(got, []byte(`"@module":"raft"`)) {
+			r.Fatalf("got %q and did not find the raft module", got)
+		}
+	})
 }
 
 type closableRecorder struct {
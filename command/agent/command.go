@@ -14,7 +14,6 @@ import (
 
 	"github.com/armon/go-metrics"
 	"github.com/armon/go-metrics/circonus"
-	"github.com/armon/go-metrics/datadog"
 	"github.com/hashicorp/consul/command/base"
 	"github.com/hashicorp/consul/consul"
 	"github.com/hashicorp/consul/consul/structs"
@@ -48,10 +47,17 @@ type Command struct {
 	configReloadCh    chan chan error
 	args              []string
 	logFilter         *logutils.LevelFilter
+	logFile           *logger.LogFile
 	logOutput         io.Writer
 	agent             *Agent
 	httpServers       []*HTTPServer
 	dnsServer         *DNSServer
+	telemetrySink     *reloadableSink
+
+	// fileConfig is the configuration as last read from the config files,
+	// before the agent filled in any runtime values. Reloads compare the
+	// new configuration against it to see what changed.
+	fileConfig *Config
 }
 
 // readConfig is responsible for setup of our configuration using
@@ -61,6 +67,7 @@ func (c *Command) readConfig() *Config {
 	var configFiles []string
 	var retryInterval string
 	var retryIntervalWan string
+	var logRotateMaxAge string
 	var dnsRecursors []string
 	var dev bool
 	var nodeMeta []string
@@ -80,6 +87,14 @@ func (c *Command) readConfig() *Config {
 	f.BoolVar(&dev, "dev", false, "Starts the agent in development mode.")
 
 	f.StringVar(&cmdConfig.LogLevel, "log-level", "", "Log level of the agent.")
+	f.BoolVar(&cmdConfig.LogJSON, "log-json", false, "Output logs in JSON format.")
+	f.StringVar(&cmdConfig.LogFile, "log-file", "", "Path to a file to also write logs to.")
+	f.Int64Var(&cmdConfig.LogRotateBytes, "log-rotate-bytes", 0,
+		"Size in bytes at which the log file is rotated. Defaults to no rotation.")
+	f.StringVar(&logRotateMaxAge, "log-rotate-max-age", "",
+		"How long to keep rotated log files for. Defaults to keeping them forever.")
+	f.IntVar(&cmdConfig.LogRotateMaxFiles, "log-rotate-max-files", 0,
+		"Number of rotated log files to keep. Defaults to keeping them all.")
 	f.StringVar(&cmdConfig.NodeName, "node", "", "Name of this node. Must be unique in the cluster.")
 	f.StringVar((*string)(&cmdConfig.NodeID), "node-id", "",
 		"A unique ID for this node across space and time. Defaults to a randomly-generated ID"+
@@ -96,10 +111,13 @@ func (c *Command) readConfig() *Config {
 	f.StringVar(&cmdConfig.EncryptKey, "encrypt", "", "Provides the gossip encryption key.")
 
 	f.BoolVar(&cmdConfig.Server, "server", false, "Switches agent to server mode.")
-	f.BoolVar(&cmdConfig.NonVotingServer, "non-voting-server", false,
-		"(Enterprise-only) This flag is used to make the server not participate in the Raft quorum, "+
-			"and have it only receive the data replication stream. This can be used to add read scalability "+
+	f.BoolVar(&cmdConfig.ReadReplica, "read-replica", false,
+		"This flag is used to make the server a read replica, which does not participate in the Raft "+
+			"quorum and only receives the data replication stream. Read replicas serve stale reads "+
+			"locally and are preferred by clients for them, which can be used to add read scalability "+
 			"to a cluster in cases where a high volume of reads to servers are needed.")
+	f.BoolVar(&cmdConfig.NonVotingServer, "non-voting-server", false,
+		"Deprecated alias for -read-replica.")
 	f.BoolVar(&cmdConfig.Bootstrap, "bootstrap", false, "Sets server to bootstrap mode.")
 	f.IntVar(&cmdConfig.BootstrapExpect, "bootstrap-expect", 0, "Sets server to expect bootstrap mode.")
 	f.StringVar(&cmdConfig.Domain, "domain", "", "Domain to use for DNS interface.")
@@ -129,7 +147,9 @@ func (c *Command) readConfig() *Config {
 	f.Var((*AppendSliceValue)(&cmdConfig.StartJoinWan), "join-wan",
 		"Address of an agent to join -wan at start time. Can be specified multiple times.")
 	f.Var((*AppendSliceValue)(&cmdConfig.RetryJoin), "retry-join",
-		"Address of an agent to join at start time with retries enabled. Can be specified multiple times.")
+		"Address of an agent to join at start time with retries enabled, or a "+
+			"discovery provider given as \"provider=<name> key=value ...\". Can be "+
+			"specified multiple times.")
 	f.IntVar(&cmdConfig.RetryMaxAttempts, "retry-max", 0,
 		"Maximum number of join attempts. Defaults to 0, which will retry indefinitely.")
 	f.StringVar(&retryInterval, "retry-interval", "",
@@ -148,6 +168,12 @@ func (c *Command) readConfig() *Config {
 		"Google Compute Engine tag value to filter on for server discovery.")
 	f.StringVar(&cmdConfig.RetryJoinGCE.CredentialsFile, "retry-join-gce-credentials-file", "",
 		"Path to credentials JSON file to use with Google Compute Engine.")
+	f.Var((*AppendSliceValue)(&cmdConfig.RetryJoinSRV), "retry-join-srv",
+		"DNS name to look up SRV records for to discover servers, retrying the join "+
+			"until it succeeds. Can be specified multiple times.")
+	f.StringVar(&cmdConfig.RetryJoinFile, "retry-join-file", "",
+		"Path to a file listing addresses of agents to join, one per line. The file "+
+			"is read again on each join attempt.")
 	f.Var((*AppendSliceValue)(&cmdConfig.RetryJoinWan), "retry-join-wan",
 		"Address of an agent to join -wan at start time with retries enabled. "+
 			"Can be specified multiple times.")
@@ -193,6 +219,15 @@ func (c *Command) readConfig() *Config {
 		cmdConfig.Datacenter = dcDeprecated
 	}
 
+	if logRotateMaxAge != "" {
+		dur, err := time.ParseDuration(logRotateMaxAge)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error: %s", err))
+			return nil
+		}
+		cmdConfig.LogRotateMaxAge = dur
+	}
+
 	if retryInterval != "" {
 		dur, err := time.ParseDuration(retryInterval)
 		if err != nil {
@@ -367,6 +402,18 @@ func (c *Command) readConfig() *Config {
 		return nil
 	}
 
+	// Read replicas can't vote, so they can't take part in bootstrapping
+	if config.ReadReplica || config.NonVotingServer {
+		if !config.Server {
+			c.UI.Error("Read replica mode cannot be enabled when server mode is not enabled")
+			return nil
+		}
+		if config.Bootstrap || config.BootstrapExpect != 0 {
+			c.UI.Error("Read replica mode cannot be enabled with bootstrap or expect mode")
+			return nil
+		}
+	}
+
 	// Expect & Bootstrap are mutually exclusive
 	if config.BootstrapExpect != 0 && config.Bootstrap {
 		c.UI.Error("Bootstrap cannot be provided with an expected server count")
@@ -430,6 +477,12 @@ func (c *Command) readConfig() *Config {
 		return nil
 	}
 
+	// Make sure the retry join providers are valid
+	if _, err := config.joinProviders(); err != nil {
+		c.UI.Error(err.Error())
+		return nil
+	}
+
 	// Verify the node metadata entries are valid
 	if err := structs.ValidateMetadata(config.Meta); err != nil {
 		c.UI.Error(fmt.Sprintf("Failed to parse node metadata: %v", err))
@@ -452,13 +505,14 @@ func (c *Command) readConfig() *Config {
 }
 
 // setupAgent is used to start the agent and various interfaces
-func (c *Command) setupAgent(config *Config, logOutput io.Writer, logWriter *logger.LogWriter) error {
+func (c *Command) setupAgent(config *Config, logOutput io.Writer, logWriter *logger.LogWriter, inm *metrics.InmemSink) error {
 	c.UI.Output("Starting Consul agent...")
 	agent, err := Create(config, logOutput, logWriter, c.configReloadCh)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error starting agent: %s", err))
 		return err
 	}
+	agent.metricsSink = inm
 	c.agent = agent
 
 	if config.Ports.HTTP > 0 || config.Ports.HTTPS > 0 {
@@ -569,35 +623,34 @@ func (c *Command) startupJoinWan(config *Config) error {
 // retryJoin is used to handle retrying a join until it succeeds or all
 // retries are exhausted.
 func (c *Command) retryJoin(config *Config, errCh chan<- struct{}) {
-	ec2Enabled := config.RetryJoinEC2.TagKey != "" && config.RetryJoinEC2.TagValue != ""
-
-	if len(config.RetryJoin) == 0 && !ec2Enabled && config.RetryJoinGCE.TagValue == "" {
+	logger := c.agent.logger
+	providers, err := config.joinProviders()
+	if err != nil {
+		// This was checked when reading the config, so shouldn't happen.
+		logger.Printf("[ERROR] agent: %v", err)
+		close(errCh)
+		return
+	}
+	if len(providers) == 0 {
 		return
 	}
 
-	logger := c.agent.logger
 	logger.Printf("[INFO] agent: Joining cluster...")
 
 	attempt := 0
 	for {
 		var servers []string
 		var err error
-		switch {
-		case ec2Enabled:
-			servers, err = config.discoverEc2Hosts(logger)
+		for _, provider := range providers {
+			addrs, err := provider.Addrs(logger)
 			if err != nil {
-				logger.Printf("[ERROR] agent: Unable to query EC2 instances: %s", err)
+				logger.Printf("[ERROR] agent: Unable to discover servers from %s: %s", provider.Name(), err)
+				continue
 			}
-			logger.Printf("[INFO] agent: Discovered %d servers from EC2", len(servers))
-		case config.RetryJoinGCE.TagValue != "":
-			servers, err = config.discoverGCEHosts(logger)
-			if err != nil {
-				logger.Printf("[ERROR] agent: Unable to query GCE insances: %s", err)
-			}
-			logger.Printf("[INFO] agent: Discovered %d servers from GCE", len(servers))
+			logger.Printf("[INFO] agent: Discovered %d servers from %s", len(addrs), provider.Name())
+			servers = append(servers, addrs...)
 		}
 
-		servers = append(servers, config.RetryJoin...)
 		if len(servers) == 0 {
 			err = fmt.Errorf("No servers to join")
 		} else {
@@ -690,6 +743,16 @@ func (c *Command) Run(args []string) int {
 		LogLevel:       config.LogLevel,
 		EnableSyslog:   config.EnableSyslog,
 		SyslogFacility: config.SyslogFacility,
+		LogJSON:        config.LogJSON,
+	}
+	if config.LogFile != "" {
+		c.logFile = &logger.LogFile{
+			Path:     config.LogFile,
+			MaxBytes: config.LogRotateBytes,
+			MaxAge:   config.LogRotateMaxAge,
+			MaxFiles: config.LogRotateMaxFiles,
+		}
+		logConfig.LogFile = c.logFile
 	}
 	logFilter, logGate, logWriter, logOutput, ok := logger.Setup(logConfig, c.UI)
 	if !ok {
@@ -710,43 +773,16 @@ func (c *Command) Run(args []string) int {
 	metricsConf := metrics.DefaultConfig(config.Telemetry.StatsitePrefix)
 	metricsConf.EnableHostname = !config.Telemetry.DisableHostname
 
-	// Configure the statsite sink
-	var fanout metrics.FanoutSink
-	if config.Telemetry.StatsiteAddr != "" {
-		sink, err := metrics.NewStatsiteSink(config.Telemetry.StatsiteAddr)
-		if err != nil {
-			c.UI.Error(fmt.Sprintf("Failed to start statsite sink. Got: %s", err))
-			return 1
-		}
-		fanout = append(fanout, sink)
-	}
-
-	// Configure the statsd sink
-	if config.Telemetry.StatsdAddr != "" {
-		sink, err := metrics.NewStatsdSink(config.Telemetry.StatsdAddr)
-		if err != nil {
-			c.UI.Error(fmt.Sprintf("Failed to start statsd sink. Got: %s", err))
-			return 1
-		}
-		fanout = append(fanout, sink)
-	}
-
-	// Configure the DogStatsd sink
-	if config.Telemetry.DogStatsdAddr != "" {
-		var tags []string
-
-		if config.Telemetry.DogStatsdTags != nil {
-			tags = config.Telemetry.DogStatsdTags
-		}
-
-		sink, err := datadog.NewDogStatsdSink(config.Telemetry.DogStatsdAddr, metricsConf.HostName)
-		if err != nil {
-			c.UI.Error(fmt.Sprintf("Failed to start DogStatsd sink. Got: %s", err))
-			return 1
-		}
-		sink.SetTags(tags)
-		fanout = append(fanout, sink)
+	// Configure the statsite, statsd and DogStatsd sinks. These sit behind
+	// a reloadable sink so they can be changed on a configuration reload.
+	telemetrySink, err := newReloadableSink(&config.Telemetry, metricsConf.HostName)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
 	}
+	c.telemetrySink = telemetrySink
+	hasSinks := telemetrySink.Len() > 0
+	fanout := metrics.FanoutSink{telemetrySink}
 
 	if config.Telemetry.CirconusAPIToken != "" || config.Telemetry.CirconusCheckSubmissionURL != "" {
 		cfg := &circonus.Config{}
@@ -783,19 +819,23 @@ func (c *Command) Run(args []string) int {
 		}
 		sink.Start()
 		fanout = append(fanout, sink)
+		hasSinks = true
 	}
 
 	// Initialize the global sink
-	if len(fanout) > 0 {
-		fanout = append(fanout, inm)
-		metrics.NewGlobal(metricsConf, fanout)
-	} else {
+	if !hasSinks {
 		metricsConf.EnableHostname = false
-		metrics.NewGlobal(metricsConf, inm)
 	}
+	fanout = append(fanout, inm)
+	metrics.NewGlobal(metricsConf, fanout)
+
+	// Keep a copy of the configuration as it was read, since the agent
+	// fills in runtime values on the one it is given.
+	fileConfig := *config
+	c.fileConfig = &fileConfig
 
 	// Create the agent
-	if err := c.setupAgent(config, logOutput, logWriter); err != nil {
+	if err := c.setupAgent(config, logOutput, logWriter, inm); err != nil {
 		return 1
 	}
 	defer c.agent.Shutdown()
@@ -820,7 +860,6 @@ func (c *Command) Run(args []string) int {
 
 	// Get the new client http listener addr
 	var httpAddr net.Addr
-	var err error
 	if config.Ports.HTTP != -1 {
 		httpAddr, err = config.ClientListener(config.Addresses.HTTP, config.Ports.HTTP)
 	} else if config.Ports.HTTPS != -1 {
@@ -993,6 +1032,52 @@ func (c *Command) handleReload(config *Config) (*Config, error) {
 		newConf.LogLevel = config.LogLevel
 	}
 
+	// Reopen the log file, so that external tools can move it aside and
+	// have a new one started.
+	if c.logFile != nil {
+		if err := c.logFile.Reopen(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("Failed to reopen log file: %s", err))
+		}
+	}
+
+	// Reload the TLS certificates and CAs from the configured files. New
+	// connections pick these up while existing connections are kept.
+	if err := c.agent.ReloadTLS(); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("Failed reloading TLS for RPC: %s", err))
+	}
+	for _, srv := range c.httpServers {
+		if err := srv.ReloadTLS(); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("Failed reloading TLS for HTTPS: %s", err))
+		}
+	}
+
+	// Apply the settings that can change at runtime, and report the ones
+	// that need a restart.
+	applied, restart := configChanges(c.fileConfig, newConf)
+	if newConf.ACLToken != c.fileConfig.ACLToken {
+		c.agent.tokens.UpdateUserToken(newConf.ACLToken)
+	}
+	if newConf.ACLAgentToken != c.fileConfig.ACLAgentToken {
+		c.agent.tokens.UpdateAgentToken(newConf.ACLAgentToken)
+	}
+	if c.dnsServer != nil {
+		if err := c.dnsServer.Reload(&newConf.DNSConfig, newConf.DNSRecursors); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("Failed reloading DNS config: %s", err))
+		}
+	}
+	if c.telemetrySink != nil && telemetrySinksChanged(&c.fileConfig.Telemetry, &newConf.Telemetry) {
+		if err := c.telemetrySink.Reload(&newConf.Telemetry); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("Failed reloading telemetry sinks: %s", err))
+		}
+	}
+	c.fileConfig = newConf
+	if len(applied) > 0 {
+		c.UI.Output(fmt.Sprintf("Applied configuration changes: %s", strings.Join(applied, ", ")))
+	}
+	if len(restart) > 0 {
+		c.UI.Warn(fmt.Sprintf("Configuration changes that require a restart: %s", strings.Join(restart, ", ")))
+	}
+
 	// Bulk update the services and checks
 	c.agent.PauseSync()
 	defer c.agent.ResumeSync()
@@ -2,11 +2,13 @@ package agent
 
 import (
 	"fmt"
+	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"reflect"
+	"runtime"
 	"strings"
 	"testing"
 
@@ -81,6 +83,14 @@ func TestConfigFail(t *testing.T) {
 			args: []string{"agent", "-server", "-data-dir", "foo", "-advertise-wan", "[::]"},
 			out:  "==> Advertise WAN address cannot be [::]\n",
 		},
+		{
+			args: []string{"agent", "-data-dir", "foo", "-read-replica"},
+			out:  "==> Read replica mode cannot be enabled when server mode is not enabled\n",
+		},
+		{
+			args: []string{"agent", "-server", "-data-dir", "foo", "-read-replica", "-bootstrap"},
+			out:  "==> Read replica mode cannot be enabled with bootstrap or expect mode\n",
+		},
 	}
 
 	for _, tt := range tests {
@@ -158,6 +168,137 @@ func TestRetryJoin(t *testing.T) {
 	})
 }
 
+func TestRetryJoinFile(t *testing.T) {
+	dir, agent := makeAgent(t, nextConfig())
+	defer os.RemoveAll(dir)
+	defer agent.Shutdown()
+
+	conf2 := nextConfig()
+	tmpDir := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(tmpDir)
+
+	// Start with a file that doesn't list any servers.
+	joinFile := filepath.Join(tmpDir, "servers")
+	if err := ioutil.WriteFile(joinFile, []byte("# none yet\n"), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	doneCh := make(chan struct{})
+	shutdownCh := make(chan struct{})
+
+	defer func() {
+		close(shutdownCh)
+		<-doneCh
+	}()
+
+	cmd := &Command{
+		Version:    version.Version,
+		ShutdownCh: shutdownCh,
+		Command:    baseCommand(new(cli.MockUi)),
+	}
+
+	args := []string{
+		"-bind", agent.config.BindAddr,
+		"-data-dir", filepath.Join(tmpDir, "data"),
+		"-node", fmt.Sprintf(`"%s"`, conf2.NodeName),
+		"-advertise", agent.config.BindAddr,
+		"-retry-join-file", joinFile,
+		"-retry-interval", "1s",
+	}
+
+	go func() {
+		if code := cmd.Run(args); code != 0 {
+			log.Printf("bad: %d", code)
+		}
+		close(doneCh)
+	}()
+
+	// The join should be retried with the new contents of the file.
+	serfAddr := fmt.Sprintf("%s:%d", agent.config.BindAddr, agent.config.Ports.SerfLan)
+	if err := ioutil.WriteFile(joinFile, []byte(serfAddr+"\n"), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	retry.Run(t, func(r *retry.R) {
+		if got, want := len(agent.LANMembers()), 2; got != want {
+			r.Fatalf("got %d LAN members want %d", got, want)
+		}
+	})
+}
+
+func TestRetryJoinExecProvider(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell commands")
+	}
+
+	dir, agent := makeAgent(t, nextConfig())
+	defer os.RemoveAll(dir)
+	defer agent.Shutdown()
+
+	conf2 := nextConfig()
+	tmpDir := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(tmpDir)
+
+	doneCh := make(chan struct{})
+	shutdownCh := make(chan struct{})
+
+	defer func() {
+		close(shutdownCh)
+		<-doneCh
+	}()
+
+	cmd := &Command{
+		Version:    version.Version,
+		ShutdownCh: shutdownCh,
+		Command:    baseCommand(new(cli.MockUi)),
+	}
+
+	serfAddr := fmt.Sprintf("%s:%d", agent.config.BindAddr, agent.config.Ports.SerfLan)
+	args := []string{
+		"-bind", agent.config.BindAddr,
+		"-data-dir", tmpDir,
+		"-node", fmt.Sprintf(`"%s"`, conf2.NodeName),
+		"-advertise", agent.config.BindAddr,
+		"-retry-join", fmt.Sprintf(`provider=exec command="echo %s"`, serfAddr),
+		"-retry-interval", "1s",
+	}
+
+	go func() {
+		if code := cmd.Run(args); code != 0 {
+			log.Printf("bad: %d", code)
+		}
+		close(doneCh)
+	}()
+	retry.Run(t, func(r *retry.R) {
+		if got, want := len(agent.LANMembers()), 2; got != want {
+			r.Fatalf("got %d LAN members want %d", got, want)
+		}
+	})
+}
+
+func TestReadCliConfig_InvalidJoinProvider(t *testing.T) {
+	tmpDir := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(tmpDir)
+
+	shutdownCh := make(chan struct{})
+	defer close(shutdownCh)
+
+	ui := new(cli.MockUi)
+	cmd := &Command{
+		args: []string{
+			"-data-dir", tmpDir,
+			"-retry-join", "provider=nope",
+		},
+		ShutdownCh: shutdownCh,
+		Command:    baseCommand(ui),
+	}
+	if config := cmd.readConfig(); config != nil {
+		t.Fatalf("should fail")
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), `unknown provider "nope"`) {
+		t.Fatalf("bad: %s", ui.ErrorWriter.String())
+	}
+}
+
 func TestReadCliConfig(t *testing.T) {
 	tmpDir := testutil.TempDir(t, "consul")
 	defer os.RemoveAll(tmpDir)
@@ -356,15 +497,15 @@ func TestDiscoverEC2Hosts(t *testing.T) {
 		t.Skip("AWS_SECRET_ACCESS_KEY not set, skipping")
 	}
 
-	c := &Config{
-		RetryJoinEC2: RetryJoinEC2{
+	p := &ec2JoinProvider{
+		config: RetryJoinEC2{
 			Region:   os.Getenv("AWS_REGION"),
 			TagKey:   "ConsulRole",
 			TagValue: "Server",
 		},
 	}
 
-	servers, err := c.discoverEc2Hosts(&log.Logger{})
+	servers, err := p.Addrs(&log.Logger{})
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -382,8 +523,8 @@ func TestDiscoverGCEHosts(t *testing.T) {
 		t.Skip("GOOGLE_APPLICATION_CREDENTIALS or GCE_CONFIG_CREDENTIALS not set, skipping")
 	}
 
-	c := &Config{
-		RetryJoinGCE: RetryJoinGCE{
+	p := &gceJoinProvider{
+		config: RetryJoinGCE{
 			ProjectName:     os.Getenv("GCE_PROJECT"),
 			ZonePattern:     os.Getenv("GCE_ZONE"),
 			TagValue:        "consulrole-server",
@@ -391,7 +532,7 @@ func TestDiscoverGCEHosts(t *testing.T) {
 		},
 	}
 
-	servers, err := c.discoverGCEHosts(log.New(os.Stderr, "", log.LstdFlags))
+	servers, err := p.Addrs(log.New(os.Stderr, "", log.LstdFlags))
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -452,3 +593,154 @@ func TestBadDataDirPermissions(t *testing.T) {
 		t.Fatalf("expected permission denied error, got: %s", out)
 	}
 }
+
+func TestCommand_Reload_RuntimeSettings(t *testing.T) {
+	conf := nextConfig()
+	tmpDir := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(tmpDir)
+
+	// Write initial config, to be reloaded later
+	tmpFile := testutil.TempFile(t, "config")
+	_, err := tmpFile.WriteString(`{"acl_token": "before"}`)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	tmpFile.Close()
+
+	doneCh := make(chan struct{})
+	shutdownCh := make(chan struct{})
+	defer func() {
+		close(shutdownCh)
+		<-doneCh
+	}()
+
+	ui := new(cli.MockUi)
+	cmd := &Command{
+		ShutdownCh: shutdownCh,
+		Command:    baseCommand(ui),
+	}
+	args := []string{
+		"-server",
+		"-bind", "127.0.0.1",
+		"-data-dir", tmpDir,
+		"-http-port", fmt.Sprintf("%d", conf.Ports.HTTP),
+		"-dns-port", fmt.Sprintf("%d", conf.Ports.DNS),
+		"-config-file", tmpFile.Name(),
+	}
+	go func() {
+		cmd.Run(args)
+		close(doneCh)
+	}()
+
+	retry.Run(t, func(r *retry.R) {
+		if got, want := len(cmd.httpServers), 1; got != want {
+			r.Fatalf("got %d servers want %d", got, want)
+		}
+	})
+	if got := cmd.agent.tokens.UserToken(); got != "before" {
+		t.Fatalf("bad: %s", got)
+	}
+
+	data := []byte(`{
+		"acl_token": "after",
+		"acl_agent_token": "agent",
+		"dns_config": {"only_passing": true},
+		"recursor": "8.8.8.8",
+		"enable_debug": true
+	}`)
+	if err := ioutil.WriteFile(tmpFile.Name(), data, 0644); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	errCh := make(chan error)
+	cmd.configReloadCh <- errCh
+	if err := <-errCh; err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	if got := cmd.agent.tokens.UserToken(); got != "after" {
+		t.Fatalf("bad: %s", got)
+	}
+	if got := cmd.agent.tokens.AgentToken(); got != "agent" {
+		t.Fatalf("bad: %s", got)
+	}
+	if !cmd.dnsServer.dnsConfig().OnlyPassing {
+		t.Fatalf("DNS config was not reloaded")
+	}
+	if got, want := cmd.dnsServer.dnsRecursors(), []string{"8.8.8.8:53"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v want %v", got, want)
+	}
+
+	out := ui.OutputWriter.String()
+	if !strings.Contains(out, "Applied configuration changes: acl_agent_token, acl_token, dns_config, recursors") {
+		t.Fatalf("bad: %s", out)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "require a restart: enable_debug") {
+		t.Fatalf("bad: %s", out)
+	}
+}
+
+func TestCommand_LogFile(t *testing.T) {
+	conf := nextConfig()
+	tmpDir := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(tmpDir)
+	logFile := filepath.Join(tmpDir, "consul.log")
+
+	doneCh := make(chan struct{})
+	shutdownCh := make(chan struct{})
+	defer func() {
+		close(shutdownCh)
+		<-doneCh
+	}()
+
+	ui := new(cli.MockUi)
+	cmd := &Command{
+		ShutdownCh: shutdownCh,
+		Command:    baseCommand(ui),
+	}
+	args := []string{
+		"-server",
+		"-bind", "127.0.0.1",
+		"-data-dir", tmpDir,
+		"-http-port", fmt.Sprintf("%d", conf.Ports.HTTP),
+		"-dns-port", fmt.Sprintf("%d", conf.Ports.DNS),
+		"-log-file", logFile,
+	}
+	go func() {
+		cmd.Run(args)
+		close(doneCh)
+	}()
+
+	retry.Run(t, func(r *retry.R) {
+		if got, want := len(cmd.httpServers), 1; got != want {
+			r.Fatalf("got %d servers want %d", got, want)
+		}
+	})
+	retry.Run(t, func(r *retry.R) {
+		got, err := ioutil.ReadFile(logFile)
+		if err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if !strings.Contains(string(got), "[INFO] raft:") {
+			r.Fatalf("bad: %s", got)
+		}
+	})
+
+	// A reload should start a new file if the old one was moved aside.
+	if err := os.Rename(logFile, logFile+".1"); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	errCh := make(chan error)
+	cmd.configReloadCh <- errCh
+	if err := <-errCh; err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	cmd.agent.logger.Printf("[INFO] agent: after reopen")
+	got, err := ioutil.ReadFile(logFile)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !strings.Contains(string(got), "agent: after reopen") {
+		t.Fatalf("bad: %s", got)
+	}
+}
@@ -170,6 +170,25 @@ type Performance struct {
 	// RaftMultiplier is an integer multiplier used to scale Raft timing
 	// parameters: HeartbeatTimeout, ElectionTimeout, and LeaderLeaseTimeout.
 	RaftMultiplier uint `mapstructure:"raft_multiplier"`
+
+	// FollowerReads lets servers that aren't the leader answer default and
+	// consistent reads themselves, after catching up to the leader's read
+	// index, instead of forwarding them to the leader.
+	FollowerReads bool `mapstructure:"follower_reads"`
+}
+
+// RTTSort controls how the agent sorts service instances by network
+// round trip time.
+type RTTSort struct {
+	// DefaultNearAgent sorts service lookups in the local datacenter by
+	// distance from this agent when the request doesn't give a source
+	// node with ?near. This applies to /v1/health/service and DNS service
+	// lookups.
+	DefaultNearAgent bool `mapstructure:"default_near_agent"`
+
+	// Nearest limits results that are sorted by distance to the N nearest
+	// healthy instances. A value of 0 returns all instances.
+	Nearest int `mapstructure:"nearest"`
 }
 
 // Telemetry is the telemetry configuration for the server
@@ -290,11 +309,11 @@ type Autopilot struct {
 	ServerStabilizationTime    *time.Duration `mapstructure:"-" json:"-"`
 	ServerStabilizationTimeRaw string         `mapstructure:"server_stabilization_time"`
 
-	// (Enterprise-only) RedundancyZoneTag is the Meta tag to use for separating servers
+	// RedundancyZoneTag is the Meta tag to use for separating servers
 	// into zones for redundancy. If left blank, this feature will be disabled.
 	RedundancyZoneTag string `mapstructure:"redundancy_zone_tag"`
 
-	// (Enterprise-only) DisableUpgradeMigration will disable Autopilot's upgrade migration
+	// DisableUpgradeMigration will disable Autopilot's upgrade migration
 	// strategy of waiting until enough newer-versioned servers have been added to the
 	// cluster before promoting them to voters.
 	DisableUpgradeMigration *bool `mapstructure:"disable_upgrade_migration"`
@@ -324,8 +343,12 @@ type Config struct {
 	// in leader election, etc.
 	Server bool `mapstructure:"server"`
 
-	// (Enterprise-only) NonVotingServer is whether this server will act as a non-voting member
-	// of the cluster to help provide read scalability.
+	// ReadReplica is whether this server will act as a read replica, which
+	// never votes but receives the Raft log and serves stale reads, to help
+	// provide read scalability.
+	ReadReplica bool `mapstructure:"read_replica"`
+
+	// NonVotingServer is a deprecated alias for ReadReplica.
 	NonVotingServer bool `mapstructure:"non_voting_server"`
 
 	// Datacenter is the datacenter this node is in. Defaults to dc1
@@ -346,15 +369,38 @@ type Config struct {
 	// DNS configuration
 	DNSConfig DNSConfig `mapstructure:"dns_config"`
 
+	// RTTSort controls how service instances are sorted by network round
+	// trip time.
+	RTTSort RTTSort `mapstructure:"rtt_sort"`
+
 	// Domain is the DNS domain for the records. Defaults to "consul."
 	Domain string `mapstructure:"domain"`
 
 	// Encryption key to use for the Serf communication
 	EncryptKey string `mapstructure:"encrypt" json:"-"`
 
+	// SnapshotEncryptionKeyFile is the path to a file holding a base64-encoded
+	// key used by servers to encrypt snapshots taken through the snapshot
+	// endpoint, and to decrypt encrypted snapshots being restored.
+	SnapshotEncryptionKeyFile string `mapstructure:"snapshot_encryption_key_file"`
+
 	// LogLevel is the level of the logs to putout
 	LogLevel string `mapstructure:"log_level"`
 
+	// LogJSON writes the agent's logs out as JSON objects instead of plain
+	// text lines.
+	LogJSON bool `mapstructure:"log_json"`
+
+	// LogFile is a file to also write the agent's logs to. It's rotated
+	// once it reaches LogRotateBytes, and rotated files are removed once
+	// there are more than LogRotateMaxFiles of them or they are older than
+	// LogRotateMaxAge.
+	LogFile            string        `mapstructure:"log_file"`
+	LogRotateBytes     int64         `mapstructure:"log_rotate_bytes"`
+	LogRotateMaxAge    time.Duration `mapstructure:"-" json:"-"`
+	LogRotateMaxAgeRaw string        `mapstructure:"log_rotate_max_age"`
+	LogRotateMaxFiles  int           `mapstructure:"log_rotate_max_files"`
+
 	// Node ID is a unique ID for this node across space and time. Defaults
 	// to a randomly-generated ID that persists in the data-dir.
 	NodeID types.NodeID `mapstructure:"node_id"`
@@ -517,7 +563,9 @@ type Config struct {
 	// addresses, then the agent will error and exit.
 	StartJoinWan []string `mapstructure:"start_join_wan"`
 
-	// RetryJoin is a list of addresses to join with retry enabled.
+	// RetryJoin is a list of addresses to join with retry enabled. Entries
+	// of the form "provider=<name> key=value ..." discover the addresses
+	// using one of the join providers instead.
 	RetryJoin []string `mapstructure:"retry_join"`
 
 	// RetryMaxAttempts specifies the maximum number of times to retry joining a
@@ -537,6 +585,14 @@ type Config struct {
 	// The config struct for the GCE tag server discovery feature.
 	RetryJoinGCE RetryJoinGCE `mapstructure:"retry_join_gce"`
 
+	// RetryJoinSRV is a list of DNS names whose SRV records give the
+	// addresses of agents to join with retry enabled.
+	RetryJoinSRV []string `mapstructure:"retry_join_srv"`
+
+	// RetryJoinFile is the path to a file listing addresses of agents to
+	// join with retry enabled, one per line. It's read again on each attempt.
+	RetryJoinFile string `mapstructure:"retry_join_file"`
+
 	// RetryJoinWan is a list of addresses to join -wan with retry enabled.
 	RetryJoinWan []string `mapstructure:"retry_join_wan"`
 
@@ -559,6 +615,19 @@ type Config struct {
 	ReconnectTimeoutWan    time.Duration `mapstructure:"-"`
 	ReconnectTimeoutWanRaw string        `mapstructure:"reconnect_timeout_wan"`
 
+	// PreparedQueryCacheTTL enables caching of prepared query results on
+	// the agent, and is how long a result can be served without being
+	// refreshed. Cached results are refreshed in the background while
+	// they are being used. A zero value disables the cache.
+	PreparedQueryCacheTTL    time.Duration `mapstructure:"-"`
+	PreparedQueryCacheTTLRaw string        `mapstructure:"prepared_query_cache_ttl"`
+
+	// DurableEventRetention is how long servers keep durable user events
+	// so that agents can catch up on ones they missed. This only applies
+	// to servers.
+	DurableEventRetention    time.Duration `mapstructure:"-"`
+	DurableEventRetentionRaw string        `mapstructure:"durable_event_retention"`
+
 	// EnableUI enables the statically-compiled assets for the Consul web UI and
 	// serves them at the default /ui/ endpoint automatically.
 	EnableUI bool `mapstructure:"ui"`
@@ -661,6 +730,11 @@ type Config struct {
 	// are opt-in prior to Consul 0.8 and opt-out in Consul 0.8 and later.
 	ACLEnforceVersion8 *bool `mapstructure:"acl_enforce_version_8"`
 
+	// ACLEnableTokenPersistence saves the ACL tokens set through the
+	// agent's token endpoint to the data dir, so they are loaded again
+	// after a restart in place of the ones in the configuration.
+	ACLEnableTokenPersistence bool `mapstructure:"acl_enable_token_persistence" json:"-"`
+
 	// Watches are used to monitor various endpoints and to invoke a
 	// handler to act appropriately. These are managed entirely in the
 	// agent layer using the standard APIs.
@@ -1154,6 +1228,14 @@ func DecodeConfig(r io.Reader) (*Config, error) {
 		result.ACLTTL = dur
 	}
 
+	if raw := result.LogRotateMaxAgeRaw; raw != "" {
+		dur, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("LogRotateMaxAge invalid: %v", err)
+		}
+		result.LogRotateMaxAge = dur
+	}
+
 	if raw := result.RetryIntervalRaw; raw != "" {
 		dur, err := time.ParseDuration(raw)
 		if err != nil {
@@ -1192,6 +1274,28 @@ func DecodeConfig(r io.Reader) (*Config, error) {
 		result.ReconnectTimeoutWan = dur
 	}
 
+	if raw := result.PreparedQueryCacheTTLRaw; raw != "" {
+		dur, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("PreparedQueryCacheTTL invalid: %v", err)
+		}
+		if dur < 0 {
+			return nil, fmt.Errorf("PreparedQueryCacheTTL must be >= 0")
+		}
+		result.PreparedQueryCacheTTL = dur
+	}
+
+	if raw := result.DurableEventRetentionRaw; raw != "" {
+		dur, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("DurableEventRetention invalid: %v", err)
+		}
+		if dur <= 0 {
+			return nil, fmt.Errorf("DurableEventRetention must be > 0")
+		}
+		result.DurableEventRetention = dur
+	}
+
 	if raw := result.Autopilot.LastContactThresholdRaw; raw != "" {
 		dur, err := time.ParseDuration(raw)
 		if err != nil {
@@ -1262,6 +1366,10 @@ func DecodeConfig(r io.Reader) (*Config, error) {
 		result.AdvertiseAddrs.RPC = addr
 	}
 
+	if result.RTTSort.Nearest < 0 {
+		return nil, fmt.Errorf("RTTSort.Nearest must be >= 0")
+	}
+
 	// Enforce the max Raft multiplier.
 	if result.Performance.RaftMultiplier > consul.MaxRaftMultiplier {
 		return nil, fmt.Errorf("Performance.RaftMultiplier must be <= %d", consul.MaxRaftMultiplier)
@@ -1435,6 +1543,9 @@ func MergeConfig(a, b *Config) *Config {
 	if b.Performance.RaftMultiplier > 0 {
 		result.Performance.RaftMultiplier = b.Performance.RaftMultiplier
 	}
+	if b.Performance.FollowerReads {
+		result.Performance.FollowerReads = true
+	}
 
 	// Copy the strings if they're set
 	if b.Bootstrap {
@@ -1461,9 +1572,30 @@ func MergeConfig(a, b *Config) *Config {
 	if b.EncryptKey != "" {
 		result.EncryptKey = b.EncryptKey
 	}
+	if b.SnapshotEncryptionKeyFile != "" {
+		result.SnapshotEncryptionKeyFile = b.SnapshotEncryptionKeyFile
+	}
 	if b.LogLevel != "" {
 		result.LogLevel = b.LogLevel
 	}
+	if b.LogJSON {
+		result.LogJSON = true
+	}
+	if b.LogFile != "" {
+		result.LogFile = b.LogFile
+	}
+	if b.LogRotateBytes != 0 {
+		result.LogRotateBytes = b.LogRotateBytes
+	}
+	if b.LogRotateMaxAge != 0 {
+		result.LogRotateMaxAge = b.LogRotateMaxAge
+	}
+	if b.LogRotateMaxAgeRaw != "" {
+		result.LogRotateMaxAgeRaw = b.LogRotateMaxAgeRaw
+	}
+	if b.LogRotateMaxFiles != 0 {
+		result.LogRotateMaxFiles = b.LogRotateMaxFiles
+	}
 	if b.Protocol > 0 {
 		result.Protocol = b.Protocol
 	}
@@ -1515,6 +1647,9 @@ func MergeConfig(a, b *Config) *Config {
 	if b.Server == true {
 		result.Server = b.Server
 	}
+	if b.ReadReplica == true {
+		result.ReadReplica = b.ReadReplica
+	}
 	if b.NonVotingServer == true {
 		result.NonVotingServer = b.NonVotingServer
 	}
@@ -1728,12 +1863,23 @@ func MergeConfig(a, b *Config) *Config {
 	if b.RetryJoinGCE.CredentialsFile != "" {
 		result.RetryJoinGCE.CredentialsFile = b.RetryJoinGCE.CredentialsFile
 	}
+	if b.RetryJoinFile != "" {
+		result.RetryJoinFile = b.RetryJoinFile
+	}
 	if b.RetryMaxAttemptsWan != 0 {
 		result.RetryMaxAttemptsWan = b.RetryMaxAttemptsWan
 	}
 	if b.RetryIntervalWan != 0 {
 		result.RetryIntervalWan = b.RetryIntervalWan
 	}
+	if b.PreparedQueryCacheTTL != 0 {
+		result.PreparedQueryCacheTTL = b.PreparedQueryCacheTTL
+		result.PreparedQueryCacheTTLRaw = b.PreparedQueryCacheTTLRaw
+	}
+	if b.DurableEventRetention != 0 {
+		result.DurableEventRetention = b.DurableEventRetention
+		result.DurableEventRetentionRaw = b.DurableEventRetentionRaw
+	}
 	if b.ReconnectTimeoutLan != 0 {
 		result.ReconnectTimeoutLan = b.ReconnectTimeoutLan
 		result.ReconnectTimeoutLanRaw = b.ReconnectTimeoutLanRaw
@@ -1742,6 +1888,12 @@ func MergeConfig(a, b *Config) *Config {
 		result.ReconnectTimeoutWan = b.ReconnectTimeoutWan
 		result.ReconnectTimeoutWanRaw = b.ReconnectTimeoutWanRaw
 	}
+	if b.RTTSort.DefaultNearAgent {
+		result.RTTSort.DefaultNearAgent = true
+	}
+	if b.RTTSort.Nearest != 0 {
+		result.RTTSort.Nearest = b.RTTSort.Nearest
+	}
 	if b.DNSConfig.NodeTTL != 0 {
 		result.DNSConfig.NodeTTL = b.DNSConfig.NodeTTL
 	}
@@ -1811,6 +1963,9 @@ func MergeConfig(a, b *Config) *Config {
 	if b.ACLEnforceVersion8 != nil {
 		result.ACLEnforceVersion8 = b.ACLEnforceVersion8
 	}
+	if b.ACLEnableTokenPersistence {
+		result.ACLEnableTokenPersistence = true
+	}
 	if len(b.Watches) != 0 {
 		result.Watches = append(result.Watches, b.Watches...)
 	}
@@ -1874,6 +2029,11 @@ func MergeConfig(a, b *Config) *Config {
 	result.RetryJoin = append(result.RetryJoin, a.RetryJoin...)
 	result.RetryJoin = append(result.RetryJoin, b.RetryJoin...)
 
+	// Copy the retry join SRV names
+	result.RetryJoinSRV = make([]string, 0, len(a.RetryJoinSRV)+len(b.RetryJoinSRV))
+	result.RetryJoinSRV = append(result.RetryJoinSRV, a.RetryJoinSRV...)
+	result.RetryJoinSRV = append(result.RetryJoinSRV, b.RetryJoinSRV...)
+
 	// Copy the retry join -wan addresses
 	result.RetryJoinWan = make([]string, 0, len(a.RetryJoinWan)+len(b.RetryJoinWan))
 	result.RetryJoinWan = append(result.RetryJoinWan, a.RetryJoinWan...)
@@ -48,7 +48,7 @@ func TestConfigEncryptBytes(t *testing.T) {
 
 func TestDecodeConfig(t *testing.T) {
 	// Basics
-	input := `{"data_dir": "/tmp/", "log_level": "debug"}`
+	input := `{"data_dir": "/tmp/", "log_level": "debug", "log_json": true}`
 	config, err := DecodeConfig(bytes.NewReader([]byte(input)))
 	if err != nil {
 		t.Fatalf("err: %s", err)
@@ -62,6 +62,30 @@ func TestDecodeConfig(t *testing.T) {
 		t.Fatalf("bad: %#v", config)
 	}
 
+	if !config.LogJSON {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	// Log file
+	input = `{"log_file": "/var/log/consul.log", "log_rotate_bytes": 1048576, "log_rotate_max_age": "24h", "log_rotate_max_files": 5}`
+	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if config.LogFile != "/var/log/consul.log" {
+		t.Fatalf("bad: %#v", config)
+	}
+	if config.LogRotateBytes != 1048576 {
+		t.Fatalf("bad: %#v", config)
+	}
+	if config.LogRotateMaxAge != 24*time.Hour {
+		t.Fatalf("bad: %#v", config)
+	}
+	if config.LogRotateMaxFiles != 5 {
+		t.Fatalf("bad: %#v", config)
+	}
+
 	// Node info
 	input = `{"node_id": "bar", "disable_host_node_id": true, "node_name": "foo", "datacenter": "dc2"}`
 	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
@@ -471,6 +495,20 @@ func TestDecodeConfig(t *testing.T) {
 		t.Fatalf("bad: %#v", config)
 	}
 
+	// Retry join SRV names and file
+	input = `{"retry_join_srv": ["consul.example.com"], "retry_join_file": "/etc/consul/servers"}`
+	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if len(config.RetryJoinSRV) != 1 || config.RetryJoinSRV[0] != "consul.example.com" {
+		t.Fatalf("bad: %#v", config)
+	}
+	if config.RetryJoinFile != "/etc/consul/servers" {
+		t.Fatalf("bad: %#v", config)
+	}
+
 	// Retry interval
 	input = `{"retry_interval": "10s"}`
 	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
@@ -561,6 +599,38 @@ func TestDecodeConfig(t *testing.T) {
 		t.Fatalf("decode should have failed")
 	}
 
+	// Prepared query cache TTL
+	input = `{"prepared_query_cache_ttl": "10s"}`
+	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config.PreparedQueryCacheTTLRaw != "10s" ||
+		config.PreparedQueryCacheTTL != 10*time.Second {
+		t.Fatalf("bad: %#v", config)
+	}
+	input = `{"prepared_query_cache_ttl": "-1s"}`
+	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
+	if err == nil {
+		t.Fatalf("decode should have failed")
+	}
+
+	// Durable event retention
+	input = `{"durable_event_retention": "24h"}`
+	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if config.DurableEventRetentionRaw != "24h" ||
+		config.DurableEventRetention != 24*time.Hour {
+		t.Fatalf("bad: %#v", config)
+	}
+	input = `{"durable_event_retention": "0s"}`
+	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
+	if err == nil {
+		t.Fatalf("decode should have failed")
+	}
+
 	// Static UI server
 	input = `{"ui": true}`
 	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
@@ -790,6 +860,16 @@ func TestDecodeConfig(t *testing.T) {
 		t.Fatalf("bad: %#v", config)
 	}
 
+	// ACL token persistence
+	input = `{"acl_enable_token_persistence": true}`
+	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !config.ACLEnableTokenPersistence {
+		t.Fatalf("bad: %#v", config)
+	}
+
 	// Watches
 	input = `{"watches": [{"type":"keyprefix", "prefix":"foo/", "handler":"foobar"}]}`
 	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
@@ -1058,6 +1138,28 @@ func TestDecodeConfig(t *testing.T) {
 	if config.SessionTTLMin != 5*time.Second {
 		t.Fatalf("bad: %s %#v", config.SessionTTLMin.String(), config)
 	}
+
+	// Snapshot encryption key file
+	input = `{"snapshot_encryption_key_file": "/path/to/key"}`
+	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if config.SnapshotEncryptionKeyFile != "/path/to/key" {
+		t.Fatalf("bad: %#v", config)
+	}
+
+	// Read replica
+	input = `{"server": true, "read_replica": true}`
+	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	if !config.ReadReplica {
+		t.Fatalf("bad: %#v", config)
+	}
 }
 
 func TestDecodeConfig_invalidKeys(t *testing.T) {
@@ -1139,6 +1241,32 @@ func TestDecodeConfig_Performance(t *testing.T) {
 	if err == nil || !strings.Contains(err.Error(), "Performance.RaftMultiplier must be <=") {
 		t.Fatalf("bad: %v", err)
 	}
+
+	input = `{"performance": { "follower_reads": true }}`
+	config, err = DecodeConfig(bytes.NewReader([]byte(input)))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !config.Performance.FollowerReads {
+		t.Fatalf("bad: follower reads aren't set: %#v", config)
+	}
+}
+
+func TestDecodeConfig_RTTSort(t *testing.T) {
+	input := `{"rtt_sort": { "default_near_agent": true, "nearest": 3 }}`
+	config, err := DecodeConfig(bytes.NewReader([]byte(input)))
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !config.RTTSort.DefaultNearAgent || config.RTTSort.Nearest != 3 {
+		t.Fatalf("bad: %#v", config.RTTSort)
+	}
+
+	input = `{"rtt_sort": { "nearest": -1 }}`
+	_, err = DecodeConfig(bytes.NewReader([]byte(input)))
+	if err == nil || !strings.Contains(err.Error(), "RTTSort.Nearest must be >= 0") {
+		t.Fatalf("bad: %v", err)
+	}
 }
 
 func TestDecodeConfig_Autopilot(t *testing.T) {
@@ -1631,6 +1759,11 @@ func TestMergeConfig(t *testing.T) {
 	b := &Config{
 		Performance: Performance{
 			RaftMultiplier: 99,
+			FollowerReads:  true,
+		},
+		RTTSort: RTTSort{
+			DefaultNearAgent: true,
+			Nearest:          3,
 		},
 		Bootstrap:       true,
 		BootstrapExpect: 3,
@@ -1666,12 +1799,17 @@ func TestMergeConfig(t *testing.T) {
 			Server:  6,
 			HTTPS:   7,
 		},
+		PreparedQueryCacheTTLRaw: "10s",
+		PreparedQueryCacheTTL:    10 * time.Second,
+		DurableEventRetentionRaw: "24h",
+		DurableEventRetention:    24 * time.Hour,
 		Addresses: AddressConfig{
 			DNS:   "127.0.0.1",
 			HTTP:  "127.0.0.2",
 			HTTPS: "127.0.0.4",
 		},
 		Server:         true,
+		ReadReplica:    true,
 		LeaveOnTerm:    Bool(true),
 		SkipLeaveOnInt: Bool(true),
 		RaftProtocol:   3,
@@ -1757,8 +1895,9 @@ func TestMergeConfig(t *testing.T) {
 			AccessKeyID:     "foo",
 			SecretAccessKey: "bar",
 		},
-		SessionTTLMinRaw: "1000s",
-		SessionTTLMin:    1000 * time.Second,
+		SessionTTLMinRaw:          "1000s",
+		SessionTTLMin:             1000 * time.Second,
+		SnapshotEncryptionKeyFile: "/path/to/key",
 		AdvertiseAddrs: AdvertiseAddrsConfig{
 			SerfLan:    &net.TCPAddr{},
 			SerfLanRaw: "127.0.0.5:1231",
@@ -1768,6 +1907,15 @@ func TestMergeConfig(t *testing.T) {
 			RPCRaw:     "127.0.0.5:1233",
 		},
 	}
+	b.ACLEnableTokenPersistence = true
+	b.LogJSON = true
+	b.LogFile = "/var/log/consul.log"
+	b.LogRotateBytes = 1048576
+	b.LogRotateMaxAge = 24 * time.Hour
+	b.LogRotateMaxAgeRaw = "24h"
+	b.LogRotateMaxFiles = 5
+	b.RetryJoinSRV = []string{"consul.example.com"}
+	b.RetryJoinFile = "/etc/consul/servers"
 
 	c := MergeConfig(a, b)
 
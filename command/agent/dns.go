@@ -8,6 +8,7 @@ import (
 	"net"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/armon/go-metrics"
@@ -33,13 +34,17 @@ const (
 // service discovery endpoints using a DNS interface.
 type DNSServer struct {
 	agent        *Agent
-	config       *DNSConfig
 	dnsHandler   *dns.ServeMux
 	dnsServer    *dns.Server
 	dnsServerTCP *dns.Server
 	domain       string
-	recursors    []string
 	logger       *log.Logger
+
+	// config holds the *DNSConfig and recursors holds the validated
+	// []string of recursor addresses. Both are swapped out when the
+	// agent's configuration is reloaded.
+	config    atomic.Value
+	recursors atomic.Value
 }
 
 // Shutdown stops the DNS Servers
@@ -52,6 +57,33 @@ func (d *DNSServer) Shutdown() {
 	}
 }
 
+// Reload swaps in a new DNS configuration and set of recursors. Queries
+// that are already being answered finish with the previous settings.
+func (d *DNSServer) Reload(config *DNSConfig, recursors []string) error {
+	validatedRecursors := make([]string, len(recursors))
+	for idx, recursor := range recursors {
+		recursor, err := recursorAddr(recursor)
+		if err != nil {
+			return fmt.Errorf("Invalid recursor address: %v", err)
+		}
+		validatedRecursors[idx] = recursor
+	}
+
+	d.config.Store(config)
+	d.recursors.Store(validatedRecursors)
+	return nil
+}
+
+// dnsConfig returns the current DNS configuration.
+func (d *DNSServer) dnsConfig() *DNSConfig {
+	return d.config.Load().(*DNSConfig)
+}
+
+// dnsRecursors returns the current recursor addresses.
+func (d *DNSServer) dnsRecursors() []string {
+	return d.recursors.Load().([]string)
+}
+
 // NewDNSServer starts a new DNS server to provide an agent interface
 func NewDNSServer(agent *Agent, config *DNSConfig, logOutput io.Writer, domain string, bind string, recursors []string) (*DNSServer, error) {
 	// Make sure domain is FQDN, make it case insensitive for ServeMux
@@ -80,34 +112,30 @@ func NewDNSServer(agent *Agent, config *DNSConfig, logOutput io.Writer, domain s
 	// Create the server
 	srv := &DNSServer{
 		agent:        agent,
-		config:       config,
 		dnsHandler:   mux,
 		dnsServer:    server,
 		dnsServerTCP: serverTCP,
 		domain:       domain,
-		recursors:    recursors,
 		logger:       log.New(logOutput, "", log.LstdFlags),
 	}
+	if err := srv.Reload(config, recursors); err != nil {
+		return nil, err
+	}
 
 	// Register mux handler, for reverse lookup
 	mux.HandleFunc("arpa.", srv.handlePtr)
 
-	// Register mux handlers
+	// Register mux handlers. The recursor handler is always registered
+	// since recursors can be added when the configuration is reloaded,
+	// but it fails like an unhandled name if there aren't any.
 	mux.HandleFunc(domain, srv.handleQuery)
-	if len(recursors) > 0 {
-		validatedRecursors := make([]string, len(recursors))
-
-		for idx, recursor := range recursors {
-			recursor, err := recursorAddr(recursor)
-			if err != nil {
-				return nil, fmt.Errorf("Invalid recursor address: %v", err)
-			}
-			validatedRecursors[idx] = recursor
+	mux.HandleFunc(".", func(resp dns.ResponseWriter, req *dns.Msg) {
+		if len(srv.dnsRecursors()) == 0 {
+			dns.HandleFailed(resp, req)
+			return
 		}
-
-		srv.recursors = validatedRecursors
-		mux.HandleFunc(".", srv.handleRecurse)
-	}
+		srv.handleRecurse(resp, req)
+	})
 
 	wg.Add(2)
 
@@ -184,9 +212,9 @@ func (d *DNSServer) handlePtr(resp dns.ResponseWriter, req *dns.Msg) {
 	// Setup the message response
 	m := new(dns.Msg)
 	m.SetReply(req)
-	m.Compress = !d.config.DisableCompression
+	m.Compress = !d.dnsConfig().DisableCompression
 	m.Authoritative = true
-	m.RecursionAvailable = (len(d.recursors) > 0)
+	m.RecursionAvailable = (len(d.dnsRecursors()) > 0)
 
 	// Only add the SOA if requested
 	if req.Question[0].Qtype == dns.TypeSOA {
@@ -201,8 +229,8 @@ func (d *DNSServer) handlePtr(resp dns.ResponseWriter, req *dns.Msg) {
 	args := structs.DCSpecificRequest{
 		Datacenter: datacenter,
 		QueryOptions: structs.QueryOptions{
-			Token:      d.agent.config.ACLToken,
-			AllowStale: *d.config.AllowStale,
+			Token:      d.agent.tokens.UserToken(),
+			AllowStale: *d.dnsConfig().AllowStale,
 		},
 	}
 	var out structs.IndexedNodes
@@ -254,9 +282,9 @@ func (d *DNSServer) handleQuery(resp dns.ResponseWriter, req *dns.Msg) {
 	// Setup the message response
 	m := new(dns.Msg)
 	m.SetReply(req)
-	m.Compress = !d.config.DisableCompression
+	m.Compress = !d.dnsConfig().DisableCompression
 	m.Authoritative = true
-	m.RecursionAvailable = (len(d.recursors) > 0)
+	m.RecursionAvailable = (len(d.dnsRecursors()) > 0)
 
 	// Only add the SOA if requested
 	if req.Question[0].Qtype == dns.TypeSOA {
@@ -387,7 +415,7 @@ PARSE:
 					Name:   qName + d.domain,
 					Rrtype: dns.TypeA,
 					Class:  dns.ClassINET,
-					Ttl:    uint32(d.config.NodeTTL / time.Second),
+					Ttl:    uint32(d.dnsConfig().NodeTTL / time.Second),
 				},
 				A: ip,
 			})
@@ -403,7 +431,7 @@ PARSE:
 					Name:   qName + d.domain,
 					Rrtype: dns.TypeAAAA,
 					Class:  dns.ClassINET,
-					Ttl:    uint32(d.config.NodeTTL / time.Second),
+					Ttl:    uint32(d.dnsConfig().NodeTTL / time.Second),
 				},
 				AAAA: ip,
 			})
@@ -435,8 +463,8 @@ func (d *DNSServer) nodeLookup(network, datacenter, node string, req, resp *dns.
 		Datacenter: datacenter,
 		Node:       node,
 		QueryOptions: structs.QueryOptions{
-			Token:      d.agent.config.ACLToken,
-			AllowStale: *d.config.AllowStale,
+			Token:      d.agent.tokens.UserToken(),
+			AllowStale: *d.dnsConfig().AllowStale,
 		},
 	}
 	var out structs.IndexedNodeServices
@@ -449,7 +477,7 @@ RPC:
 
 	// Verify that request is not too stale, redo the request
 	if args.AllowStale {
-		if out.LastContact > d.config.MaxStale {
+		if out.LastContact > d.dnsConfig().MaxStale {
 			args.AllowStale = false
 			d.logger.Printf("[WARN] dns: Query results too stale, re-requesting")
 			goto RPC
@@ -469,7 +497,7 @@ RPC:
 	n := out.NodeServices.Node
 	addr := translateAddress(d.agent.config, datacenter, n.Address, n.TaggedAddresses)
 	records := d.formatNodeRecord(out.NodeServices.Node, addr,
-		req.Question[0].Name, qType, d.config.NodeTTL)
+		req.Question[0].Name, qType, d.dnsConfig().NodeTTL)
 	if records != nil {
 		resp.Answer = append(resp.Answer, records...)
 	}
@@ -638,11 +666,15 @@ func (d *DNSServer) serviceLookup(network, datacenter, service, tag string, req,
 		ServiceName: service,
 		ServiceTag:  tag,
 		TagFilter:   tag != "",
+		Source: structs.QuerySource{
+			Datacenter: datacenter,
+		},
 		QueryOptions: structs.QueryOptions{
-			Token:      d.agent.config.ACLToken,
-			AllowStale: *d.config.AllowStale,
+			Token:      d.agent.tokens.UserToken(),
+			AllowStale: *d.dnsConfig().AllowStale,
 		},
 	}
+	sorted := d.agent.setDefaultRTTSource(&args.Source)
 	var out structs.IndexedCheckServiceNodes
 RPC:
 	if err := d.agent.RPC("Health.ServiceNodes", &args, &out); err != nil {
@@ -653,7 +685,7 @@ RPC:
 
 	// Verify that request is not too stale, redo the request
 	if args.AllowStale {
-		if out.LastContact > d.config.MaxStale {
+		if out.LastContact > d.dnsConfig().MaxStale {
 			args.AllowStale = false
 			d.logger.Printf("[WARN] dns: Query results too stale, re-requesting")
 			goto RPC
@@ -664,16 +696,22 @@ RPC:
 
 	// Determine the TTL
 	var ttl time.Duration
-	if d.config.ServiceTTL != nil {
+	if d.dnsConfig().ServiceTTL != nil {
 		var ok bool
-		ttl, ok = d.config.ServiceTTL[service]
+		ttl, ok = d.dnsConfig().ServiceTTL[service]
 		if !ok {
-			ttl = d.config.ServiceTTL["*"]
+			ttl = d.dnsConfig().ServiceTTL["*"]
 		}
 	}
 
-	// Filter out any service nodes due to health checks
-	out.Nodes = out.Nodes.Filter(d.config.OnlyPassing)
+	// Filter out any service nodes due to health checks. Results sorted
+	// by RTT need to keep their order, and can be limited to the nearest
+	// instances.
+	if sorted {
+		out.Nodes = nearestHealthy(out.Nodes, d.dnsConfig().OnlyPassing, d.agent.config.RTTSort.Nearest)
+	} else {
+		out.Nodes = out.Nodes.Filter(d.dnsConfig().OnlyPassing)
+	}
 
 	// If we have no nodes, return not found!
 	if len(out.Nodes) == 0 {
@@ -682,8 +720,10 @@ RPC:
 		return
 	}
 
-	// Perform a random shuffle
-	out.Nodes.Shuffle()
+	// Perform a random shuffle, unless the nodes are sorted by RTT
+	if !sorted {
+		out.Nodes.Shuffle()
+	}
 
 	// Add various responses depending on the request
 	qType := req.Question[0].Qtype
@@ -695,10 +735,10 @@ RPC:
 
 	// If the network is not TCP, restrict the number of responses
 	if network != "tcp" {
-		wasTrimmed := trimUDPResponse(d.config, resp)
+		wasTrimmed := trimUDPResponse(d.dnsConfig(), resp)
 
 		// Flag that there are more records to return in the UDP response
-		if wasTrimmed && d.config.EnableTruncate {
+		if wasTrimmed && d.dnsConfig().EnableTruncate {
 			resp.Truncated = true
 		}
 	}
@@ -717,8 +757,8 @@ func (d *DNSServer) preparedQueryLookup(network, datacenter, query string, req,
 		Datacenter:    datacenter,
 		QueryIDOrName: query,
 		QueryOptions: structs.QueryOptions{
-			Token:      d.agent.config.ACLToken,
-			AllowStale: *d.config.AllowStale,
+			Token:      d.agent.tokens.UserToken(),
+			AllowStale: *d.dnsConfig().AllowStale,
 		},
 
 		// Always pass the local agent through. In the DNS interface, there
@@ -739,10 +779,9 @@ func (d *DNSServer) preparedQueryLookup(network, datacenter, query string, req,
 	// likely work in practice, like 10*maxUDPAnswerLimit which should help
 	// reduce bandwidth if there are thousands of nodes available.
 
-	endpoint := d.agent.getEndpoint(preparedQueryEndpoint)
 	var out structs.PreparedQueryExecuteResponse
 RPC:
-	if err := d.agent.RPC(endpoint+".Execute", &args, &out); err != nil {
+	if err := d.agent.executePreparedQuery(&args, &out); err != nil {
 		// If they give a bogus query name, treat that as a name error,
 		// not a full on server error. We have to use a string compare
 		// here since the RPC layer loses the type information.
@@ -759,7 +798,7 @@ RPC:
 
 	// Verify that request is not too stale, redo the request.
 	if args.AllowStale {
-		if out.LastContact > d.config.MaxStale {
+		if out.LastContact > d.dnsConfig().MaxStale {
 			args.AllowStale = false
 			d.logger.Printf("[WARN] dns: Query results too stale, re-requesting")
 			goto RPC
@@ -779,11 +818,11 @@ RPC:
 		if err != nil {
 			d.logger.Printf("[WARN] dns: Failed to parse TTL '%s' for prepared query '%s', ignoring", out.DNS.TTL, query)
 		}
-	} else if d.config.ServiceTTL != nil {
+	} else if d.dnsConfig().ServiceTTL != nil {
 		var ok bool
-		ttl, ok = d.config.ServiceTTL[out.Service]
+		ttl, ok = d.dnsConfig().ServiceTTL[out.Service]
 		if !ok {
-			ttl = d.config.ServiceTTL["*"]
+			ttl = d.dnsConfig().ServiceTTL["*"]
 		}
 	}
 
@@ -804,10 +843,10 @@ RPC:
 
 	// If the network is not TCP, restrict the number of responses.
 	if network != "tcp" {
-		wasTrimmed := trimUDPResponse(d.config, resp)
+		wasTrimmed := trimUDPResponse(d.dnsConfig(), resp)
 
 		// Flag that there are more records to return in the UDP response
-		if wasTrimmed && d.config.EnableTruncate {
+		if wasTrimmed && d.dnsConfig().EnableTruncate {
 			resp.Truncated = true
 		}
 	}
@@ -933,17 +972,17 @@ func (d *DNSServer) handleRecurse(resp dns.ResponseWriter, req *dns.Msg) {
 	}
 
 	// Recursively resolve
-	c := &dns.Client{Net: network, Timeout: d.config.RecursorTimeout}
+	c := &dns.Client{Net: network, Timeout: d.dnsConfig().RecursorTimeout}
 	var r *dns.Msg
 	var rtt time.Duration
 	var err error
-	for _, recursor := range d.recursors {
+	for _, recursor := range d.dnsRecursors() {
 		r, rtt, err = c.Exchange(req, recursor)
 		if err == nil || err == dns.ErrTruncated {
 			// Compress the response; we don't know if the incoming
 			// response was compressed or not, so by not compressing
 			// we might generate an invalid packet on the way out.
-			r.Compress = !d.config.DisableCompression
+			r.Compress = !d.dnsConfig().DisableCompression
 
 			// Forward the response
 			d.logger.Printf("[DEBUG] dns: recurse RTT for %v (%v)", q, rtt)
@@ -960,7 +999,7 @@ func (d *DNSServer) handleRecurse(resp dns.ResponseWriter, req *dns.Msg) {
 		q, resp.RemoteAddr().String(), resp.RemoteAddr().Network())
 	m := &dns.Msg{}
 	m.SetReply(req)
-	m.Compress = !d.config.DisableCompression
+	m.Compress = !d.dnsConfig().DisableCompression
 	m.RecursionAvailable = true
 	m.SetRcode(req, dns.RcodeServerFailure)
 	resp.WriteMsg(m)
@@ -982,7 +1021,7 @@ func (d *DNSServer) resolveCNAME(name string) []dns.RR {
 	}
 
 	// Do nothing if we don't have a recursor
-	if len(d.recursors) == 0 {
+	if len(d.dnsRecursors()) == 0 {
 		return nil
 	}
 
@@ -991,11 +1030,11 @@ func (d *DNSServer) resolveCNAME(name string) []dns.RR {
 	m.SetQuestion(name, dns.TypeA)
 
 	// Make a DNS lookup request
-	c := &dns.Client{Net: "udp", Timeout: d.config.RecursorTimeout}
+	c := &dns.Client{Net: "udp", Timeout: d.dnsConfig().RecursorTimeout}
 	var r *dns.Msg
 	var rtt time.Duration
 	var err error
-	for _, recursor := range d.recursors {
+	for _, recursor := range d.dnsRecursors() {
 		r, rtt, err = c.Exchange(m, recursor)
 		if err == nil {
 			d.logger.Printf("[DEBUG] dns: cname recurse RTT for %v (%v)", name, rtt)
@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/hashicorp/serf/coordinate"
 	"github.com/miekg/dns"
 )
 
@@ -2434,6 +2435,78 @@ func TestDNS_ServiceLookup_OnlyPassing(t *testing.T) {
 	}
 }
 
+func TestDNS_ServiceLookup_DefaultNearAgent(t *testing.T) {
+	dir, srv := makeDNSServerConfig(t, func(c *Config) {
+		c.RTTSort.DefaultNearAgent = true
+		c.RTTSort.Nearest = 1
+	}, nil)
+	defer os.RemoveAll(dir)
+	defer srv.agent.Shutdown()
+
+	testrpc.WaitForLeader(t, srv.agent.RPC, "dc1")
+
+	// Register the service on two nodes, and put "bar" far away from the
+	// agent.
+	nodes := map[string]string{
+		"foo": "127.0.0.1",
+		"bar": "127.0.0.2",
+	}
+	for node, addr := range nodes {
+		args := &structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       node,
+			Address:    addr,
+			Service: &structs.NodeService{
+				Service: "db",
+				Port:    12345,
+			},
+		}
+		var out struct{}
+		if err := srv.agent.RPC("Catalog.Register", args, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	far := coordinate.NewCoordinate(coordinate.DefaultConfig())
+	far.Vec[0] = 1.0
+	coords := map[string]*coordinate.Coordinate{
+		"foo": coordinate.NewCoordinate(coordinate.DefaultConfig()),
+		"bar": far,
+	}
+	coords[srv.agent.config.NodeName] = coordinate.NewCoordinate(coordinate.DefaultConfig())
+	for node, coord := range coords {
+		arg := structs.CoordinateUpdateRequest{
+			Datacenter: "dc1",
+			Node:       node,
+			Coord:      coord,
+		}
+		var out struct{}
+		if err := srv.agent.RPC("Coordinate.Update", &arg, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Only the nearest node should be returned.
+	retry.Run(t, func(r *retry.R) {
+		m := new(dns.Msg)
+		m.SetQuestion("db.service.consul.", dns.TypeA)
+
+		c := new(dns.Client)
+		addr, _ := srv.agent.config.ClientListener("", srv.agent.config.Ports.DNS)
+		in, _, err := c.Exchange(m, addr.String())
+		if err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if len(in.Answer) != 1 {
+			r.Fatalf("Bad: %#v", in)
+		}
+		aRec, ok := in.Answer[0].(*dns.A)
+		if !ok || aRec.A.String() != "127.0.0.1" {
+			r.Fatalf("Bad: %#v", in.Answer[0])
+		}
+	})
+}
+
 func TestDNS_ServiceLookup_Randomize(t *testing.T) {
 	dir, srv := makeDNSServer(t)
 	defer os.RemoveAll(dir)
@@ -3663,7 +3736,7 @@ func TestDNS_ServiceLookup_FilterACL(t *testing.T) {
 	m.SetQuestion("foo.service.consul.", dns.TypeA)
 
 	// Query with the root token. Should get results.
-	srv.agent.config.ACLToken = "root"
+	srv.agent.tokens.UpdateUserToken("root")
 	in, _, err := c.Exchange(m, addr.String())
 	if err != nil {
 		t.Fatalf("err: %v", err)
@@ -3673,7 +3746,7 @@ func TestDNS_ServiceLookup_FilterACL(t *testing.T) {
 	}
 
 	// Query with a non-root token without access. Should get nothing.
-	srv.agent.config.ACLToken = "anonymous"
+	srv.agent.tokens.UpdateUserToken("anonymous")
 	in, _, err = c.Exchange(m, addr.String())
 	if err != nil {
 		t.Fatalf("err: %v", err)
@@ -4505,7 +4578,7 @@ func TestDNS_Compression_Query(t *testing.T) {
 		}
 
 		// Do a manual exchange with compression on (the default).
-		srv.config.DisableCompression = false
+		srv.dnsConfig().DisableCompression = false
 		if err := conn.WriteMsg(m); err != nil {
 			t.Fatalf("err: %v", err)
 		}
@@ -4516,7 +4589,7 @@ func TestDNS_Compression_Query(t *testing.T) {
 		}
 
 		// Disable compression and try again.
-		srv.config.DisableCompression = true
+		srv.dnsConfig().DisableCompression = true
 		if err := conn.WriteMsg(m); err != nil {
 			t.Fatalf("err: %v", err)
 		}
@@ -4572,7 +4645,7 @@ func TestDNS_Compression_ReverseLookup(t *testing.T) {
 	}
 
 	// Disable compression and try again.
-	srv.config.DisableCompression = true
+	srv.dnsConfig().DisableCompression = true
 	if err := conn.WriteMsg(m); err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -4618,7 +4691,7 @@ func TestDNS_Compression_Recurse(t *testing.T) {
 	}
 
 	// Disable compression and try again.
-	srv.config.DisableCompression = true
+	srv.dnsConfig().DisableCompression = true
 	if err := conn.WriteMsg(m); err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -0,0 +1,85 @@
+package agent
+
+import (
+	"time"
+
+	"github.com/hashicorp/consul/consul/structs"
+	"github.com/hashicorp/consul/lib"
+)
+
+const (
+	// durableEventRetryIntv is how long we wait between attempts to fetch
+	// missed durable events from the servers at startup.
+	durableEventRetryIntv = 15 * time.Second
+)
+
+// ackDurableEvent records with the servers that this node has processed the
+// given durable user event.
+func (a *Agent) ackDurableEvent(id string) {
+	req := structs.DurableEventAckRequest{
+		Datacenter: a.config.Datacenter,
+		Ack: structs.DurableEventAck{
+			EventID: id,
+			Node:    a.config.NodeName,
+		},
+		WriteRequest: structs.WriteRequest{Token: a.tokens.AgentToken()},
+	}
+	var reply struct{}
+	if err := a.RPC("Internal.EventAck", &req, &reply); err != nil {
+		a.logger.Printf("[ERR] agent: failed to ack durable event (%s): %v", id, err)
+	}
+}
+
+// catchUpDurableEvents runs once at startup and processes any durable user
+// events that were fired while this agent was offline. It retries until the
+// servers can be reached or the agent shuts down.
+func (a *Agent) catchUpDurableEvents() {
+	for {
+		if err := a.syncDurableEvents(); err != nil {
+			a.logger.Printf("[DEBUG] agent: failed to fetch durable events: %v", err)
+		} else {
+			return
+		}
+
+		select {
+		case <-time.After(durableEventRetryIntv + lib.RandomStagger(durableEventRetryIntv)):
+		case <-a.shutdownCh:
+			return
+		}
+	}
+}
+
+// syncDurableEvents fetches the durable events known to the servers and
+// ingests any that this node hasn't acknowledged yet.
+func (a *Agent) syncDurableEvents() error {
+	args := structs.DCSpecificRequest{
+		Datacenter:   a.config.Datacenter,
+		QueryOptions: structs.QueryOptions{Token: a.tokens.AgentToken()},
+	}
+	var reply structs.IndexedDurableEvents
+	if err := a.RPC("Internal.DurableEventList", &args, &reply); err != nil {
+		return err
+	}
+
+	acked := make(map[string]struct{})
+	for _, ack := range reply.Acks {
+		if ack.Node == a.config.NodeName {
+			acked[ack.EventID] = struct{}{}
+		}
+	}
+
+	for _, event := range reply.Events {
+		if _, ok := acked[event.ID]; ok {
+			continue
+		}
+
+		msg := new(UserEvent)
+		if err := decodeMsgPack(event.Payload, msg); err != nil {
+			a.logger.Printf("[ERR] agent: Failed to decode durable event (%s): %v", event.ID, err)
+			continue
+		}
+		msg.LTime = event.CreateIndex
+		a.processUserEvent(msg)
+	}
+	return nil
+}
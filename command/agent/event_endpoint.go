@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -51,6 +52,9 @@ func (s *HTTPServer) EventFire(resp http.ResponseWriter, req *http.Request) (int
 	if filt := req.URL.Query().Get("tag"); filt != "" {
 		event.TagFilter = filt
 	}
+	if _, ok := req.URL.Query()["durable"]; ok {
+		event.Durable = true
+	}
 
 	// Get the payload
 	if req.ContentLength > 0 {
@@ -76,8 +80,21 @@ func (s *HTTPServer) EventFire(resp http.ResponseWriter, req *http.Request) (int
 	return event, nil
 }
 
+// durableUserEvent is a durable user event along with the nodes that have
+// acknowledged it.
+type durableUserEvent struct {
+	*UserEvent
+	Acks []string
+}
+
 // EventList is used to retrieve the recent list of events
 func (s *HTTPServer) EventList(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	// Durable events come from the servers, so they are handled as a
+	// regular blocking query.
+	if _, ok := req.URL.Query()["durable"]; ok {
+		return s.durableEventList(resp, req)
+	}
+
 	// Parse the query options, since we simulate a blocking query
 	var b structs.QueryOptions
 	if parseWait(resp, req, &b) {
@@ -184,6 +201,49 @@ RUN_QUERY:
 	return events, nil
 }
 
+// durableEventList returns the durable events recorded by the servers, along
+// with the nodes that have acknowledged each of them.
+func (s *HTTPServer) durableEventList(resp http.ResponseWriter, req *http.Request) (interface{}, error) {
+	args := structs.DCSpecificRequest{}
+	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
+		return nil, nil
+	}
+	nameFilter := req.URL.Query().Get("name")
+
+	var out structs.IndexedDurableEvents
+	defer setMeta(resp, &out.QueryMeta)
+	if err := s.agent.RPC("Internal.DurableEventList", &args, &out); err != nil {
+		return nil, err
+	}
+
+	acks := make(map[string][]string)
+	for _, ack := range out.Acks {
+		acks[ack.EventID] = append(acks[ack.EventID], ack.Node)
+	}
+
+	events := make([]*durableUserEvent, 0, len(out.Events))
+	for _, event := range out.Events {
+		if nameFilter != "" && event.Name != nameFilter {
+			continue
+		}
+
+		msg := new(UserEvent)
+		if err := decodeMsgPack(event.Payload, msg); err != nil {
+			s.agent.logger.Printf("[ERR] agent: Failed to decode durable event (%s): %v", event.ID, err)
+			continue
+		}
+		msg.LTime = event.CreateIndex
+
+		nodes := acks[event.ID]
+		if nodes == nil {
+			nodes = make([]string, 0)
+		}
+		sort.Strings(nodes)
+		events = append(events, &durableUserEvent{msg, nodes})
+	}
+	return events, nil
+}
+
 // uuidToUint64 is a bit of a hack to generate a 64bit Consul index.
 // In effect, we take our random UUID, convert it to a 128 bit number,
 // then XOR the high-order and low-order 64bit's together to get the
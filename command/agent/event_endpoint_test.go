@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/hashicorp/consul/consul/structs"
+	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/consul/testutil/retry"
 )
 
@@ -140,6 +141,73 @@ func TestEventList(t *testing.T) {
 	})
 }
 
+func TestEventList_Durable(t *testing.T) {
+	httpTestWithConfig(t, func(srv *HTTPServer) {
+		testrpc.WaitForLeader(t, srv.agent.RPC, "dc1")
+
+		// Fire a regular event, which shouldn't show up.
+		if err := srv.agent.UserEvent("dc1", "root", &UserEvent{Name: "foo"}); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		body := bytes.NewBuffer([]byte("test"))
+		req, _ := http.NewRequest("PUT", "/v1/event/fire/test?durable", body)
+		resp := httptest.NewRecorder()
+		obj, err := srv.EventFire(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		fired := obj.(*UserEvent)
+		if !fired.Durable {
+			t.Fatalf("bad: %#v", fired)
+		}
+
+		// The agent should receive and acknowledge the event.
+		retry.Run(t, func(r *retry.R) {
+			req, _ := http.NewRequest("GET", "/v1/event/list?durable", nil)
+			resp := httptest.NewRecorder()
+			obj, err := srv.EventList(resp, req)
+			if err != nil {
+				r.Fatal(err)
+			}
+
+			list, ok := obj.([]*durableUserEvent)
+			if !ok {
+				r.Fatalf("bad: %#v", obj)
+			}
+			if len(list) != 1 {
+				r.Fatalf("bad: %#v", list)
+			}
+			event := list[0]
+			if event.ID != fired.ID || event.Name != "test" ||
+				string(event.Payload) != "test" || !event.Durable {
+				r.Fatalf("bad: %#v", event.UserEvent)
+			}
+			if len(event.Acks) != 1 || event.Acks[0] != srv.agent.config.NodeName {
+				r.Fatalf("bad: %#v", event.Acks)
+			}
+			header := resp.Header().Get("X-Consul-Index")
+			if header == "" || header == "0" {
+				r.Fatalf("bad: %#v", header)
+			}
+		})
+
+		// Filtering by name should exclude it.
+		req, _ = http.NewRequest("GET", "/v1/event/list?durable&name=nope", nil)
+		resp = httptest.NewRecorder()
+		obj, err = srv.EventList(resp, req)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if list := obj.([]*durableUserEvent); len(list) != 0 {
+			t.Fatalf("bad: %#v", list)
+		}
+	}, func(c *Config) {
+		// Durable events need all the servers to support them.
+		c.Version = "0.8.4"
+	})
+}
+
 func TestEventList_Filter(t *testing.T) {
 	httpTest(t, func(srv *HTTPServer) {
 		p := &UserEvent{Name: "test"}
@@ -123,6 +123,7 @@ func (s *HTTPServer) HealthServiceNodes(resp http.ResponseWriter, req *http.Requ
 	if done := s.parse(resp, req, &args.Datacenter, &args.QueryOptions); done {
 		return nil, nil
 	}
+	defaultNear := s.agent.setDefaultRTTSource(&args.Source)
 
 	// Check for a tag
 	params := req.URL.Query()
@@ -146,8 +147,15 @@ func (s *HTTPServer) HealthServiceNodes(resp http.ResponseWriter, req *http.Requ
 		return nil, err
 	}
 
-	// Filter to only passing if specified
-	if _, ok := params[api.HealthPassing]; ok {
+	// Filter to only passing if specified. Results sorted by RTT need to
+	// keep their order, and results sorted near this agent by default can
+	// be limited to the nearest instances.
+	_, passing := params[api.HealthPassing]
+	if n := s.agent.config.RTTSort.Nearest; defaultNear && n > 0 {
+		out.Nodes = nearestHealthy(out.Nodes, passing, n)
+	} else if passing && s.agent.isSortedByRTT(args.Source) {
+		out.Nodes = nearestHealthy(out.Nodes, true, 0)
+	} else if passing {
 		out.Nodes = filterNonPassing(out.Nodes)
 	}
 
@@ -606,6 +606,100 @@ func TestHealthServiceNodes_DistanceSort(t *testing.T) {
 	})
 }
 
+func TestHealthServiceNodes_DefaultNearAgent(t *testing.T) {
+	dir, srv := makeHTTPServerWithConfig(t, func(c *Config) {
+		c.RTTSort.DefaultNearAgent = true
+		c.RTTSort.Nearest = 1
+	})
+	defer os.RemoveAll(dir)
+	defer srv.Shutdown()
+	defer srv.agent.Shutdown()
+
+	testrpc.WaitForLeader(t, srv.agent.RPC, "dc1")
+
+	// Register the service on two nodes, and put "bar" far away from the
+	// agent. The nearer instance on "foo" has a warning.
+	args := &structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "bar",
+		Address:    "127.0.0.1",
+		Service: &structs.NodeService{
+			ID:      "test",
+			Service: "test",
+		},
+		Check: &structs.HealthCheck{
+			Node:      "bar",
+			Name:      "test check",
+			ServiceID: "test",
+			Status:    api.HealthPassing,
+		},
+	}
+	var out struct{}
+	if err := srv.agent.RPC("Catalog.Register", args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	args.Node, args.Check.Node, args.Check.Status = "foo", "foo", api.HealthWarning
+	if err := srv.agent.RPC("Catalog.Register", args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	far := coordinate.NewCoordinate(coordinate.DefaultConfig())
+	far.Vec[0] = 1.0
+	coords := map[string]*coordinate.Coordinate{
+		"foo": coordinate.NewCoordinate(coordinate.DefaultConfig()),
+		"bar": far,
+	}
+	coords[srv.agent.config.NodeName] = coordinate.NewCoordinate(coordinate.DefaultConfig())
+	for node, coord := range coords {
+		arg := structs.CoordinateUpdateRequest{
+			Datacenter: "dc1",
+			Node:       node,
+			Coord:      coord,
+		}
+		if err := srv.agent.RPC("Coordinate.Update", &arg, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Only the nearest instance should be returned, without ?near. The
+	// warning doesn't rule it out unless ?passing is given.
+	retry.Run(t, func(r *retry.R) {
+		req, _ := http.NewRequest("GET", "/v1/health/service/test", nil)
+		resp := httptest.NewRecorder()
+		obj, err := srv.HealthServiceNodes(resp, req)
+		if err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		nodes := obj.(structs.CheckServiceNodes)
+		if len(nodes) != 1 || nodes[0].Node.Node != "foo" {
+			r.Fatalf("bad: %v", nodes)
+		}
+	})
+	req, _ := http.NewRequest("GET", "/v1/health/service/test?passing", nil)
+	resp := httptest.NewRecorder()
+	obj, err := srv.HealthServiceNodes(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	nodes := obj.(structs.CheckServiceNodes)
+	if len(nodes) != 1 || nodes[0].Node.Node != "bar" {
+		t.Fatalf("bad: %v", nodes)
+	}
+
+	// An explicit source still wins, and isn't limited to the nearest
+	// instances.
+	req, _ = http.NewRequest("GET", "/v1/health/service/test?near=bar", nil)
+	resp = httptest.NewRecorder()
+	obj, err = srv.HealthServiceNodes(resp, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	nodes = obj.(structs.CheckServiceNodes)
+	if len(nodes) != 2 || nodes[0].Node.Node != "bar" || nodes[1].Node.Node != "foo" {
+		t.Fatalf("bad: %v", nodes)
+	}
+}
+
 func TestHealthServiceNodes_PassingFilter(t *testing.T) {
 	dir, srv := makeHTTPServer(t)
 	defer os.RemoveAll(dir)
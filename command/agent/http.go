@@ -30,6 +30,10 @@ type HTTPServer struct {
 	logger   *log.Logger
 	uiDir    string
 	addr     string
+
+	// tlsConfigurator is used to reload the TLS material of an HTTPS
+	// server. It is nil for plain HTTP servers.
+	tlsConfigurator *tlsutil.Configurator
 }
 
 // NewHTTPServers starts new HTTP servers to provide an interface to
@@ -61,10 +65,11 @@ func NewHTTPServers(agent *Agent, config *Config, logOutput io.Writer) ([]*HTTPS
 			PreferServerCipherSuites: config.TLSPreferServerCipherSuites,
 		}
 
-		tlsConfig, err := tlsConf.IncomingTLSConfig()
+		tlsConfigurator, err := tlsutil.NewConfigurator(tlsConf)
 		if err != nil {
 			return nil, err
 		}
+		tlsConfig := tlsConfigurator.IncomingTLSConfig()
 
 		ln, err := net.Listen(httpAddr.Network(), httpAddr.String())
 		if err != nil {
@@ -84,6 +89,8 @@ func NewHTTPServers(agent *Agent, config *Config, logOutput io.Writer) ([]*HTTPS
 			logger:   log.New(logOutput, "", log.LstdFlags),
 			uiDir:    config.UIDir,
 			addr:     httpAddr.String(),
+
+			tlsConfigurator: tlsConfigurator,
 		}
 		srv.registerHandlers(config.EnableDebug)
 
@@ -172,6 +179,15 @@ func (s *HTTPServer) Shutdown() {
 	}
 }
 
+// ReloadTLS re-reads the TLS material of an HTTPS server. It is a no-op for
+// plain HTTP servers.
+func (s *HTTPServer) ReloadTLS() error {
+	if s.tlsConfigurator == nil {
+		return nil
+	}
+	return s.tlsConfigurator.Reload()
+}
+
 // handleFuncMetrics takes the given pattern and handler and wraps to produce
 // metrics based on the pattern and request.
 func (s *HTTPServer) handleFuncMetrics(pattern string, handler func(http.ResponseWriter, *http.Request)) {
@@ -228,7 +244,9 @@ func (s *HTTPServer) registerHandlers(enableDebug bool) {
 	s.handleFuncMetrics("/v1/agent/self", s.wrap(s.AgentSelf))
 	s.handleFuncMetrics("/v1/agent/maintenance", s.wrap(s.AgentNodeMaintenance))
 	s.handleFuncMetrics("/v1/agent/reload", s.wrap(s.AgentReload))
+	s.handleFuncMetrics("/v1/agent/token/", s.wrap(s.AgentToken))
 	s.handleFuncMetrics("/v1/agent/monitor", s.wrap(s.AgentMonitor))
+	s.handleFuncMetrics("/v1/agent/metrics", s.wrap(s.AgentMetrics))
 	s.handleFuncMetrics("/v1/agent/services", s.wrap(s.AgentServices))
 	s.handleFuncMetrics("/v1/agent/checks", s.wrap(s.AgentChecks))
 	s.handleFuncMetrics("/v1/agent/members", s.wrap(s.AgentMembers))
@@ -539,7 +557,7 @@ func (s *HTTPServer) parseToken(req *http.Request, token *string) {
 	}
 
 	// Set the default ACLToken
-	*token = s.agent.config.ACLToken
+	*token = s.agent.tokens.UserToken()
 }
 
 // parseSource is used to parse the ?near=<node> query parameter, used for
@@ -540,14 +540,14 @@ func TestACLResolution(t *testing.T) {
 
 	httpTest(t, func(srv *HTTPServer) {
 		// Check when no token is set
-		srv.agent.config.ACLToken = ""
+		srv.agent.tokens.UpdateUserToken("")
 		srv.parseToken(req, &token)
 		if token != "" {
 			t.Fatalf("bad: %s", token)
 		}
 
 		// Check when ACLToken set
-		srv.agent.config.ACLToken = "agent"
+		srv.agent.tokens.UpdateUserToken("agent")
 		srv.parseToken(req, &token)
 		if token != "agent" {
 			t.Fatalf("bad: %s", token)
@@ -1,6 +1,7 @@
 package agent
 
 import (
+	"fmt"
 	"log"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -11,10 +12,39 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
-// discoverEc2Hosts searches an AWS region, returning a list of instance ips
-// where EC2TagKey = EC2TagValue
-func (c *Config) discoverEc2Hosts(logger *log.Logger) ([]string, error) {
-	config := c.RetryJoinEC2
+// ec2JoinProvider discovers servers by their tags in EC2. It's used for the
+// "aws" join provider and the retry_join_ec2 configuration.
+type ec2JoinProvider struct {
+	config RetryJoinEC2
+}
+
+// newEC2JoinProvider creates an EC2 join provider from the arguments of a
+// "provider=aws" retry_join entry.
+func newEC2JoinProvider(args map[string]string) (joinProvider, error) {
+	if err := checkJoinArgs(args, "region", "tag_key", "tag_value", "access_key_id", "secret_access_key"); err != nil {
+		return nil, err
+	}
+	config := RetryJoinEC2{
+		Region:          args["region"],
+		TagKey:          args["tag_key"],
+		TagValue:        args["tag_value"],
+		AccessKeyID:     args["access_key_id"],
+		SecretAccessKey: args["secret_access_key"],
+	}
+	if config.TagKey == "" || config.TagValue == "" {
+		return nil, fmt.Errorf("tag_key and tag_value are both required")
+	}
+	return &ec2JoinProvider{config: config}, nil
+}
+
+func (p *ec2JoinProvider) Name() string {
+	return "EC2"
+}
+
+// Addrs searches an AWS region, returning a list of instance ips where
+// TagKey = TagValue
+func (p *ec2JoinProvider) Addrs(logger *log.Logger) ([]string, error) {
+	config := p.config
 
 	ec2meta := ec2metadata.New(session.New())
 	if config.Region == "" {
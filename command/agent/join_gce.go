@@ -13,10 +13,39 @@ import (
 	compute "google.golang.org/api/compute/v1"
 )
 
-// discoverGCEHosts searches a Google Compute Engine region, returning a list
-// of instance ips that match the tags given in GCETags.
-func (c *Config) discoverGCEHosts(logger *log.Logger) ([]string, error) {
-	config := c.RetryJoinGCE
+// gceJoinProvider discovers servers by their tags in Google Compute Engine.
+// It's used for the "gce" join provider and the retry_join_gce
+// configuration.
+type gceJoinProvider struct {
+	config RetryJoinGCE
+}
+
+// newGCEJoinProvider creates a GCE join provider from the arguments of a
+// "provider=gce" retry_join entry.
+func newGCEJoinProvider(args map[string]string) (joinProvider, error) {
+	if err := checkJoinArgs(args, "project_name", "zone_pattern", "tag_value", "credentials_file"); err != nil {
+		return nil, err
+	}
+	config := RetryJoinGCE{
+		ProjectName:     args["project_name"],
+		ZonePattern:     args["zone_pattern"],
+		TagValue:        args["tag_value"],
+		CredentialsFile: args["credentials_file"],
+	}
+	if config.TagValue == "" {
+		return nil, fmt.Errorf("tag_value is required")
+	}
+	return &gceJoinProvider{config: config}, nil
+}
+
+func (p *gceJoinProvider) Name() string {
+	return "GCE"
+}
+
+// Addrs searches a Google Compute Engine region, returning a list of
+// instance ips that match the tag given in TagValue.
+func (p *gceJoinProvider) Addrs(logger *log.Logger) ([]string, error) {
+	config := p.config
 	ctx := oauth2.NoContext
 	var client *http.Client
 	var err error
@@ -0,0 +1,379 @@
+package agent
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultJoinExecTimeout is how long the exec join provider waits for its
+// command to finish if no timeout is given.
+const defaultJoinExecTimeout = 30 * time.Second
+
+// joinSecretRe matches the arguments of a retry_join provider entry that
+// hold credentials, so they can be hidden when the config is displayed.
+var joinSecretRe = regexp.MustCompile(`((?:^|\s)(?:access_key_id|secret_access_key)=)("(?:[^"\\]|\\.)*"|\S*)`)
+
+// joinProvider is used by retryJoin to find the addresses of agents to join.
+// Providers are asked for addresses again on every attempt, so they can pick
+// up servers that have come and gone since the agent started.
+type joinProvider interface {
+	// Name is used to identify the provider in log messages.
+	Name() string
+
+	// Addrs returns the addresses of agents to join.
+	Addrs(logger *log.Logger) ([]string, error)
+}
+
+// joinProviderFactory creates a join provider from the key=value arguments
+// of a "provider=<name>" retry_join entry, leaving out the provider key.
+type joinProviderFactory func(args map[string]string) (joinProvider, error)
+
+// joinProviderFactories holds the providers that can be used in retry_join
+// entries, by name.
+var joinProviderFactories = map[string]joinProviderFactory{
+	"aws":  newEC2JoinProvider,
+	"dns":  newSRVJoinProvider,
+	"exec": newExecJoinProvider,
+	"file": newFileJoinProvider,
+	"gce":  newGCEJoinProvider,
+}
+
+// joinProviders returns the providers for the configured retry join
+// settings, in the order their addresses should be tried. Entries in
+// retry_join that contain key=value pairs are looked up in the provider
+// registry, and the rest are used as addresses.
+func (c *Config) joinProviders() ([]joinProvider, error) {
+	var providers []joinProvider
+	switch {
+	case c.RetryJoinEC2.TagKey != "" && c.RetryJoinEC2.TagValue != "":
+		providers = append(providers, &ec2JoinProvider{config: c.RetryJoinEC2})
+	case c.RetryJoinGCE.TagValue != "":
+		providers = append(providers, &gceJoinProvider{config: c.RetryJoinGCE})
+	}
+	if len(c.RetryJoinSRV) > 0 {
+		providers = append(providers, &srvJoinProvider{names: c.RetryJoinSRV})
+	}
+	if c.RetryJoinFile != "" {
+		providers = append(providers, &fileJoinProvider{path: c.RetryJoinFile})
+	}
+
+	var addrs []string
+	for _, entry := range c.RetryJoin {
+		if !strings.Contains(entry, "=") {
+			addrs = append(addrs, entry)
+			continue
+		}
+		provider, err := newJoinProvider(entry)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid retry_join entry %q: %v", redactJoinEntry(entry), err)
+		}
+		providers = append(providers, provider)
+	}
+	if len(addrs) > 0 {
+		providers = append(providers, &staticJoinProvider{addrs: addrs})
+	}
+	return providers, nil
+}
+
+// newJoinProvider creates the provider for a retry_join entry of the form
+// "provider=<name> key=value ...".
+func newJoinProvider(entry string) (joinProvider, error) {
+	args, err := parseJoinArgs(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	name, ok := args["provider"]
+	if !ok {
+		return nil, fmt.Errorf("missing provider")
+	}
+	factory, ok := joinProviderFactories[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown provider %q", name)
+	}
+	delete(args, "provider")
+	return factory(args)
+}
+
+// parseJoinArgs parses the space separated key=value pairs of a retry_join
+// provider entry. Values containing spaces can be double quoted, using Go
+// string escapes.
+func parseJoinArgs(entry string) (map[string]string, error) {
+	args := make(map[string]string)
+	s := strings.TrimSpace(entry)
+	for s != "" {
+		eq := strings.Index(s, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("expected key=value, got %q", s)
+		}
+		key := s[:eq]
+		if key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("expected key=value, got %q", strings.Fields(s)[0])
+		}
+		s = s[eq+1:]
+
+		var value string
+		if strings.HasPrefix(s, `"`) {
+			end := 1
+			for ; end < len(s); end++ {
+				if s[end] == '\\' {
+					end++
+				} else if s[end] == '"' {
+					break
+				}
+			}
+			if end >= len(s) {
+				return nil, fmt.Errorf("unterminated quote in value for %q", key)
+			}
+			var err error
+			if value, err = strconv.Unquote(s[:end+1]); err != nil {
+				return nil, fmt.Errorf("invalid quoted value for %q: %v", key, err)
+			}
+			s = s[end+1:]
+			if s != "" && s[0] != ' ' && s[0] != '\t' {
+				return nil, fmt.Errorf("expected space after value for %q", key)
+			}
+		} else {
+			end := strings.IndexAny(s, " \t")
+			if end < 0 {
+				end = len(s)
+			}
+			value, s = s[:end], s[end:]
+		}
+
+		if _, ok := args[key]; ok {
+			return nil, fmt.Errorf("duplicate key %q", key)
+		}
+		args[key] = value
+		s = strings.TrimLeft(s, " \t")
+	}
+	return args, nil
+}
+
+// checkJoinArgs returns an error if any of the arguments given to a join
+// provider aren't in the allowed list.
+func checkJoinArgs(args map[string]string, allowed ...string) error {
+	var unknown []string
+	for key := range args {
+		found := false
+		for _, a := range allowed {
+			if key == a {
+				found = true
+				break
+			}
+		}
+		if !found {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown keys: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// redactJoinEntry hides any credentials in a retry_join entry.
+func redactJoinEntry(entry string) string {
+	return joinSecretRe.ReplaceAllString(entry, "${1}hidden")
+}
+
+// redactRetryJoin returns the retry_join entries with any credentials
+// hidden, so they can be displayed.
+func redactRetryJoin(entries []string) []string {
+	if entries == nil {
+		return nil
+	}
+	redacted := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		redacted = append(redacted, redactJoinEntry(entry))
+	}
+	return redacted
+}
+
+// staticJoinProvider returns the fixed list of addresses from retry_join.
+type staticJoinProvider struct {
+	addrs []string
+}
+
+func (p *staticJoinProvider) Name() string {
+	return "retry_join"
+}
+
+func (p *staticJoinProvider) Addrs(logger *log.Logger) ([]string, error) {
+	return p.addrs, nil
+}
+
+// srvJoinProvider discovers servers by looking up DNS SRV records. Each
+// record gives the host and Serf LAN port of one agent. It's used for the
+// "dns" join provider and the retry_join_srv configuration.
+type srvJoinProvider struct {
+	names []string
+
+	// lookupSRV is used to look up the records. It's net.LookupSRV unless
+	// it's replaced for testing.
+	lookupSRV func(service, proto, name string) (string, []*net.SRV, error)
+}
+
+// newSRVJoinProvider creates a DNS SRV join provider from the arguments of
+// a "provider=dns" retry_join entry.
+func newSRVJoinProvider(args map[string]string) (joinProvider, error) {
+	if err := checkJoinArgs(args, "name"); err != nil {
+		return nil, err
+	}
+	if args["name"] == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+	return &srvJoinProvider{names: []string{args["name"]}}, nil
+}
+
+func (p *srvJoinProvider) Name() string {
+	return "DNS SRV records"
+}
+
+func (p *srvJoinProvider) Addrs(logger *log.Logger) ([]string, error) {
+	lookup := p.lookupSRV
+	if lookup == nil {
+		lookup = net.LookupSRV
+	}
+
+	var addrs []string
+	for _, name := range p.names {
+		_, records, err := lookup("", "", name)
+		if err != nil {
+			return nil, fmt.Errorf("failed looking up %q: %v", name, err)
+		}
+		for _, record := range records {
+			host := strings.TrimSuffix(record.Target, ".")
+			addrs = append(addrs, net.JoinHostPort(host, fmt.Sprintf("%d", record.Port)))
+		}
+	}
+	return addrs, nil
+}
+
+// fileJoinProvider reads addresses from a file, one per line. Blank lines
+// and lines starting with # are ignored. The file is read again on every
+// attempt, so it can be kept up to date by an external tool. It's used for
+// the "file" join provider and the retry_join_file configuration.
+type fileJoinProvider struct {
+	path string
+}
+
+// newFileJoinProvider creates a file join provider from the arguments of a
+// "provider=file" retry_join entry.
+func newFileJoinProvider(args map[string]string) (joinProvider, error) {
+	if err := checkJoinArgs(args, "path"); err != nil {
+		return nil, err
+	}
+	if args["path"] == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	return &fileJoinProvider{path: args["path"]}, nil
+}
+
+func (p *fileJoinProvider) Name() string {
+	return fmt.Sprintf("file %q", p.path)
+}
+
+func (p *fileJoinProvider) Addrs(logger *log.Logger) ([]string, error) {
+	f, err := os.Open(p.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var addrs []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		addrs = append(addrs, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return addrs, nil
+}
+
+// execJoinProvider runs a command through the shell and joins the addresses
+// it prints, separated by whitespace. It's used for the "exec" join
+// provider, so environments without a built-in provider can be supported
+// by a script.
+type execJoinProvider struct {
+	command string
+	timeout time.Duration
+}
+
+// newExecJoinProvider creates an exec join provider from the arguments of a
+// "provider=exec" retry_join entry.
+func newExecJoinProvider(args map[string]string) (joinProvider, error) {
+	if err := checkJoinArgs(args, "command", "timeout"); err != nil {
+		return nil, err
+	}
+	if args["command"] == "" {
+		return nil, fmt.Errorf("command is required")
+	}
+
+	timeout := defaultJoinExecTimeout
+	if raw, ok := args["timeout"]; ok {
+		var err error
+		if timeout, err = time.ParseDuration(raw); err != nil {
+			return nil, fmt.Errorf("invalid timeout: %v", err)
+		}
+		if timeout <= 0 {
+			return nil, fmt.Errorf("timeout must be positive")
+		}
+	}
+	return &execJoinProvider{command: args["command"], timeout: timeout}, nil
+}
+
+func (p *execJoinProvider) Name() string {
+	return fmt.Sprintf("command %q", p.command)
+}
+
+func (p *execJoinProvider) Addrs(logger *log.Logger) ([]string, error) {
+	cmd, err := ExecScript(p.command)
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- cmd.Wait()
+	}()
+	select {
+	case err := <-errCh:
+		if err != nil {
+			if msg := strings.TrimSpace(stderr.String()); msg != "" {
+				return nil, fmt.Errorf("%v: %s", err, msg)
+			}
+			return nil, err
+		}
+	case <-time.After(p.timeout):
+		// Kill the command along with any children it started, so they
+		// aren't left running after we give up on it.
+		if err := killProcessGroup(cmd); err != nil {
+			logger.Printf("[ERR] agent: failed to kill join command: %v", err)
+		}
+		return nil, fmt.Errorf("timed out after %v", p.timeout)
+	}
+	return strings.Fields(stdout.String()), nil
+}
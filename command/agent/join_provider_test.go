@@ -0,0 +1,283 @@
+package agent
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/testutil"
+	"github.com/hashicorp/consul/testutil/retry"
+)
+
+func TestConfig_joinProviders(t *testing.T) {
+	c := &Config{
+		RetryJoin: []string{
+			"1.2.3.4",
+			"provider=aws tag_key=ConsulRole tag_value=Server",
+			"[::1]:8301",
+			`provider=exec command="echo 1.2.3.5"`,
+		},
+		RetryJoinSRV:  []string{"consul.example.com"},
+		RetryJoinFile: "/etc/consul/servers",
+		RetryJoinGCE:  RetryJoinGCE{TagValue: "consul-server"},
+	}
+
+	providers, err := c.joinProviders()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var names []string
+	for _, provider := range providers {
+		names = append(names, provider.Name())
+	}
+	expected := []string{
+		"GCE",
+		"DNS SRV records",
+		`file "/etc/consul/servers"`,
+		"EC2",
+		`command "echo 1.2.3.5"`,
+		"retry_join",
+	}
+	if !reflect.DeepEqual(names, expected) {
+		t.Fatalf("bad: %v", names)
+	}
+
+	// The plain addresses are gathered up into a single provider.
+	addrs, err := providers[len(providers)-1].Addrs(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if expected := []string{"1.2.3.4", "[::1]:8301"}; !reflect.DeepEqual(addrs, expected) {
+		t.Fatalf("bad: %v", addrs)
+	}
+
+	if got, err := (&Config{}).joinProviders(); err != nil || len(got) != 0 {
+		t.Fatalf("bad: %v %v", got, err)
+	}
+}
+
+func TestConfig_joinProviders_Invalid(t *testing.T) {
+	cases := []struct {
+		entry string
+		err   string
+	}{
+		{"tag_key=ConsulRole", "missing provider"},
+		{"provider=nope", `unknown provider "nope"`},
+		{"provider=aws tag_key=ConsulRole", "tag_key and tag_value are both required"},
+		{"provider=aws tag_key=a tag_value=b zone=c", "unknown keys: zone"},
+		{"provider=gce", "tag_value is required"},
+		{"provider=dns", "name is required"},
+		{"provider=file", "path is required"},
+		{"provider=exec", "command is required"},
+		{"provider=exec command=true timeout=soon", "invalid timeout"},
+		{"provider=aws tag_key=a tag_value=b secret_access_key=shh bad", `Invalid retry_join entry "provider=aws tag_key=a tag_value=b secret_access_key=hidden bad"`},
+	}
+	for _, tc := range cases {
+		c := &Config{RetryJoin: []string{tc.entry}}
+		_, err := c.joinProviders()
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("entry %q: got %v want %q", tc.entry, err, tc.err)
+		}
+	}
+}
+
+func TestParseJoinArgs(t *testing.T) {
+	cases := []struct {
+		entry string
+		args  map[string]string
+		err   string
+	}{
+		{
+			entry: "provider=aws  region=us-east-1\ttag_key=Role ",
+			args:  map[string]string{"provider": "aws", "region": "us-east-1", "tag_key": "Role"},
+		},
+		{
+			entry: `provider=exec command="find-servers -dc \"dc1\"" timeout=5s`,
+			args:  map[string]string{"provider": "exec", "command": `find-servers -dc "dc1"`, "timeout": "5s"},
+		},
+		{
+			entry: "provider= empty=",
+			args:  map[string]string{"provider": "", "empty": ""},
+		},
+		{entry: "provider=aws region", err: `expected key=value, got "region"`},
+		{entry: "provider=aws =us-east-1", err: `expected key=value, got "=us-east-1"`},
+		{entry: "provider=aws provider=gce", err: `duplicate key "provider"`},
+		{entry: `command="echo`, err: `unterminated quote in value for "command"`},
+		{entry: `command="echo"x`, err: `expected space after value for "command"`},
+	}
+	for _, tc := range cases {
+		args, err := parseJoinArgs(tc.entry)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("entry %q: got %v want %q", tc.entry, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("entry %q: err: %v", tc.entry, err)
+		}
+		if !reflect.DeepEqual(args, tc.args) {
+			t.Fatalf("entry %q: bad: %v", tc.entry, args)
+		}
+	}
+}
+
+func TestRedactRetryJoin(t *testing.T) {
+	entries := []string{
+		"1.2.3.4",
+		`provider=aws tag_key=a tag_value=b access_key_id=AKID secret_access_key="s e c r e t"`,
+	}
+	expected := []string{
+		"1.2.3.4",
+		"provider=aws tag_key=a tag_value=b access_key_id=hidden secret_access_key=hidden",
+	}
+	if got := redactRetryJoin(entries); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("bad: %v", got)
+	}
+	if got := redactRetryJoin(nil); got != nil {
+		t.Fatalf("bad: %v", got)
+	}
+}
+
+func TestSRVJoinProvider(t *testing.T) {
+	p := &srvJoinProvider{
+		names: []string{"_consul._tcp.dc1.example.com", "missing.example.com"},
+		lookupSRV: func(service, proto, name string) (string, []*net.SRV, error) {
+			if name != "_consul._tcp.dc1.example.com" {
+				return "", nil, fmt.Errorf("no such host")
+			}
+			return "", []*net.SRV{
+				{Target: "server1.example.com.", Port: 8301},
+				{Target: "10.0.0.2", Port: 9301},
+			}, nil
+		},
+	}
+
+	// A failed lookup fails the whole attempt.
+	if _, err := p.Addrs(log.New(os.Stderr, "", log.LstdFlags)); err == nil {
+		t.Fatalf("should fail")
+	}
+
+	p.names = p.names[:1]
+	addrs, err := p.Addrs(log.New(os.Stderr, "", log.LstdFlags))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := []string{"server1.example.com:8301", "10.0.0.2:9301"}
+	if !reflect.DeepEqual(addrs, expected) {
+		t.Fatalf("bad: %v", addrs)
+	}
+}
+
+func TestFileJoinProvider(t *testing.T) {
+	dir := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(dir)
+
+	p := &fileJoinProvider{path: filepath.Join(dir, "servers")}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+
+	// A missing file is an error.
+	if _, err := p.Addrs(logger); err == nil {
+		t.Fatalf("should fail")
+	}
+
+	contents := "# servers\n10.0.0.1\n\n  10.0.0.2:8301  \n"
+	if err := ioutil.WriteFile(p.path, []byte(contents), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	addrs, err := p.Addrs(logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if expected := []string{"10.0.0.1", "10.0.0.2:8301"}; !reflect.DeepEqual(addrs, expected) {
+		t.Fatalf("bad: %v", addrs)
+	}
+
+	// Changes to the file are picked up on the next call.
+	if err := ioutil.WriteFile(p.path, []byte("10.0.0.3\n"), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	addrs, err = p.Addrs(logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if expected := []string{"10.0.0.3"}; !reflect.DeepEqual(addrs, expected) {
+		t.Fatalf("bad: %v", addrs)
+	}
+}
+
+func TestExecJoinProvider(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses shell commands")
+	}
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+
+	p, err := newExecJoinProvider(map[string]string{
+		"command": "echo 10.0.0.1; echo '10.0.0.2:8301  [::1]:8301'",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	addrs, err := p.Addrs(logger)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if expected := []string{"10.0.0.1", "10.0.0.2:8301", "[::1]:8301"}; !reflect.DeepEqual(addrs, expected) {
+		t.Fatalf("bad: %v", addrs)
+	}
+
+	// A failing command's stderr is included in the error.
+	p = &execJoinProvider{command: "echo no servers >&2; exit 2", timeout: time.Minute}
+	if _, err := p.Addrs(logger); err == nil || !strings.Contains(err.Error(), "no servers") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Commands that run too long are given up on.
+	p = &execJoinProvider{command: "sleep 5", timeout: 50 * time.Millisecond}
+	if _, err := p.Addrs(logger); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+func TestExecJoinProvider_TimeoutChildren(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("checks for the child process in /proc")
+	}
+
+	dir := testutil.TempDir(t, "consul")
+	defer os.RemoveAll(dir)
+	pidFile := filepath.Join(dir, "child.pid")
+
+	// Children the command started should be killed along with it.
+	p := &execJoinProvider{
+		command: fmt.Sprintf("sleep 30 & echo $! > %s; wait", pidFile),
+		timeout: 500 * time.Millisecond,
+	}
+	if _, err := p.Addrs(log.New(os.Stderr, "", log.LstdFlags)); err == nil || !strings.Contains(err.Error(), "timed out") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	raw, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pid := strings.TrimSpace(string(raw))
+	retry.Run(t, func(r *retry.R) {
+		// The child is either gone or a zombie waiting to be reaped.
+		stat, err := ioutil.ReadFile(filepath.Join("/proc", pid, "stat"))
+		if err != nil {
+			return
+		}
+		if fields := strings.Fields(string(stat)); len(fields) < 3 || fields[2] != "Z" {
+			r.Fatalf("child %s still running: %s", pid, stat)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package agent
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/consul"
+	"github.com/hashicorp/consul/consul/structs"
+	"github.com/hashicorp/consul/types"
+	"github.com/hashicorp/serf/serf"
+)
+
+const (
+	// largeEventPrefix is the KV prefix where payloads that are too large
+	// to gossip are stored, e.g. _event/event_id
+	largeEventPrefix = structs.EventPayloadPrefix
+
+	// largeEventTTL is the TTL of the session that the payload keys are
+	// bound to. Receivers must fetch the payload before it expires.
+	largeEventTTL = "1h"
+
+	// largeEventNameOverhead is the size of the prefix the servers add to
+	// the event name before firing it on Serf, which counts towards the
+	// size limit.
+	largeEventNameOverhead = len("consul:event:")
+)
+
+// isLargeUserEvent checks if an encoded event is too large to be gossiped.
+func isLargeUserEvent(name string, payload []byte) bool {
+	return largeEventNameOverhead+len(name)+len(payload) > serf.UserEventSizeLimit
+}
+
+// largeEventChecksum returns the checksum used to verify large payloads.
+func largeEventChecksum(payload []byte) string {
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// storeLargeEventPayload writes the event's payload to a KV key bound to a new
+// session in the given datacenter, and records the key and checksum in the
+// event so receivers can fetch it. Durable events may be replayed long after
+// the session would have gone away, so their payloads are written without one
+// and the servers delete them when the event is pruned.
+func (a *Agent) storeLargeEventPayload(dc, token string, params *UserEvent) error {
+	key := largeEventPrefix + params.ID
+	if params.Durable {
+		write := structs.KVSRequest{
+			Datacenter: dc,
+			Op:         api.KVSet,
+			DirEnt: structs.DirEntry{
+				Key:   key,
+				Value: params.Payload,
+			},
+			WriteRequest: structs.WriteRequest{Token: token},
+		}
+		var success bool
+		if err := a.RPC("KVS.Apply", &write, &success); err != nil {
+			return fmt.Errorf("failed to store payload: %v", err)
+		}
+		params.PayloadKey = key
+		params.PayloadChecksum = largeEventChecksum(params.Payload)
+		return nil
+	}
+
+	// Bind the session to this node when we can, so the payload goes
+	// away with it. In other datacenters we have to bind to one of the
+	// servers and rely on the TTL.
+	session := structs.Session{
+		Name:     fmt.Sprintf("Large user event '%s'", params.Name),
+		Node:     a.config.NodeName,
+		Checks:   []types.CheckID{consul.SerfCheckID},
+		Behavior: structs.SessionKeysDelete,
+		TTL:      largeEventTTL,
+	}
+	if dc != "" && dc != a.config.Datacenter {
+		args := structs.ServiceSpecificRequest{
+			Datacenter:   dc,
+			ServiceName:  consul.ConsulServiceName,
+			QueryOptions: structs.QueryOptions{Token: token},
+		}
+		var out structs.IndexedServiceNodes
+		if err := a.RPC("Catalog.ServiceNodes", &args, &out); err != nil {
+			return fmt.Errorf("failed to find servers in datacenter %q: %v", dc, err)
+		}
+		if len(out.ServiceNodes) == 0 {
+			return fmt.Errorf("no servers found in datacenter %q", dc)
+		}
+		session.Node = out.ServiceNodes[0].Node
+		session.Checks = nil
+	}
+
+	create := structs.SessionRequest{
+		Datacenter:   dc,
+		Op:           structs.SessionCreate,
+		Session:      session,
+		WriteRequest: structs.WriteRequest{Token: token},
+	}
+	var id string
+	if err := a.RPC("Session.Apply", &create, &id); err != nil {
+		return fmt.Errorf("failed to create session: %v", err)
+	}
+
+	write := structs.KVSRequest{
+		Datacenter: dc,
+		Op:         api.KVLock,
+		DirEnt: structs.DirEntry{
+			Key:     key,
+			Value:   params.Payload,
+			Session: id,
+		},
+		WriteRequest: structs.WriteRequest{Token: token},
+	}
+	var success bool
+	if err := a.RPC("KVS.Apply", &write, &success); err != nil {
+		return fmt.Errorf("failed to store payload: %v", err)
+	}
+	if !success {
+		return fmt.Errorf("failed to store payload: write failed")
+	}
+
+	params.PayloadKey = key
+	params.PayloadChecksum = largeEventChecksum(params.Payload)
+	return nil
+}
+
+// deleteLargeEventPayload removes a stored payload. Failures are only logged,
+// since there's nothing more the caller can do about them.
+func (a *Agent) deleteLargeEventPayload(dc, token, key string) {
+	del := structs.KVSRequest{
+		Datacenter:   dc,
+		Op:           api.KVDelete,
+		DirEnt:       structs.DirEntry{Key: key},
+		WriteRequest: structs.WriteRequest{Token: token},
+	}
+	var success bool
+	if err := a.RPC("KVS.Apply", &del, &success); err != nil {
+		a.logger.Printf("[WARN] agent: Failed to delete event payload %q: %v", key, err)
+	}
+}
+
+// fetchLargeEventPayload reads an event's payload from the KV store and
+// verifies its checksum.
+func (a *Agent) fetchLargeEventPayload(msg *UserEvent) error {
+	// Don't let events point us at arbitrary keys.
+	if !strings.HasPrefix(msg.PayloadKey, largeEventPrefix) ||
+		path.Clean(msg.PayloadKey) != msg.PayloadKey {
+		return fmt.Errorf("invalid payload key %q", msg.PayloadKey)
+	}
+
+	get := structs.KeyRequest{
+		Datacenter: a.config.Datacenter,
+		Key:        msg.PayloadKey,
+		QueryOptions: structs.QueryOptions{
+			AllowStale: true, // Stale read for scale! Retry on failure.
+		},
+	}
+	get.Token = a.tokens.UserToken()
+	var out structs.IndexedDirEntries
+QUERY:
+	if err := a.RPC("KVS.Get", &get, &out); err != nil {
+		return err
+	}
+	if len(out.Entries) == 0 {
+		// If the initial read was stale and had no data, retry as a consistent read
+		if get.QueryOptions.AllowStale {
+			get.QueryOptions.AllowStale = false
+			goto QUERY
+		}
+		return fmt.Errorf("payload missing")
+	}
+
+	payload := out.Entries[0].Value
+	if sum := largeEventChecksum(payload); sum != msg.PayloadChecksum {
+		return fmt.Errorf("payload checksum mismatch, got %s want %s", sum, msg.PayloadChecksum)
+	}
+	msg.Payload = payload
+	return nil
+}
@@ -0,0 +1,179 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/consul/structs"
+	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil/retry"
+)
+
+func TestIsLargeUserEvent(t *testing.T) {
+	if isLargeUserEvent("deploy", make([]byte, 100)) {
+		t.Fatalf("should not be large")
+	}
+	if !isLargeUserEvent("deploy", make([]byte, 500)) {
+		t.Fatalf("should be large")
+	}
+}
+
+func TestFireReceiveLargeEvent(t *testing.T) {
+	conf := nextConfig()
+	dir, agent := makeAgent(t, conf)
+	defer os.RemoveAll(dir)
+	defer agent.Shutdown()
+
+	testrpc.WaitForLeader(t, agent.RPC, "dc1")
+
+	payload := bytes.Repeat([]byte("x"), 4096)
+	p := &UserEvent{Name: "deploy", Payload: payload}
+	if err := agent.UserEvent("dc1", "root", p); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if p.PayloadKey != largeEventPrefix+p.ID || p.PayloadChecksum == "" {
+		t.Fatalf("bad: %#v", p)
+	}
+
+	// The payload should be stored in the KV store, bound to a session.
+	get := structs.KeyRequest{
+		Datacenter: "dc1",
+		Key:        p.PayloadKey,
+	}
+	var out structs.IndexedDirEntries
+	if err := agent.RPC("KVS.Get", &get, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Entries) != 1 || out.Entries[0].Session == "" ||
+		!bytes.Equal(out.Entries[0].Value, payload) {
+		t.Fatalf("bad: %#v", out.Entries)
+	}
+
+	// The receiving agent should fetch the full payload.
+	retry.Run(t, func(r *retry.R) {
+		last := agent.LastUserEvent()
+		if last == nil || last.ID != p.ID {
+			r.Fatalf("bad: %#v", last)
+		}
+		if !bytes.Equal(last.Payload, payload) {
+			r.Fatalf("bad payload: %d bytes", len(last.Payload))
+		}
+	})
+}
+
+func TestLargeDurableEventCatchUp(t *testing.T) {
+	conf := nextConfig()
+	conf.Version = "0.8.4"
+	dir, agent := makeAgent(t, conf)
+	defer os.RemoveAll(dir)
+	defer agent.Shutdown()
+
+	testrpc.WaitForLeader(t, agent.RPC, "dc1")
+
+	payload := bytes.Repeat([]byte("x"), 4096)
+	p := &UserEvent{Name: "deploy", Payload: payload, Durable: true}
+	if err := agent.UserEvent("dc1", "root", p); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The payload shouldn't be bound to a session, since it needs to be
+	// around for as long as the servers keep the event.
+	get := structs.KeyRequest{
+		Datacenter: "dc1",
+		Key:        p.PayloadKey,
+	}
+	var out structs.IndexedDirEntries
+	if err := agent.RPC("KVS.Get", &get, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Entries) != 1 || out.Entries[0].Session != "" {
+		t.Fatalf("bad: %#v", out.Entries)
+	}
+
+	// An agent that joins later should catch up on the event, including
+	// its payload.
+	conf2 := nextConfig()
+	conf2.Server = false
+	conf2.Bootstrap = false
+	dir2, agent2 := makeAgent(t, conf2)
+	defer os.RemoveAll(dir2)
+	defer agent2.Shutdown()
+
+	addr := fmt.Sprintf("127.0.0.1:%d", conf.Ports.SerfLan)
+	if _, err := agent2.JoinLAN([]string{addr}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	retry.Run(t, func(r *retry.R) {
+		if err := agent2.syncDurableEvents(); err != nil {
+			r.Fatal(err)
+		}
+		last := agent2.LastUserEvent()
+		if last == nil || last.ID != p.ID {
+			r.Fatalf("bad: %#v", last)
+		}
+		if !bytes.Equal(last.Payload, payload) {
+			r.Fatalf("bad payload: %d bytes", len(last.Payload))
+		}
+	})
+}
+
+func TestFetchLargeEventPayload(t *testing.T) {
+	conf := nextConfig()
+	dir, agent := makeAgent(t, conf)
+	defer os.RemoveAll(dir)
+	defer agent.Shutdown()
+
+	testrpc.WaitForLeader(t, agent.RPC, "dc1")
+
+	put := func(key string, val []byte) {
+		write := structs.KVSRequest{
+			Datacenter: "dc1",
+			Op:         api.KVSet,
+			DirEnt: structs.DirEntry{
+				Key:   key,
+				Value: val,
+			},
+		}
+		var success bool
+		if err := agent.RPC("KVS.Apply", &write, &success); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	put("_event/good", []byte("hello"))
+	put("secret", []byte("hello"))
+
+	cases := []struct {
+		key      string
+		checksum string
+		err      string
+	}{
+		{"_event/good", largeEventChecksum([]byte("hello")), ""},
+		{"_event/good", largeEventChecksum([]byte("nope")), "checksum mismatch"},
+		{"_event/missing", largeEventChecksum([]byte("hello")), "payload missing"},
+		{"secret", largeEventChecksum([]byte("hello")), "invalid payload key"},
+		{"_event/../secret", largeEventChecksum([]byte("hello")), "invalid payload key"},
+	}
+	for _, tc := range cases {
+		msg := &UserEvent{PayloadKey: tc.key, PayloadChecksum: tc.checksum}
+		err := agent.fetchLargeEventPayload(msg)
+		if tc.err == "" {
+			if err != nil {
+				t.Fatalf("%s: err: %v", tc.key, err)
+			}
+			if string(msg.Payload) != "hello" {
+				t.Fatalf("%s: bad: %q", tc.key, msg.Payload)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: expected %q, got %v", tc.key, tc.err, err)
+		}
+		if msg.Payload != nil {
+			t.Fatalf("%s: should not set payload", tc.key)
+		}
+	}
+}
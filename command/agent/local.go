@@ -13,6 +13,7 @@ import (
 	"github.com/hashicorp/consul/consul"
 	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/token"
 	"github.com/hashicorp/consul/types"
 )
 
@@ -41,6 +42,9 @@ type localState struct {
 	// Config is the agent config
 	config *Config
 
+	// tokens holds the agent's ACL tokens, which can change at runtime
+	tokens *token.Store
+
 	// iface is the consul interface to use for keeping in sync
 	iface consul.Interface
 
@@ -75,8 +79,9 @@ type localState struct {
 }
 
 // Init is used to initialize the local state
-func (l *localState) Init(config *Config, logger *log.Logger) {
+func (l *localState) Init(config *Config, tokens *token.Store, logger *log.Logger) {
 	l.config = config
+	l.tokens = tokens
 	l.logger = logger
 	l.services = make(map[string]*structs.NodeService)
 	l.serviceStatus = make(map[string]syncStatus)
@@ -147,7 +152,7 @@ func (l *localState) ServiceToken(id string) string {
 func (l *localState) serviceToken(id string) string {
 	token := l.serviceTokens[id]
 	if token == "" {
-		token = l.config.ACLToken
+		token = l.tokens.UserToken()
 	}
 	return token
 }
@@ -214,7 +219,7 @@ func (l *localState) CheckToken(checkID types.CheckID) string {
 func (l *localState) checkToken(checkID types.CheckID) string {
 	token := l.checkTokens[checkID]
 	if token == "" {
-		token = l.config.ACLToken
+		token = l.tokens.UserToken()
 	}
 	return token
 }
@@ -417,7 +422,7 @@ func (l *localState) setSyncState() error {
 	req := structs.NodeSpecificRequest{
 		Datacenter:   l.config.Datacenter,
 		Node:         l.config.NodeName,
-		QueryOptions: structs.QueryOptions{Token: l.config.GetTokenForAgent()},
+		QueryOptions: structs.QueryOptions{Token: l.tokens.AgentToken()},
 	}
 	var out1 structs.IndexedNodeServices
 	var out2 structs.IndexedHealthChecks
@@ -748,7 +753,7 @@ func (l *localState) syncNodeInfo() error {
 		Address:         l.config.AdvertiseAddr,
 		TaggedAddresses: l.config.TaggedAddresses,
 		NodeMeta:        l.metadata,
-		WriteRequest:    structs.WriteRequest{Token: l.config.GetTokenForAgent()},
+		WriteRequest:    structs.WriteRequest{Token: l.tokens.AgentToken()},
 	}
 	var out struct{}
 	err := l.iface.RPC("Catalog.Register", &req, &out)
@@ -10,6 +10,7 @@ import (
 	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/hashicorp/consul/token"
 	"github.com/hashicorp/consul/types"
 )
 
@@ -1347,9 +1348,10 @@ func TestAgentAntiEntropy_deleteCheck_fails(t *testing.T) {
 
 func TestAgent_serviceTokens(t *testing.T) {
 	config := nextConfig()
-	config.ACLToken = "default"
+	tokens := new(token.Store)
+	tokens.UpdateUserToken("default")
 	l := new(localState)
-	l.Init(config, nil)
+	l.Init(config, tokens, nil)
 
 	l.AddService(&structs.NodeService{
 		ID: "redis",
@@ -1375,9 +1377,10 @@ func TestAgent_serviceTokens(t *testing.T) {
 
 func TestAgent_checkTokens(t *testing.T) {
 	config := nextConfig()
-	config.ACLToken = "default"
+	tokens := new(token.Store)
+	tokens.UpdateUserToken("default")
 	l := new(localState)
-	l.Init(config, nil)
+	l.Init(config, tokens, nil)
 
 	// Returns default when no token is set
 	if token := l.CheckToken("mem"); token != "default" {
@@ -1400,7 +1403,7 @@ func TestAgent_checkTokens(t *testing.T) {
 func TestAgent_checkCriticalTime(t *testing.T) {
 	config := nextConfig()
 	l := new(localState)
-	l.Init(config, nil)
+	l.Init(config, new(token.Store), nil)
 
 	// Add a passing check and make sure it's not critical.
 	checkID := types.CheckID("redis:1")
@@ -0,0 +1,163 @@
+package agent
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+)
+
+// MetricsSummary holds the agent's in-memory metrics for a single
+// aggregation interval.
+type MetricsSummary struct {
+	Timestamp string
+	Gauges    []GaugeValue
+	Points    []PointValue
+	Counters  []SampledValue
+	Samples   []SampledValue
+}
+
+// GaugeValue is the last value set for a gauge.
+type GaugeValue struct {
+	Name  string
+	Value float32
+}
+
+// PointValue holds the values emitted for a key.
+type PointValue struct {
+	Name   string
+	Points []float32
+}
+
+// SampledValue holds the aggregated values for a counter or a sample.
+type SampledValue struct {
+	Name   string
+	Count  int
+	Sum    float64
+	Min    float64
+	Max    float64
+	Mean   float64
+	Stddev float64
+}
+
+// summarizeMetrics returns the most recently completed interval from the
+// sink, or the current one if none have completed yet, so counters and
+// samples cover a whole interval.
+func summarizeMetrics(sink *metrics.InmemSink) *MetricsSummary {
+	data := sink.Data()
+	summary := &MetricsSummary{
+		Gauges:   make([]GaugeValue, 0),
+		Points:   make([]PointValue, 0),
+		Counters: make([]SampledValue, 0),
+		Samples:  make([]SampledValue, 0),
+	}
+	if len(data) == 0 {
+		return summary
+	}
+	intv := data[len(data)-1]
+	if len(data) > 1 {
+		intv = data[len(data)-2]
+	}
+
+	intv.RLock()
+	defer intv.RUnlock()
+
+	summary.Timestamp = intv.Interval.Round(time.Second).UTC().String()
+	for name, val := range intv.Gauges {
+		summary.Gauges = append(summary.Gauges, GaugeValue{name, val})
+	}
+	for name, points := range intv.Points {
+		summary.Points = append(summary.Points, PointValue{name, points})
+	}
+	for name, agg := range intv.Counters {
+		summary.Counters = append(summary.Counters, newSampledValue(name, agg))
+	}
+	for name, agg := range intv.Samples {
+		summary.Samples = append(summary.Samples, newSampledValue(name, agg))
+	}
+
+	sort.Slice(summary.Gauges, func(i, j int) bool {
+		return summary.Gauges[i].Name < summary.Gauges[j].Name
+	})
+	sort.Slice(summary.Points, func(i, j int) bool {
+		return summary.Points[i].Name < summary.Points[j].Name
+	})
+	sort.Slice(summary.Counters, func(i, j int) bool {
+		return summary.Counters[i].Name < summary.Counters[j].Name
+	})
+	sort.Slice(summary.Samples, func(i, j int) bool {
+		return summary.Samples[i].Name < summary.Samples[j].Name
+	})
+	return summary
+}
+
+// newSampledValue converts an aggregate sample.
+func newSampledValue(name string, agg *metrics.AggregateSample) SampledValue {
+	return SampledValue{
+		Name:   name,
+		Count:  agg.Count,
+		Sum:    agg.Sum,
+		Min:    agg.Min,
+		Max:    agg.Max,
+		Mean:   agg.Mean(),
+		Stddev: agg.Stddev(),
+	}
+}
+
+// prometheusName converts a metric key into a valid Prometheus metric name.
+func prometheusName(name string) string {
+	out := []byte(name)
+	for i, c := range out {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_', c == ':':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			out[i] = '_'
+		}
+	}
+	return string(out)
+}
+
+// prometheusFloat formats a value for the Prometheus text format.
+func prometheusFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// encodePrometheus writes the summary in the Prometheus text exposition
+// format. Gauges are exported as gauges. Counters and samples are only
+// aggregated over the interval, so they aren't monotonic, and are exported
+// as untyped _count, _sum, _min, _max and _mean series. Points are skipped
+// since they have no equivalent.
+func encodePrometheus(summary *MetricsSummary) []byte {
+	var buf bytes.Buffer
+	for _, g := range summary.Gauges {
+		name := prometheusName(g.Name)
+		fmt.Fprintf(&buf, "# TYPE %s gauge\n", name)
+		fmt.Fprintf(&buf, "%s %s\n", name, prometheusFloat(float64(g.Value)))
+	}
+
+	sampled := append(append([]SampledValue{}, summary.Counters...), summary.Samples...)
+	for _, s := range sampled {
+		name := prometheusName(s.Name)
+		series := []struct {
+			suffix string
+			value  float64
+		}{
+			{"count", float64(s.Count)},
+			{"sum", s.Sum},
+			{"min", s.Min},
+			{"max", s.Max},
+			{"mean", s.Mean},
+		}
+		for _, ser := range series {
+			full := strings.Join([]string{name, ser.suffix}, "_")
+			fmt.Fprintf(&buf, "# TYPE %s untyped\n", full)
+			fmt.Fprintf(&buf, "%s %s\n", full, prometheusFloat(ser.value))
+		}
+	}
+	return buf.Bytes()
+}
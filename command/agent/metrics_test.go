@@ -0,0 +1,47 @@
+package agent
+
+import (
+	"testing"
+)
+
+func TestPrometheusName(t *testing.T) {
+	cases := map[string]string{
+		"consul.raft.apply":          "consul_raft_apply",
+		"consul.my-host.runtime.gc":  "consul_my_host_runtime_gc",
+		"consul.dns.ptr_query.node1": "consul_dns_ptr_query_node1",
+		"1consul":                    "_consul",
+		"a:b":                        "a:b",
+	}
+	for in, want := range cases {
+		if got := prometheusName(in); got != want {
+			t.Fatalf("%q: got %q want %q", in, got, want)
+		}
+	}
+}
+
+func TestEncodePrometheus(t *testing.T) {
+	summary := &MetricsSummary{
+		Gauges: []GaugeValue{
+			{Name: "consul.runtime.num_goroutines", Value: 12},
+		},
+		Counters: []SampledValue{
+			{Name: "consul.rpc.request", Count: 2, Sum: 2, Min: 1, Max: 1, Mean: 1},
+		},
+	}
+	expected := `# TYPE consul_runtime_num_goroutines gauge
+consul_runtime_num_goroutines 12
+# TYPE consul_rpc_request_count untyped
+consul_rpc_request_count 2
+# TYPE consul_rpc_request_sum untyped
+consul_rpc_request_sum 2
+# TYPE consul_rpc_request_min untyped
+consul_rpc_request_min 1
+# TYPE consul_rpc_request_max untyped
+consul_rpc_request_max 1
+# TYPE consul_rpc_request_mean untyped
+consul_rpc_request_mean 1
+`
+	if got := string(encodePrometheus(summary)); got != expected {
+		t.Fatalf("got:\n%s\nwant:\n%s", got, expected)
+	}
+}
@@ -0,0 +1,224 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/consul/structs"
+	"github.com/hashicorp/consul/lib"
+	"github.com/mitchellh/copystructure"
+)
+
+const (
+	// queryCacheRetryInterval is how long to wait before retrying a failed
+	// refresh of a cached prepared query result.
+	queryCacheRetryInterval = 5 * time.Second
+)
+
+// rpcFn is used to make RPC calls to the servers.
+type rpcFn func(method string, args interface{}, reply interface{}) error
+
+// queryCacheEntry is a cached prepared query result, along with what's
+// needed to refresh it.
+type queryCacheEntry struct {
+	// endpoint and args are used to re-execute the query.
+	endpoint string
+	args     structs.PreparedQueryExecuteRequest
+
+	// reply is the most recent result.
+	reply structs.PreparedQueryExecuteResponse
+
+	// updated is when reply was last fetched, and used is when it was
+	// last served.
+	updated time.Time
+	used    time.Time
+}
+
+// preparedQueryCache keeps recent prepared query results on the agent so DNS
+// and HTTP lookups don't each need a round trip to the servers. Each entry is
+// kept fresh by a goroutine that runs a blocking query on the service that
+// the results came from, and re-executes the query when it changes. Entries
+// that haven't been refreshed within the TTL aren't served, and entries that
+// haven't been used within the TTL are dropped.
+type preparedQueryCache struct {
+	ttl        time.Duration
+	rpc        rpcFn
+	logger     *log.Logger
+	shutdownCh <-chan struct{}
+
+	entries map[string]*queryCacheEntry
+	lock    sync.Mutex
+}
+
+// newPreparedQueryCache returns a cache that makes RPC calls with the given
+// function. Background refreshes stop when shutdownCh is closed.
+func newPreparedQueryCache(ttl time.Duration, rpc rpcFn, logger *log.Logger,
+	shutdownCh <-chan struct{}) *preparedQueryCache {
+	return &preparedQueryCache{
+		ttl:        ttl,
+		rpc:        rpc,
+		logger:     logger,
+		shutdownCh: shutdownCh,
+		entries:    make(map[string]*queryCacheEntry),
+	}
+}
+
+// queryCacheKey returns the cache key for the given request. Everything that
+// can change the results is part of the key, including the token since it
+// is used to filter the results.
+func queryCacheKey(args *structs.PreparedQueryExecuteRequest) string {
+	return fmt.Sprintf("%s\x00%s\x00%d\x00%s\x00%s\x00%s\x00%s\x00%s\x00%v",
+		args.Datacenter, args.QueryIDOrName, args.Limit,
+		args.Source.Datacenter, args.Source.Node,
+		args.Agent.Datacenter, args.Agent.Node,
+		args.Token, args.AllowStale)
+}
+
+// Execute runs the given prepared query request, serving it from the cache
+// if possible. Consistent requests always go to the servers.
+func (c *preparedQueryCache) Execute(endpoint string, args *structs.PreparedQueryExecuteRequest,
+	reply *structs.PreparedQueryExecuteResponse) error {
+	if args.RequireConsistent {
+		return c.rpc(endpoint+".Execute", args, reply)
+	}
+
+	// Serve from the cache if we have a fresh enough result.
+	key := queryCacheKey(args)
+	c.lock.Lock()
+	entry, ok := c.entries[key]
+	if ok && time.Since(entry.updated) < c.ttl {
+		entry.used = time.Now()
+		dup, err := copystructure.Copy(&entry.reply)
+		c.lock.Unlock()
+		if err != nil {
+			return err
+		}
+		*reply = *(dup.(*structs.PreparedQueryExecuteResponse))
+		metrics.IncrCounter([]string{"consul", "query_cache", "hits"}, 1)
+		return nil
+	}
+	c.lock.Unlock()
+	metrics.IncrCounter([]string{"consul", "query_cache", "misses"}, 1)
+
+	// Go to the servers. Errors aren't cached.
+	var out structs.PreparedQueryExecuteResponse
+	if err := c.rpc(endpoint+".Execute", args, &out); err != nil {
+		return err
+	}
+	dup, err := copystructure.Copy(&out)
+	if err != nil {
+		return err
+	}
+	*reply = *(dup.(*structs.PreparedQueryExecuteResponse))
+
+	// Store the result, starting a refresh goroutine if this is a new
+	// entry. A stale entry already has one running.
+	now := time.Now()
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if entry, ok := c.entries[key]; ok {
+		entry.reply, entry.updated, entry.used = out, now, now
+		return nil
+	}
+	entry = &queryCacheEntry{
+		endpoint: endpoint,
+		args:     *args,
+		reply:    out,
+		updated:  now,
+		used:     now,
+	}
+	c.entries[key] = entry
+	go c.refresh(key, entry)
+	return nil
+}
+
+// refresh keeps the given entry up to date until it goes unused for the TTL,
+// or the cache is shut down.
+func (c *preparedQueryCache) refresh(key string, entry *queryCacheEntry) {
+	var index uint64
+	for {
+		// Drop the entry if nobody has used it lately.
+		c.lock.Lock()
+		if time.Since(entry.used) > c.ttl {
+			delete(c.entries, key)
+			c.lock.Unlock()
+			return
+		}
+		req := structs.ServiceSpecificRequest{
+			Datacenter:  entry.reply.Datacenter,
+			ServiceName: entry.reply.Service,
+			QueryOptions: structs.QueryOptions{
+				Token:         entry.args.Token,
+				AllowStale:    true,
+				MinQueryIndex: index,
+				MaxQueryTime:  c.ttl / 2,
+			},
+		}
+		c.lock.Unlock()
+
+		// Wait for the service to change. We also wake up at least twice
+		// per TTL so that changes which don't touch this service, such as
+		// a failover target recovering, are picked up.
+		var nodes structs.IndexedCheckServiceNodes
+		if err := c.rpc("Health.ServiceNodes", &req, &nodes); err != nil {
+			c.logger.Printf("[WARN] agent: Failed to watch service %q for prepared query %q: %v",
+				req.ServiceName, entry.args.QueryIDOrName, err)
+			if !c.wait(lib.RandomStagger(queryCacheRetryInterval) + queryCacheRetryInterval) {
+				return
+			}
+			continue
+		}
+
+		// The first call just establishes the index.
+		first := index == 0
+		index = nodes.Index
+		if first {
+			continue
+		}
+
+		// Re-execute the query with the original request.
+		var out structs.PreparedQueryExecuteResponse
+		if err := c.rpc(entry.endpoint+".Execute", &entry.args, &out); err != nil {
+			c.logger.Printf("[WARN] agent: Failed to refresh prepared query %q: %v",
+				entry.args.QueryIDOrName, err)
+			if !c.wait(lib.RandomStagger(queryCacheRetryInterval) + queryCacheRetryInterval) {
+				return
+			}
+			continue
+		}
+		c.lock.Lock()
+		entry.reply, entry.updated = out, time.Now()
+		c.lock.Unlock()
+
+		select {
+		case <-c.shutdownCh:
+			return
+		default:
+		}
+	}
+}
+
+// wait sleeps for the given duration, and returns false if the cache was shut
+// down in the meantime.
+func (c *preparedQueryCache) wait(d time.Duration) bool {
+	select {
+	case <-c.shutdownCh:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// executePreparedQuery runs a prepared query, using the agent's cache of
+// results if it's enabled.
+func (a *Agent) executePreparedQuery(args *structs.PreparedQueryExecuteRequest,
+	reply *structs.PreparedQueryExecuteResponse) error {
+	endpoint := a.getEndpoint(preparedQueryEndpoint)
+	if a.queryCache == nil {
+		return a.RPC(endpoint+".Execute", args, reply)
+	}
+	return a.queryCache.Execute(endpoint, args, reply)
+}
@@ -0,0 +1,234 @@
+package agent
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/consul/structs"
+	"github.com/hashicorp/consul/testutil/retry"
+)
+
+// mockQueryCacheServer fakes out the RPC calls the prepared query cache makes.
+type mockQueryCacheServer struct {
+	sync.Mutex
+
+	// executes counts the calls to execute the query, and nodes is the
+	// number of nodes each execute returns.
+	executes int
+	nodes    int
+	err      error
+
+	// index is the index of the watched service, and watches counts the
+	// calls to watch it.
+	index   uint64
+	watches int
+}
+
+func (m *mockQueryCacheServer) RPC(method string, args interface{}, reply interface{}) error {
+	switch method {
+	case "PreparedQuery.Execute":
+		m.Lock()
+		defer m.Unlock()
+		m.executes++
+		if m.err != nil {
+			return m.err
+		}
+		out := reply.(*structs.PreparedQueryExecuteResponse)
+		out.Service = "redis"
+		out.Datacenter = "dc1"
+		for i := 0; i < m.nodes; i++ {
+			out.Nodes = append(out.Nodes, structs.CheckServiceNode{
+				Node: &structs.Node{Node: fmt.Sprintf("node%d", i+1)},
+			})
+		}
+		return nil
+
+	case "Health.ServiceNodes":
+		req := args.(*structs.ServiceSpecificRequest)
+		if req.ServiceName != "redis" || req.Datacenter != "dc1" {
+			return fmt.Errorf("bad: %v", req)
+		}
+
+		// Block until the index moves past the one we were given, or
+		// we hit the timeout.
+		deadline := time.Now().Add(req.MaxQueryTime)
+		for {
+			m.Lock()
+			index := m.index
+			if index > req.MinQueryIndex || time.Now().After(deadline) {
+				m.watches++
+				m.Unlock()
+				reply.(*structs.IndexedCheckServiceNodes).Index = index
+				return nil
+			}
+			m.Unlock()
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+	return fmt.Errorf("unexpected method %q", method)
+}
+
+func (m *mockQueryCacheServer) Set(nodes int, index uint64) {
+	m.Lock()
+	defer m.Unlock()
+	m.nodes, m.index = nodes, index
+}
+
+func (m *mockQueryCacheServer) Executes() int {
+	m.Lock()
+	defer m.Unlock()
+	return m.executes
+}
+
+func testQueryCache(t *testing.T, ttl time.Duration) (*preparedQueryCache, *mockQueryCacheServer, chan struct{}) {
+	m := &mockQueryCacheServer{nodes: 1, index: 1}
+	shutdownCh := make(chan struct{})
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	return newPreparedQueryCache(ttl, m.RPC, logger, shutdownCh), m, shutdownCh
+}
+
+func TestPreparedQueryCache_Execute(t *testing.T) {
+	c, m, shutdownCh := testQueryCache(t, time.Minute)
+	defer close(shutdownCh)
+
+	args := &structs.PreparedQueryExecuteRequest{
+		Datacenter:    "dc1",
+		QueryIDOrName: "my-query",
+	}
+
+	// The first call should go to the servers.
+	var reply structs.PreparedQueryExecuteResponse
+	if err := c.Execute("PreparedQuery", args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(reply.Nodes) != 1 || m.Executes() != 1 {
+		t.Fatalf("bad: %v", reply)
+	}
+
+	// Mess with the result we got, which shouldn't affect the cache.
+	reply.Nodes[0].Node.Node = "nope"
+
+	// The next call should be served from the cache.
+	m.Set(2, 1)
+	reply = structs.PreparedQueryExecuteResponse{}
+	if err := c.Execute("PreparedQuery", args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(reply.Nodes) != 1 || reply.Nodes[0].Node.Node != "node1" || m.Executes() != 1 {
+		t.Fatalf("bad: %v", reply)
+	}
+
+	// A different request shouldn't share the entry.
+	limited := *args
+	limited.Limit = 1
+	reply = structs.PreparedQueryExecuteResponse{}
+	if err := c.Execute("PreparedQuery", &limited, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(reply.Nodes) != 2 || m.Executes() != 2 {
+		t.Fatalf("bad: %v", reply)
+	}
+
+	// Consistent requests always go to the servers.
+	consistent := *args
+	consistent.RequireConsistent = true
+	for i := 0; i < 2; i++ {
+		reply = structs.PreparedQueryExecuteResponse{}
+		if err := c.Execute("PreparedQuery", &consistent, &reply); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if m.Executes() != 4 {
+		t.Fatalf("bad: %d", m.Executes())
+	}
+
+	// Errors aren't cached.
+	errored := *args
+	errored.QueryIDOrName = "other-query"
+	m.Lock()
+	m.err = fmt.Errorf("Query not found")
+	m.Unlock()
+	for i := 0; i < 2; i++ {
+		err := c.Execute("PreparedQuery", &errored, &reply)
+		if err == nil || err.Error() != "Query not found" {
+			t.Fatalf("bad: %v", err)
+		}
+	}
+	if m.Executes() != 6 {
+		t.Fatalf("bad: %d", m.Executes())
+	}
+}
+
+func TestPreparedQueryCache_Refresh(t *testing.T) {
+	c, m, shutdownCh := testQueryCache(t, time.Minute)
+	defer close(shutdownCh)
+
+	args := &structs.PreparedQueryExecuteRequest{
+		Datacenter:    "dc1",
+		QueryIDOrName: "my-query",
+	}
+	var reply structs.PreparedQueryExecuteResponse
+	if err := c.Execute("PreparedQuery", args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Wait for the watch to get set up, then change the service.
+	retry.Run(t, func(r *retry.R) {
+		m.Lock()
+		defer m.Unlock()
+		if m.watches == 0 {
+			r.Fatal("not watching yet")
+		}
+	})
+	m.Set(3, 2)
+
+	// The cached result should get refreshed in the background.
+	retry.Run(t, func(r *retry.R) {
+		var reply structs.PreparedQueryExecuteResponse
+		if err := c.Execute("PreparedQuery", args, &reply); err != nil {
+			r.Fatalf("err: %v", err)
+		}
+		if len(reply.Nodes) != 3 {
+			r.Fatalf("bad: %v", reply)
+		}
+	})
+	if got := m.Executes(); got != 2 {
+		t.Fatalf("bad: %d", got)
+	}
+}
+
+func TestPreparedQueryCache_Expire(t *testing.T) {
+	c, m, shutdownCh := testQueryCache(t, 100*time.Millisecond)
+	defer close(shutdownCh)
+
+	args := &structs.PreparedQueryExecuteRequest{
+		Datacenter:    "dc1",
+		QueryIDOrName: "my-query",
+	}
+	var reply structs.PreparedQueryExecuteResponse
+	if err := c.Execute("PreparedQuery", args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Once it's not being used, the entry should get dropped.
+	retry.Run(t, func(r *retry.R) {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		if len(c.entries) != 0 {
+			r.Fatalf("bad: %v", c.entries)
+		}
+	})
+
+	// The next call should go back to the servers.
+	before := m.Executes()
+	if err := c.Execute("PreparedQuery", args, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got := m.Executes(); got != before+1 {
+		t.Fatalf("bad: %d", got)
+	}
+}
@@ -110,8 +110,7 @@ func (s *HTTPServer) preparedQueryExecute(id string, resp http.ResponseWriter, r
 	}
 
 	var reply structs.PreparedQueryExecuteResponse
-	endpoint := s.agent.getEndpoint(preparedQueryEndpoint)
-	if err := s.agent.RPC(endpoint+".Execute", &args, &reply); err != nil {
+	if err := s.agent.executePreparedQuery(&args, &reply); err != nil {
 		// We have to check the string since the RPC sheds
 		// the specific error type.
 		if err.Error() == consul.ErrQueryNotFound.Error() {
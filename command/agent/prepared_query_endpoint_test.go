@@ -7,7 +7,9 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/consul/consul/structs"
 )
@@ -442,6 +444,52 @@ func TestPreparedQuery_Execute(t *testing.T) {
 			t.Fatalf("bad code: %d", resp.Code)
 		}
 	})
+
+	// Results should be served from the cache when it's enabled, unless
+	// a consistent read is requested.
+	httpTestWithConfig(t, func(srv *HTTPServer) {
+		m := MockPreparedQuery{}
+		if err := srv.agent.InjectEndpoint("PreparedQuery", &m); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		var lock sync.Mutex
+		executes := 0
+		m.executeFn = func(args *structs.PreparedQueryExecuteRequest, reply *structs.PreparedQueryExecuteResponse) error {
+			lock.Lock()
+			defer lock.Unlock()
+			executes++
+			reply.Service = "redis"
+			reply.Failovers = 99
+			return nil
+		}
+
+		for _, url := range []string{
+			"/v1/query/my-id/execute",
+			"/v1/query/my-id/execute",
+			"/v1/query/my-id/execute?consistent",
+		} {
+			body := bytes.NewBuffer(nil)
+			req, _ := http.NewRequest("GET", url, body)
+			resp := httptest.NewRecorder()
+			obj, err := srv.PreparedQuerySpecific(resp, req)
+			if err != nil {
+				t.Fatalf("err: %v", err)
+			}
+			r, ok := obj.(structs.PreparedQueryExecuteResponse)
+			if !ok || r.Failovers != 99 {
+				t.Fatalf("bad: %v", obj)
+			}
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		if executes != 2 {
+			t.Fatalf("bad: %d", executes)
+		}
+	}, func(c *Config) {
+		c.PreparedQueryCacheTTL = time.Minute
+	})
 }
 
 func TestPreparedQuery_Explain(t *testing.T) {
@@ -0,0 +1,79 @@
+package agent
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// reloadableConfig lists the configuration keys whose changes are applied
+// when the agent reloads its configuration. Keys inside a block are given
+// as "<block>.<key>". Changes to any other keys need a restart.
+var reloadableConfig = map[string]bool{
+	"acl_agent_token":            true,
+	"acl_token":                  true,
+	"dns_config":                 true,
+	"log_level":                  true,
+	"node_meta":                  true,
+	"recursor":                   true,
+	"recursors":                  true,
+	"telemetry.dogstatsd_addr":   true,
+	"telemetry.dogstatsd_tags":   true,
+	"telemetry.statsd_address":   true,
+	"telemetry.statsite_address": true,
+	"watches":                    true,
+}
+
+// configChanges compares two configurations and returns the keys that
+// changed, split into those that a reload applies and those that need a
+// restart to take effect. Both lists are sorted.
+func configChanges(a, b *Config) (applied, restart []string) {
+	diffConfigFields("", reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem(), &applied, &restart)
+	sort.Strings(applied)
+	sort.Strings(restart)
+	return applied, restart
+}
+
+// diffConfigFields compares the fields of two config structs by their
+// mapstructure keys, descending into blocks that are partially reloadable.
+func diffConfigFields(prefix string, a, b reflect.Value, applied, restart *[]string) {
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		// Untagged fields are decoded using their lowercased name.
+		field := t.Field(i)
+		key, ok := field.Tag.Lookup("mapstructure")
+		if !ok {
+			key = strings.ToLower(field.Name)
+		}
+		key = strings.Split(key, ",")[0]
+		if key == "" || key == "-" {
+			continue
+		}
+		key = prefix + key
+
+		fa, fb := a.Field(i), b.Field(i)
+		if fa.Kind() == reflect.Struct && hasReloadableChild(key) {
+			diffConfigFields(key+".", fa, fb, applied, restart)
+			continue
+		}
+		if reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			continue
+		}
+		if reloadableConfig[key] {
+			*applied = append(*applied, key)
+		} else {
+			*restart = append(*restart, key)
+		}
+	}
+}
+
+// hasReloadableChild returns true if any key inside the given block is
+// reloadable.
+func hasReloadableChild(block string) bool {
+	for key := range reloadableConfig {
+		if strings.HasPrefix(key, block+".") {
+			return true
+		}
+	}
+	return false
+}
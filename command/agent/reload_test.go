@@ -0,0 +1,35 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfigChanges(t *testing.T) {
+	a := DefaultConfig()
+	b := DefaultConfig()
+
+	// Nothing changed.
+	applied, restart := configChanges(a, b)
+	if len(applied) != 0 || len(restart) != 0 {
+		t.Fatalf("bad: %v %v", applied, restart)
+	}
+
+	b.ACLToken = "token"
+	b.DNSConfig.OnlyPassing = true
+	b.Telemetry.StatsdAddr = "127.0.0.1:8125"
+	b.Telemetry.StatsitePrefix = "consul2"
+	b.NodeName = "node2"
+	b.Ports.HTTP = 9999
+
+	// Runtime-only fields aren't reported.
+	b.Version = "9.9.9"
+
+	applied, restart = configChanges(a, b)
+	if want := []string{"acl_token", "dns_config", "telemetry.statsd_address"}; !reflect.DeepEqual(applied, want) {
+		t.Fatalf("got %v want %v", applied, want)
+	}
+	if want := []string{"node_name", "ports", "telemetry.statsite_prefix"}; !reflect.DeepEqual(restart, want) {
+		t.Fatalf("got %v want %v", restart, want)
+	}
+}
@@ -36,6 +36,11 @@ const (
 	// remoteExecOutputDeadline is how long we wait before uploading
 	// less than the chunk size
 	remoteExecOutputDeadline = 500 * time.Millisecond
+
+	// remoteExecTimeoutExitCode is the exit code we report when a command
+	// is killed for running longer than its timeout. This matches the
+	// timeout(1) utility.
+	remoteExecTimeoutExitCode = 124
 )
 
 // remoteExecEvent is used as the payload of the user event to transmit
@@ -43,6 +48,10 @@ const (
 type remoteExecEvent struct {
 	Prefix  string
 	Session string
+
+	// Batch is the 1-based index of the batch in the spec that should
+	// run the command. Zero means every node that got the event.
+	Batch int
 }
 
 // remoteExecSpec is used as the specification of the remote exec.
@@ -51,6 +60,14 @@ type remoteExecSpec struct {
 	Command string
 	Script  []byte
 	Wait    time.Duration
+
+	// Timeout is a hard limit on how long the command can run before it
+	// is killed. Zero means no limit.
+	Timeout time.Duration
+
+	// Batches are the names of the nodes in each batch of a rolling
+	// execution.
+	Batches [][]string
 }
 
 type rexecWriter struct {
@@ -131,6 +148,12 @@ func (a *Agent) handleRemoteExec(msg *UserEvent) {
 		return
 	}
 
+	// Skip the event if we aren't in the batch it's for
+	if !a.remoteExecInBatch(&event, &spec) {
+		a.logger.Printf("[DEBUG] agent: skipping remote exec event (ID: %s), not in batch %d", msg.ID, event.Batch)
+		return
+	}
+
 	// Write the acknowledgement
 	if !a.remoteExecWriteAck(&event) {
 		return
@@ -176,6 +199,7 @@ func (a *Agent) handleRemoteExec(msg *UserEvent) {
 	}
 	cmd.Stdout = writer
 	cmd.Stderr = writer
+	setProcessGroup(cmd)
 
 	// Start execution
 	err = cmd.Start()
@@ -206,6 +230,14 @@ func (a *Agent) handleRemoteExec(msg *UserEvent) {
 		exitCh <- 1
 	}()
 
+	// Enforce the timeout, if any
+	var timeoutCh <-chan time.Time
+	if spec.Timeout > 0 {
+		timer := time.NewTimer(spec.Timeout)
+		defer timer.Stop()
+		timeoutCh = timer.C
+	}
+
 	// Wait until we are complete, uploading as we go
 WAIT:
 	for num := 0; ; num++ {
@@ -219,6 +251,18 @@ WAIT:
 				exitCode = 255
 				return
 			}
+		case <-timeoutCh:
+			// Kill the process along with any children it started, which
+			// may still be holding its output open.
+			a.logger.Printf("[WARN] agent: remote exec '%s' timed out after %s", script, spec.Timeout)
+			if err := killProcessGroup(cmd); err != nil {
+				a.logger.Printf("[ERR] agent: failed to kill remote exec: %v", err)
+			}
+			close(writer.CancelCh)
+			msg := fmt.Sprintf("Timed out after %s, killed\n", spec.Timeout)
+			a.remoteExecWriteOutput(&event, num, []byte(msg))
+			exitCode = remoteExecTimeoutExitCode
+			return
 		case <-time.After(spec.Wait):
 			// Acts like a heartbeat, since there is no output
 			if !a.remoteExecWriteOutput(&event, num, nil) {
@@ -243,7 +287,7 @@ func (a *Agent) remoteExecGetSpec(event *remoteExecEvent, spec *remoteExecSpec)
 			AllowStale: true, // Stale read for scale! Retry on failure.
 		},
 	}
-	get.Token = a.config.ACLToken
+	get.Token = a.tokens.UserToken()
 	var out structs.IndexedDirEntries
 QUERY:
 	if err := a.RPC("KVS.Get", &get, &out); err != nil {
@@ -268,6 +312,23 @@ QUERY:
 	return true
 }
 
+// remoteExecInBatch is used to check if this node is in the batch
+// the event is for. Events without a batch are for every node.
+func (a *Agent) remoteExecInBatch(event *remoteExecEvent, spec *remoteExecSpec) bool {
+	if event.Batch == 0 {
+		return true
+	}
+	if event.Batch > len(spec.Batches) {
+		return false
+	}
+	for _, node := range spec.Batches[event.Batch-1] {
+		if node == a.config.NodeName {
+			return true
+		}
+	}
+	return false
+}
+
 // remoteExecWriteAck is used to write an ack. Returns if execution should
 // continue.
 func (a *Agent) remoteExecWriteAck(event *remoteExecEvent) bool {
@@ -310,7 +371,7 @@ func (a *Agent) remoteExecWriteKey(event *remoteExecEvent, suffix string, val []
 			Session: event.Session,
 		},
 	}
-	write.Token = a.config.ACLToken
+	write.Token = a.tokens.UserToken()
 	var success bool
 	if err := a.RPC("KVS.Apply", &write, &success); err != nil {
 		return err
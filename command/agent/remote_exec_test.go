@@ -4,14 +4,20 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/hashicorp/consul/testutil/retry"
 	"github.com/hashicorp/go-uuid"
 )
 
@@ -208,6 +214,14 @@ func testRemoteExecWrites(t *testing.T, c *Config) {
 }
 
 func testHandleRemoteExec(t *testing.T, command string, expectedSubstring string, expectedReturnCode string) {
+	spec := &remoteExecSpec{
+		Command: command,
+		Wait:    time.Second,
+	}
+	testHandleRemoteExecSpec(t, spec, "00000", expectedSubstring, expectedReturnCode)
+}
+
+func testHandleRemoteExecSpec(t *testing.T, spec *remoteExecSpec, outputKey string, expectedSubstring string, expectedReturnCode string) {
 	dir, agent := makeAgent(t, nextConfig())
 	defer os.RemoveAll(dir)
 	defer agent.Shutdown()
@@ -219,10 +233,6 @@ func testHandleRemoteExec(t *testing.T, command string, expectedSubstring string
 	}
 	defer destroySession(t, agent, event.Session)
 
-	spec := &remoteExecSpec{
-		Command: command,
-		Wait:    time.Second,
-	}
 	buf, err := json.Marshal(spec)
 	if err != nil {
 		t.Fatalf("err: %v", err)
@@ -250,7 +260,7 @@ func testHandleRemoteExec(t *testing.T, command string, expectedSubstring string
 	}
 
 	// Verify we have output
-	key = "_rexec/" + event.Session + "/" + agent.config.NodeName + "/out/00000"
+	key = "_rexec/" + event.Session + "/" + agent.config.NodeName + "/out/" + outputKey
 	d = getKV(t, agent, key)
 	if d == nil || d.Session != event.Session ||
 		!bytes.Contains(d.Value, []byte(expectedSubstring)) {
@@ -273,6 +283,101 @@ func TestHandleRemoteExecFailed(t *testing.T) {
 	testHandleRemoteExec(t, "echo failing;exit 2", "failing", "2")
 }
 
+func TestHandleRemoteExecTimeout(t *testing.T) {
+	spec := &remoteExecSpec{
+		Command: "sleep 10",
+		Wait:    5 * time.Second,
+		Timeout: 500 * time.Millisecond,
+	}
+	testHandleRemoteExecSpec(t, spec, "00000", "Timed out after 500ms", "124")
+}
+
+func TestHandleRemoteExecTimeout_Children(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("checks for the child process in /proc")
+	}
+
+	dir := testutil.TempDir(t, "rexec")
+	defer os.RemoveAll(dir)
+	pidFile := filepath.Join(dir, "child.pid")
+
+	// The background child holds the output open after the shell is
+	// killed, so it has to be killed too.
+	spec := &remoteExecSpec{
+		Command: fmt.Sprintf("sleep 30 & echo $! > %s; wait", pidFile),
+		Wait:    5 * time.Second,
+		Timeout: 500 * time.Millisecond,
+	}
+	testHandleRemoteExecSpec(t, spec, "00000", "Timed out after 500ms", "124")
+
+	raw, err := ioutil.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	pid := strings.TrimSpace(string(raw))
+	retry.Run(t, func(r *retry.R) {
+		// The child is either gone or a zombie waiting to be reaped.
+		stat, err := ioutil.ReadFile(filepath.Join("/proc", pid, "stat"))
+		if err != nil {
+			return
+		}
+		if fields := strings.Fields(string(stat)); len(fields) < 3 || fields[2] != "Z" {
+			r.Fatalf("child %s still running: %s", pid, stat)
+		}
+	})
+}
+
+func TestHandleRemoteExec_Batches(t *testing.T) {
+	dir, agent := makeAgent(t, nextConfig())
+	defer os.RemoveAll(dir)
+	defer agent.Shutdown()
+	testrpc.WaitForLeader(t, agent.RPC, "dc1")
+
+	event := &remoteExecEvent{
+		Prefix:  "_rexec",
+		Session: makeRexecSession(t, agent),
+	}
+	defer destroySession(t, agent, event.Session)
+
+	spec := &remoteExecSpec{
+		Command: "uptime",
+		Wait:    time.Second,
+		Batches: [][]string{{"other"}, {"another", agent.config.NodeName}},
+	}
+	buf, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	setKV(t, agent, "_rexec/"+event.Session+"/job", buf)
+
+	handle := func(batch int) {
+		event.Batch = batch
+		buf, err := json.Marshal(event)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		agent.handleRemoteExec(&UserEvent{ID: generateUUID(), Payload: buf})
+	}
+	ack := "_rexec/" + event.Session + "/" + agent.config.NodeName + "/ack"
+
+	// We aren't in the first batch, or one that doesn't exist.
+	handle(1)
+	handle(3)
+	if d := getKV(t, agent, ack); d != nil {
+		t.Fatalf("should not ack: %#v", d)
+	}
+
+	// We are in the second batch.
+	handle(2)
+	if d := getKV(t, agent, ack); d == nil || d.Session != event.Session {
+		t.Fatalf("bad ack: %#v", d)
+	}
+	exit := "_rexec/" + event.Session + "/" + agent.config.NodeName + "/exit"
+	if d := getKV(t, agent, exit); d == nil || string(d.Value) != "0" {
+		t.Fatalf("bad exit: %#v", d)
+	}
+}
+
 func makeRexecSession(t *testing.T, agent *Agent) string {
 	args := structs.SessionRequest{
 		Datacenter: agent.config.Datacenter,
@@ -0,0 +1,52 @@
+package agent
+
+import (
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/consul/structs"
+)
+
+// setDefaultRTTSource sets this agent as the source node for sorting by RTT
+// if the request didn't give one and the agent is configured to sort by
+// default. Coordinates can't be compared across datacenters, so this only
+// applies to requests for the local datacenter. It returns true if the
+// source was set.
+func (a *Agent) setDefaultRTTSource(source *structs.QuerySource) bool {
+	if source.Node != "" || !a.config.RTTSort.DefaultNearAgent || a.config.DisableCoordinates {
+		return false
+	}
+	if source.Datacenter == "" {
+		source.Datacenter = a.config.Datacenter
+	}
+	if source.Datacenter != a.config.Datacenter {
+		return false
+	}
+	source.Node = a.config.NodeName
+	return true
+}
+
+// isSortedByRTT returns true if results for a request from the given source
+// will be sorted by the servers.
+func (a *Agent) isSortedByRTT(source structs.QuerySource) bool {
+	return source.Node != "" && source.Datacenter == a.config.Datacenter
+}
+
+// nearestHealthy returns up to n of the given nodes, keeping them in order
+// and skipping any with a critical check, or with any check that isn't
+// passing if onlyPassing is set. A limit of 0 returns all the healthy nodes.
+func nearestHealthy(nodes structs.CheckServiceNodes, onlyPassing bool, n int) structs.CheckServiceNodes {
+	out := nodes[:0]
+OUTER:
+	for _, node := range nodes {
+		if n > 0 && len(out) == n {
+			break
+		}
+		for _, check := range node.Checks {
+			if check.Status == api.HealthCritical ||
+				(onlyPassing && check.Status != api.HealthPassing) {
+				continue OUTER
+			}
+		}
+		out = append(out, node)
+	}
+	return out
+}
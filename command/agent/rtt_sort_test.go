@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/consul/structs"
+)
+
+func TestNearestHealthy(t *testing.T) {
+	node := func(name, status string) structs.CheckServiceNode {
+		return structs.CheckServiceNode{
+			Node:   &structs.Node{Node: name},
+			Checks: structs.HealthChecks{&structs.HealthCheck{Status: status}},
+		}
+	}
+	names := func(nodes structs.CheckServiceNodes) []string {
+		out := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			out = append(out, node.Node.Node)
+		}
+		return out
+	}
+
+	cases := []struct {
+		onlyPassing bool
+		n           int
+		expected    []string
+	}{
+		{false, 0, []string{"a", "c", "d", "e"}},
+		{true, 0, []string{"c", "e"}},
+		{false, 2, []string{"a", "c"}},
+		{true, 1, []string{"c"}},
+		{true, 5, []string{"c", "e"}},
+	}
+	for i, tc := range cases {
+		nodes := structs.CheckServiceNodes{
+			node("a", api.HealthWarning),
+			node("b", api.HealthCritical),
+			node("c", api.HealthPassing),
+			node("d", api.HealthWarning),
+			node("e", api.HealthPassing),
+		}
+		got := names(nearestHealthy(nodes, tc.onlyPassing, tc.n))
+		if !reflect.DeepEqual(got, tc.expected) {
+			t.Fatalf("case %d: got %v want %v", i, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package agent
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/armon/go-metrics"
+	"github.com/armon/go-metrics/datadog"
+)
+
+// reloadableSink is a metrics sink that forwards to the statsite, statsd
+// and DogStatsd sinks from the agent's configuration. These can be swapped
+// out when the configuration is reloaded without setting up go-metrics
+// again.
+type reloadableSink struct {
+	sinks    metrics.FanoutSink
+	hostName string
+
+	// l guards sinks. It is held for reading while forwarding so that old
+	// sinks aren't shut down while they are still in use.
+	l sync.RWMutex
+}
+
+// newReloadableSink returns a reloadableSink forwarding to the sinks given
+// in the telemetry config.
+func newReloadableSink(config *Telemetry, hostName string) (*reloadableSink, error) {
+	sinks, err := newTelemetrySinks(config, hostName)
+	if err != nil {
+		return nil, err
+	}
+	return &reloadableSink{sinks: sinks, hostName: hostName}, nil
+}
+
+// Len returns the number of sinks being forwarded to.
+func (s *reloadableSink) Len() int {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	return len(s.sinks)
+}
+
+// Reload sets up the sinks given in the telemetry config and swaps them in.
+// The current sinks are kept if any of the new ones fail.
+func (s *reloadableSink) Reload(config *Telemetry) error {
+	sinks, err := newTelemetrySinks(config, s.hostName)
+	if err != nil {
+		return err
+	}
+	s.Swap(sinks)
+	return nil
+}
+
+// Swap replaces the sinks being forwarded to, and shuts down the old ones.
+func (s *reloadableSink) Swap(sinks metrics.FanoutSink) {
+	s.l.Lock()
+	old := s.sinks
+	s.sinks = sinks
+	s.l.Unlock()
+
+	for _, sink := range old {
+		if shutdown, ok := sink.(interface {
+			Shutdown()
+		}); ok {
+			shutdown.Shutdown()
+		}
+	}
+}
+
+func (s *reloadableSink) SetGauge(key []string, val float32) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	s.sinks.SetGauge(key, val)
+}
+
+func (s *reloadableSink) EmitKey(key []string, val float32) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	s.sinks.EmitKey(key, val)
+}
+
+func (s *reloadableSink) IncrCounter(key []string, val float32) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	s.sinks.IncrCounter(key, val)
+}
+
+func (s *reloadableSink) AddSample(key []string, val float32) {
+	s.l.RLock()
+	defer s.l.RUnlock()
+	s.sinks.AddSample(key, val)
+}
+
+// telemetrySinksChanged returns true if any of the settings used by
+// newTelemetrySinks differ between the two configs.
+func telemetrySinksChanged(a, b *Telemetry) bool {
+	if a.StatsiteAddr != b.StatsiteAddr ||
+		a.StatsdAddr != b.StatsdAddr ||
+		a.DogStatsdAddr != b.DogStatsdAddr ||
+		len(a.DogStatsdTags) != len(b.DogStatsdTags) {
+		return true
+	}
+	for i := range a.DogStatsdTags {
+		if a.DogStatsdTags[i] != b.DogStatsdTags[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// newTelemetrySinks sets up the statsite, statsd and DogStatsd sinks given
+// in the telemetry config. The Circonus sink isn't included since it can't
+// be stopped once it has started.
+func newTelemetrySinks(config *Telemetry, hostName string) (metrics.FanoutSink, error) {
+	var fanout metrics.FanoutSink
+
+	// Configure the statsite sink
+	if config.StatsiteAddr != "" {
+		sink, err := metrics.NewStatsiteSink(config.StatsiteAddr)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to start statsite sink. Got: %s", err)
+		}
+		fanout = append(fanout, sink)
+	}
+
+	// Configure the statsd sink
+	if config.StatsdAddr != "" {
+		sink, err := metrics.NewStatsdSink(config.StatsdAddr)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to start statsd sink. Got: %s", err)
+		}
+		fanout = append(fanout, sink)
+	}
+
+	// Configure the DogStatsd sink
+	if config.DogStatsdAddr != "" {
+		var tags []string
+
+		if config.DogStatsdTags != nil {
+			tags = config.DogStatsdTags
+		}
+
+		sink, err := datadog.NewDogStatsdSink(config.DogStatsdAddr, hostName)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to start DogStatsd sink. Got: %s", err)
+		}
+		sink.SetTags(tags)
+		fanout = append(fanout, sink)
+	}
+
+	return fanout, nil
+}
@@ -40,6 +40,19 @@ type UserEvent struct {
 	// Version of the user event. Automatically generated.
 	Version int `codec:"v"`
 
+	// PayloadKey is set for events whose payload was too large to gossip.
+	// The payload is stored under this KV key instead, and is fetched by
+	// the receiving agents. Automatically generated.
+	PayloadKey string `codec:"pk,omitempty"`
+
+	// PayloadChecksum is the hex encoded SHA256 checksum of a payload
+	// stored under PayloadKey. Automatically generated.
+	PayloadChecksum string `codec:"pc,omitempty"`
+
+	// Durable events are also recorded by the servers, so agents that
+	// miss the gossip can catch up and acknowledge them later.
+	Durable bool `codec:"d,omitempty"`
+
 	// LTime is the lamport time. Automatically generated.
 	LTime uint64 `codec:"-"`
 }
@@ -89,6 +102,19 @@ func (a *Agent) UserEvent(dc, token string, params *UserEvent) error {
 		return fmt.Errorf("UserEvent encoding failed: %v", err)
 	}
 
+	// If the event is too large to gossip, move the payload into the KV
+	// store and send a reference to it instead.
+	if isLargeUserEvent(params.Name, payload) {
+		if err := a.storeLargeEventPayload(dc, token, params); err != nil {
+			return fmt.Errorf("Failed to store large event payload: %v", err)
+		}
+		ref := *params
+		ref.Payload = nil
+		if payload, err = encodeMsgPack(&ref); err != nil {
+			return fmt.Errorf("UserEvent encoding failed: %v", err)
+		}
+	}
+
 	// Service the event fire over RPC. This ensures that we authorize
 	// the request against the token first.
 	args := structs.EventFireRequest{
@@ -99,10 +125,24 @@ func (a *Agent) UserEvent(dc, token string, params *UserEvent) error {
 	}
 
 	// Any server can process in the remote DC, since the
-	// gossip will take over anyways
-	args.AllowStale = true
+	// gossip will take over anyways. Durable events need to be
+	// recorded by the leader.
+	if params.Durable {
+		args.Durable = true
+		args.ID = params.ID
+	} else {
+		args.AllowStale = true
+	}
 	var out structs.EventFireResponse
-	return a.RPC("Internal.EventFire", &args, &out)
+	if err := a.RPC("Internal.EventFire", &args, &out); err != nil {
+		// Durable payloads aren't bound to a session, so clean up after
+		// an event that was never recorded.
+		if params.Durable && params.PayloadKey != "" {
+			a.deleteLargeEventPayload(dc, token, params.PayloadKey)
+		}
+		return err
+	}
+	return nil
 }
 
 // handleEvents is used to process incoming user events
@@ -117,19 +157,54 @@ func (a *Agent) handleEvents() {
 				continue
 			}
 			msg.LTime = uint64(e.LTime)
+			a.processUserEvent(msg)
 
-			// Skip if we don't pass filtering
-			if !a.shouldProcessUserEvent(msg) {
-				continue
-			}
+		case <-a.shutdownCh:
+			return
+		}
+	}
+}
 
-			// Ingest the event
-			a.ingestUserEvent(msg)
+// processUserEvent filters and ingests a decoded user event, acknowledging
+// it with the servers if it's durable.
+func (a *Agent) processUserEvent(msg *UserEvent) {
+	// Durable events may arrive both over gossip and from the
+	// catch-up at startup, so only handle them once.
+	if msg.Durable && a.hasUserEvent(msg.ID) {
+		return
+	}
 
-		case <-a.shutdownCh:
+	// Skip if we don't pass filtering
+	if !a.shouldProcessUserEvent(msg) {
+		return
+	}
+
+	// Fetch the payload if it was too large to gossip
+	if msg.PayloadKey != "" {
+		if err := a.fetchLargeEventPayload(msg); err != nil {
+			a.logger.Printf("[ERR] agent: Failed to fetch payload for event '%s' (%s): %v",
+				msg.Name, msg.ID, err)
 			return
 		}
 	}
+
+	// Ingest the event
+	a.ingestUserEvent(msg)
+	if msg.Durable {
+		go a.ackDurableEvent(msg.ID)
+	}
+}
+
+// hasUserEvent checks if an event with the given ID is in the recent events.
+func (a *Agent) hasUserEvent(id string) bool {
+	a.eventLock.RLock()
+	defer a.eventLock.RUnlock()
+	for _, msg := range a.eventBuf {
+		if msg != nil && msg.ID == id {
+			return true
+		}
+	}
+	return false
 }
 
 // shouldProcessUserEvent checks if an event makes it through our filters
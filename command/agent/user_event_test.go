@@ -187,6 +187,75 @@ func TestFireReceiveEvent(t *testing.T) {
 	}
 }
 
+func TestDurableEventCatchUp(t *testing.T) {
+	conf := nextConfig()
+	conf.Version = "0.8.4"
+	dir, agent := makeAgent(t, conf)
+	defer os.RemoveAll(dir)
+	defer agent.Shutdown()
+
+	testrpc.WaitForLeader(t, agent.RPC, "dc1")
+
+	// Record a durable event directly with the servers, as if it had
+	// been fired while this agent was down.
+	msg := &UserEvent{
+		ID:      "d6a9b8a4-6b2d-4c3a-9e1e-3e6c8fd2c2a1",
+		Name:    "deploy",
+		Version: userEventMaxVersion,
+		Durable: true,
+	}
+	payload, err := encodeMsgPack(msg)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	args := structs.EventFireRequest{
+		Datacenter: "dc1",
+		Name:       msg.Name,
+		Payload:    payload,
+		Durable:    true,
+		ID:         msg.ID,
+	}
+	var out structs.EventFireResponse
+	if err := agent.RPC("Internal.EventFire", &args, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Clear out anything that arrived over gossip.
+	agent.eventLock.Lock()
+	agent.eventBuf = make([]*UserEvent, len(agent.eventBuf))
+	agent.eventIndex = 0
+	agent.eventLock.Unlock()
+
+	if err := agent.syncDurableEvents(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if !agent.hasUserEvent(msg.ID) {
+		t.Fatalf("should have caught up on the event")
+	}
+
+	// Once it's acknowledged, it shouldn't be ingested again.
+	retry.Run(t, func(r *retry.R) {
+		req := structs.DCSpecificRequest{Datacenter: "dc1"}
+		var reply structs.IndexedDurableEvents
+		if err := agent.RPC("Internal.DurableEventList", &req, &reply); err != nil {
+			r.Fatal(err)
+		}
+		if len(reply.Acks) != 1 || reply.Acks[0].Node != conf.NodeName {
+			r.Fatalf("bad: %#v", reply.Acks)
+		}
+	})
+	agent.eventLock.Lock()
+	agent.eventBuf = make([]*UserEvent, len(agent.eventBuf))
+	agent.eventIndex = 0
+	agent.eventLock.Unlock()
+	if err := agent.syncDurableEvents(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if agent.hasUserEvent(msg.ID) {
+		t.Fatalf("should not process an acknowledged event")
+	}
+}
+
 func TestUserEventToken(t *testing.T) {
 	conf := nextConfig()
 
@@ -0,0 +1,20 @@
+// +build !windows
+
+package agent
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// setProcessGroup makes the command start in a process group of its own, so
+// killProcessGroup can kill it along with any children it starts.
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills a command started with setProcessGroup, along with
+// everything else in its process group.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
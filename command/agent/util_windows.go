@@ -0,0 +1,17 @@
+// +build windows
+
+package agent
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is a no-op on Windows, which doesn't have process groups.
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// killProcessGroup kills the command. Children it started are left running,
+// since Windows doesn't have process groups.
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,560 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/agent"
+	"github.com/hashicorp/consul/command/base"
+)
+
+const (
+	// debugInterval is the default time between snapshots of metrics and
+	// profiles.
+	debugInterval = 30 * time.Second
+
+	// debugMinInterval is the shortest interval allowed. CPU profiles are
+	// taken in whole seconds.
+	debugMinInterval = time.Second
+
+	// debugDuration is the default amount of time to capture for.
+	debugDuration = 2 * time.Minute
+
+	// debugRedacted replaces sensitive values in the captured config.
+	debugRedacted = "<redacted>"
+)
+
+// debugTargets are the kinds of information that can be captured. The
+// pprof target is skipped if the agent doesn't have enable_debug set.
+var debugTargets = []string{"agent", "cluster", "metrics", "logs", "pprof"}
+
+// debugIndex is written to the archive to describe the capture.
+type debugIndex struct {
+	Version   string
+	NodeName  string
+	Timestamp time.Time
+	Duration  string
+	Interval  string
+	Targets   []string
+	Errors    []string `json:",omitempty"`
+}
+
+// DebugCommand is a Command implementation that captures information
+// about a running agent over a period of time and saves it to an archive
+// for later analysis.
+type DebugCommand struct {
+	base.Command
+
+	ShutdownCh <-chan struct{}
+
+	client   *api.Client
+	dir      string
+	interval time.Duration
+	duration time.Duration
+
+	// errors lists what couldn't be captured. It's guarded by errorsLock
+	// since logs are captured in the background.
+	errors     []string
+	errorsLock sync.Mutex
+}
+
+func (c *DebugCommand) Help() string {
+	helpText := `
+Usage: consul debug [options]
+
+  Captures information about a running Consul agent over a period of time
+  and saves it as a timestamped tar.gz archive in the current directory,
+  to help diagnose problems.
+
+  The archive holds the agent's configuration with any secrets redacted,
+  the LAN and WAN member lists, the Raft configuration and Autopilot health
+  of the servers, and the logs written during the capture. Metrics, along
+  with heap, goroutine and CPU profiles, are captured at each interval.
+  Profiles are only available if the agent has "enable_debug" set.
+
+  To capture everything for the default two minutes:
+
+      $ consul debug
+
+  To capture only metrics and profiles for ten minutes, with a snapshot
+  every minute:
+
+      $ consul debug -duration=10m -interval=1m -capture=metrics -capture=pprof
+
+` + c.Command.Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *DebugCommand) Run(args []string) int {
+	var capture []string
+	var output string
+
+	f := c.Command.NewFlagSet(c)
+	f.DurationVar(&c.duration, "duration", debugDuration,
+		"How long to capture for. Defaults to 2m.")
+	f.DurationVar(&c.interval, "interval", debugInterval,
+		"How often to capture metrics and profiles. Defaults to 30s.")
+	f.Var((*agent.AppendSliceValue)(&capture), "capture",
+		fmt.Sprintf("One of %s to capture. Can be specified multiple times. "+
+			"Defaults to all of them.", strings.Join(debugTargets, ", ")))
+	f.StringVar(&output, "output", "",
+		"Path of the archive to write, without the .tar.gz extension. Defaults "+
+			"to consul-debug-<timestamp> in the current directory.")
+
+	if err := c.Command.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(f.Args()) > 0 {
+		c.UI.Error("Too many arguments (expected 0)")
+		return 1
+	}
+	if c.interval < debugMinInterval {
+		c.UI.Error(fmt.Sprintf("The interval must be at least %s", debugMinInterval))
+		return 1
+	}
+	if c.duration < c.interval {
+		c.UI.Error("The duration must be at least as long as the interval")
+		return 1
+	}
+	targets, err := debugCaptureTargets(capture)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.client, err = c.Command.HTTPClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	// Make sure we can talk to the agent before starting, and find out if
+	// profiles are available.
+	self, err := c.client.Agent().Self()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error querying agent: %s", err))
+		return 1
+	}
+	if targets["pprof"] {
+		if enabled, _ := self["Config"]["EnableDebug"].(bool); !enabled {
+			c.UI.Warn("Skipping profiles since the agent doesn't have enable_debug set")
+			delete(targets, "pprof")
+		}
+	}
+
+	now := time.Now()
+	if output == "" {
+		output = fmt.Sprintf("consul-debug-%d", now.Unix())
+	}
+	archive := output + ".tar.gz"
+	if _, err := os.Stat(archive); err == nil {
+		c.UI.Error(fmt.Sprintf("Output file %q already exists", archive))
+		return 1
+	}
+
+	c.dir, err = ioutil.TempDir("", "consul-debug")
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error creating temporary directory: %s", err))
+		return 1
+	}
+	defer os.RemoveAll(c.dir)
+
+	c.UI.Output(fmt.Sprintf("Capturing %s of debug information, every %s", c.duration, c.interval))
+
+	if targets["agent"] {
+		c.captureSelf(self)
+	}
+	if targets["cluster"] {
+		c.captureCluster()
+	}
+	c.captureOverTime(targets)
+
+	index := &debugIndex{
+		Version:   debugString(self["Config"]["Version"]),
+		NodeName:  debugString(self["Config"]["NodeName"]),
+		Timestamp: now.UTC(),
+		Duration:  c.duration.String(),
+		Interval:  c.interval.String(),
+	}
+	c.errorsLock.Lock()
+	index.Errors = c.errors
+	c.errorsLock.Unlock()
+	for _, target := range debugTargets {
+		if targets[target] {
+			index.Targets = append(index.Targets, target)
+		}
+	}
+	c.writeJSON("index.json", index)
+
+	if err := debugArchive(c.dir, archive, filepath.Base(output)); err != nil {
+		c.UI.Error(fmt.Sprintf("Error writing archive: %s", err))
+		return 1
+	}
+
+	c.UI.Info(fmt.Sprintf("Saved debug archive: %s", archive))
+	return 0
+}
+
+// captureSelf saves the agent's configuration and stats, with any secrets
+// redacted.
+func (c *DebugCommand) captureSelf(self map[string]map[string]interface{}) {
+	for _, section := range self {
+		debugRedact(section)
+	}
+	c.writeJSON("agent.json", self)
+}
+
+// captureCluster saves the member lists along with the Raft configuration
+// and Autopilot health of the servers.
+func (c *DebugCommand) captureCluster() {
+	members, err := c.client.Agent().Members(false)
+	if err != nil {
+		c.addError("members", err)
+	} else {
+		c.writeJSON("members.json", members)
+	}
+
+	members, err = c.client.Agent().Members(true)
+	if err != nil {
+		c.addError("WAN members", err)
+	} else {
+		c.writeJSON("members_wan.json", members)
+	}
+
+	raft, err := c.client.Operator().RaftGetConfiguration(nil)
+	if err != nil {
+		c.addError("Raft configuration", err)
+	} else {
+		c.writeJSON("raft.json", raft)
+	}
+
+	health, err := c.client.Operator().AutopilotServerHealth(nil)
+	if err != nil {
+		if unhealthy, ok := unhealthyAutopilotReply(err); ok {
+			c.writeJSON("autopilot.json", unhealthy)
+		} else {
+			c.addError("Autopilot health", err)
+		}
+	} else {
+		c.writeJSON("autopilot.json", health)
+	}
+}
+
+// unhealthyAutopilotReply recovers the health report from the error the API
+// client returns when the cluster is unhealthy. The endpoint responds with a
+// 429 in that case, but the body still holds the full report, which is what
+// we want to capture.
+func unhealthyAutopilotReply(err error) (*api.OperatorHealthReply, bool) {
+	const prefix = "Unexpected response code: 429 ("
+	msg := err.Error()
+	if !strings.HasPrefix(msg, prefix) || !strings.HasSuffix(msg, ")") {
+		return nil, false
+	}
+
+	var reply api.OperatorHealthReply
+	body := msg[len(prefix) : len(msg)-1]
+	if err := json.Unmarshal([]byte(body), &reply); err != nil {
+		return nil, false
+	}
+	return &reply, true
+}
+
+// captureOverTime streams the agent's logs for the duration of the capture,
+// and saves metrics and profiles in a directory for each interval. It stops
+// early if the command is interrupted.
+func (c *DebugCommand) captureOverTime(targets map[string]bool) {
+	stopCh := make(chan struct{})
+	logsDoneCh := make(chan struct{})
+
+	// Only one value is sent on the shutdown channel for each signal, so
+	// turn the first one into a closed channel that all the waits below
+	// can see.
+	interruptCh := make(chan struct{})
+	go func() {
+		select {
+		case <-c.ShutdownCh:
+			close(interruptCh)
+		case <-stopCh:
+		}
+	}()
+
+	if targets["logs"] {
+		go c.captureLogs(stopCh, logsDoneCh)
+	} else {
+		close(logsDoneCh)
+	}
+
+	// Take a snapshot at the start of each interval. The CPU profile runs
+	// for the whole interval, so the next one starts as soon as it's done.
+	deadline := time.After(c.duration)
+	intervals := int(c.duration / c.interval)
+OUTER:
+	for i := 0; i < intervals; i++ {
+		intervalCh := time.After(c.interval)
+		dir := fmt.Sprintf("%d", time.Now().Unix())
+		if targets["metrics"] {
+			c.captureMetrics(dir)
+		}
+		if targets["pprof"] {
+			c.captureProfiles(dir, interruptCh)
+		}
+
+		select {
+		case <-interruptCh:
+			c.UI.Warn("Interrupted, saving what has been captured so far")
+			break OUTER
+		case <-intervalCh:
+		}
+	}
+
+	// Keep capturing logs for the rest of the duration.
+	select {
+	case <-deadline:
+	case <-interruptCh:
+	}
+
+	close(stopCh)
+	<-logsDoneCh
+}
+
+// captureMetrics saves a snapshot of the agent's metrics.
+func (c *DebugCommand) captureMetrics(dir string) {
+	metrics, err := c.client.Agent().Metrics()
+	if err != nil {
+		c.addError("metrics", err)
+		return
+	}
+	c.writeJSON(filepath.Join(dir, "metrics.json"), metrics)
+}
+
+// captureProfiles saves heap and goroutine profiles, and a CPU profile
+// taken over the interval. This blocks until the CPU profile is done, or
+// interruptCh is closed.
+func (c *DebugCommand) captureProfiles(dir string, interruptCh <-chan struct{}) {
+	debug := c.client.Debug()
+	if heap, err := debug.Heap(); err != nil {
+		c.addError("heap profile", err)
+	} else {
+		c.writeFile(filepath.Join(dir, "heap.prof"), heap)
+	}
+	if goroutine, err := debug.Goroutine(); err != nil {
+		c.addError("goroutine profile", err)
+	} else {
+		c.writeFile(filepath.Join(dir, "goroutine.prof"), goroutine)
+	}
+
+	type result struct {
+		profile []byte
+		err     error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		profile, err := debug.Profile(int(c.interval.Seconds()))
+		resultCh <- result{profile, err}
+	}()
+	select {
+	case r := <-resultCh:
+		if r.err != nil {
+			c.addError("CPU profile", r.err)
+		} else {
+			c.writeFile(filepath.Join(dir, "profile.prof"), r.profile)
+		}
+	case <-interruptCh:
+	}
+}
+
+// captureLogs writes the agent's logs to a file until stopCh is closed.
+func (c *DebugCommand) captureLogs(stopCh <-chan struct{}, doneCh chan<- struct{}) {
+	defer close(doneCh)
+
+	monitorStopCh := make(chan struct{})
+	defer close(monitorStopCh)
+	logCh, err := c.client.Agent().Monitor("DEBUG", monitorStopCh, nil)
+	if err != nil {
+		c.addError("logs", err)
+		return
+	}
+
+	f, err := os.Create(filepath.Join(c.dir, "consul.log"))
+	if err != nil {
+		c.addError("logs", err)
+		return
+	}
+	defer f.Close()
+
+	for {
+		select {
+		case log := <-logCh:
+			if log == "" {
+				return
+			}
+			if _, err := fmt.Fprintln(f, log); err != nil {
+				c.addError("logs", err)
+				return
+			}
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// addError records something that couldn't be captured. The capture carries
+// on, and the errors are listed in the archive's index.
+func (c *DebugCommand) addError(what string, err error) {
+	c.errorsLock.Lock()
+	defer c.errorsLock.Unlock()
+
+	msg := fmt.Sprintf("Error capturing %s: %s", what, err)
+	c.UI.Warn(msg)
+	c.errors = append(c.errors, msg)
+}
+
+// writeJSON saves the given value as indented JSON in the capture directory.
+func (c *DebugCommand) writeJSON(name string, v interface{}) {
+	buf, err := json.MarshalIndent(v, "", "    ")
+	if err != nil {
+		c.addError(name, err)
+		return
+	}
+	c.writeFile(name, buf)
+}
+
+// writeFile saves the given contents in the capture directory.
+func (c *DebugCommand) writeFile(name string, buf []byte) {
+	path := filepath.Join(c.dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		c.addError(name, err)
+		return
+	}
+	if err := ioutil.WriteFile(path, buf, 0644); err != nil {
+		c.addError(name, err)
+	}
+}
+
+func (c *DebugCommand) Synopsis() string {
+	return "Records a debugging archive for operators"
+}
+
+// debugCaptureTargets validates the -capture flags, returning the set of
+// targets to capture.
+func debugCaptureTargets(capture []string) (map[string]bool, error) {
+	if len(capture) == 0 {
+		capture = debugTargets
+	}
+	targets := make(map[string]bool)
+	for _, target := range capture {
+		valid := false
+		for _, t := range debugTargets {
+			if target == t {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return nil, fmt.Errorf("Unknown capture target %q, must be one of %s",
+				target, strings.Join(debugTargets, ", "))
+		}
+		targets[target] = true
+	}
+	return targets, nil
+}
+
+// debugRedact replaces the values of keys that look like they hold secrets,
+// descending into nested objects. The agent already leaves most secrets out
+// of its configuration, so this is a second line of defense.
+func debugRedact(m map[string]interface{}) {
+	for key, value := range m {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			debugRedact(v)
+		case string:
+			if v != "" && debugSensitive(key) {
+				m[key] = debugRedacted
+			}
+		}
+	}
+}
+
+// debugSensitive returns true if a config key looks like it holds a secret.
+func debugSensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, s := range []string{"token", "secret", "password", "encrypt"} {
+		if strings.Contains(key, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// debugString returns the value if it's a string, or an empty string.
+func debugString(v interface{}) string {
+	s, _ := v.(string)
+	return s
+}
+
+// debugArchive writes the contents of dir to a tar.gz archive at path, with
+// every entry under the given prefix.
+func debugArchive(dir, path, prefix string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	err = filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(filepath.Join(prefix, rel))
+		if info.IsDir() {
+			header.Name += "/"
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		in, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,242 @@
+package command
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/command/agent"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/mitchellh/cli"
+)
+
+func testDebugCommand(t *testing.T) (*cli.MockUi, *DebugCommand) {
+	ui := new(cli.MockUi)
+	return ui, &DebugCommand{
+		Command: base.Command{
+			UI:    ui,
+			Flags: base.FlagSetClientHTTP,
+		},
+	}
+}
+
+func TestDebugCommand_implements(t *testing.T) {
+	var _ cli.Command = &DebugCommand{}
+}
+
+func TestDebugCommand_BadArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"extra"}, "Too many arguments"},
+		{[]string{"-interval=500ms"}, "interval must be at least"},
+		{[]string{"-duration=1s", "-interval=2s"}, "duration must be at least"},
+		{[]string{"-capture=nope"}, "Unknown capture target"},
+	}
+	for _, tt := range tests {
+		ui, c := testDebugCommand(t)
+		if code := c.Run(tt.args); code != 1 {
+			t.Fatalf("%v: bad: %d", tt.args, code)
+		}
+		if out := ui.ErrorWriter.String(); !strings.Contains(out, tt.want) {
+			t.Fatalf("%v: bad: %s", tt.args, out)
+		}
+	}
+}
+
+func TestDebugCommand_Run(t *testing.T) {
+	a1 := testAgentWithConfig(t, func(c *agent.Config) {
+		c.EnableDebug = true
+		c.RaftProtocol = 3
+	})
+	defer a1.Shutdown()
+
+	dir := testutil.TempDir(t, "debug")
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "capture")
+
+	ui, c := testDebugCommand(t)
+	args := []string{
+		"-http-addr=" + a1.httpAddr,
+		"-duration=2s",
+		"-interval=1s",
+		"-output=" + output,
+
+		// The test agent doesn't keep metrics in memory, so leave them out.
+		"-capture=agent",
+		"-capture=cluster",
+		"-capture=logs",
+		"-capture=pprof",
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	// Gather up the files in the archive, folding the per-interval
+	// directories together.
+	f, err := os.Open(output + ".tar.gz")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	files := make(map[string]int)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !strings.HasPrefix(header.Name, "capture/") {
+			t.Fatalf("bad: %s", header.Name)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		files[filepath.Base(header.Name)]++
+	}
+
+	want := map[string]int{
+		"index.json":       1,
+		"agent.json":       1,
+		"members.json":     1,
+		"members_wan.json": 1,
+		"raft.json":        1,
+		"autopilot.json":   1,
+		"consul.log":       1,
+		"heap.prof":        2,
+		"goroutine.prof":   2,
+		"profile.prof":     2,
+	}
+	if !reflect.DeepEqual(files, want) {
+		t.Fatalf("got %v want %v: %s", files, want, ui.ErrorWriter.String())
+	}
+}
+
+func TestDebugCommand_NoProfiles(t *testing.T) {
+	a1 := testAgent(t)
+	defer a1.Shutdown()
+
+	dir := testutil.TempDir(t, "debug")
+	defer os.RemoveAll(dir)
+
+	ui, c := testDebugCommand(t)
+	args := []string{
+		"-http-addr=" + a1.httpAddr,
+		"-duration=1s",
+		"-interval=1s",
+		"-capture=pprof",
+		"-output=" + filepath.Join(dir, "capture"),
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "enable_debug") {
+		t.Fatalf("bad: %s", out)
+	}
+}
+
+func TestDebugCommand_Interrupt(t *testing.T) {
+	a1 := testAgentWithConfig(t, func(c *agent.Config) {
+		c.EnableDebug = true
+	})
+	defer a1.Shutdown()
+
+	dir := testutil.TempDir(t, "debug")
+	defer os.RemoveAll(dir)
+
+	// Send a single interrupt while the first CPU profile is running, the
+	// same way makeShutdownCh does for a signal.
+	shutdownCh := make(chan struct{})
+	go func() {
+		time.Sleep(500 * time.Millisecond)
+		shutdownCh <- struct{}{}
+	}()
+
+	ui, c := testDebugCommand(t)
+	c.ShutdownCh = shutdownCh
+	args := []string{
+		"-http-addr=" + a1.httpAddr,
+		"-duration=20s",
+		"-interval=10s",
+		"-capture=logs",
+		"-capture=pprof",
+		"-output=" + filepath.Join(dir, "capture"),
+	}
+	start := time.Now()
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("took too long to stop: %s", elapsed)
+	}
+	if out := ui.ErrorWriter.String(); !strings.Contains(out, "Interrupted") {
+		t.Fatalf("bad: %s", out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "capture.tar.gz")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestUnhealthyAutopilotReply(t *testing.T) {
+	body := `{"Healthy":false,"FailureTolerance":0,"Servers":[{"Name":"node1","Healthy":false}]}`
+	reply, ok := unhealthyAutopilotReply(fmt.Errorf("Unexpected response code: 429 (%s)", body))
+	if !ok {
+		t.Fatalf("should recover the report")
+	}
+	if reply.Healthy || len(reply.Servers) != 1 || reply.Servers[0].Name != "node1" {
+		t.Fatalf("bad: %#v", reply)
+	}
+
+	for _, msg := range []string{
+		"Unexpected response code: 500 (" + body + ")",
+		"Unexpected response code: 429 (not json)",
+		"connection refused",
+	} {
+		if _, ok := unhealthyAutopilotReply(errors.New(msg)); ok {
+			t.Fatalf("%q: should not recover a report", msg)
+		}
+	}
+}
+
+func TestDebugRedact(t *testing.T) {
+	config := map[string]interface{}{
+		"NodeName":   "node1",
+		"ACLToken":   "secret",
+		"EncryptKey": "",
+		"Telemetry": map[string]interface{}{
+			"CirconusAPIToken": "secret",
+			"StatsdAddr":       "127.0.0.1:8125",
+		},
+	}
+	debugRedact(config)
+
+	want := map[string]interface{}{
+		"NodeName":   "node1",
+		"ACLToken":   debugRedacted,
+		"EncryptKey": "",
+		"Telemetry": map[string]interface{}{
+			"CirconusAPIToken": debugRedacted,
+			"StatsdAddr":       "127.0.0.1:8125",
+		},
+	}
+	if !reflect.DeepEqual(config, want) {
+		t.Fatalf("got %v want %v", config, want)
+	}
+}
@@ -21,7 +21,9 @@ Usage: consul event [options] [payload]
 
   Dispatches a custom user event across a datacenter. An event must provide
   a name, but a payload is optional. Events support filtering using
-  regular expressions on node name, service, and tag definitions.
+  regular expressions on node name, service, and tag definitions. Durable
+  events are also recorded on the servers, and their acknowledgements can be
+  read from the /v1/event/list?durable endpoint.
 
 ` + c.Command.Help()
 
@@ -30,6 +32,7 @@ Usage: consul event [options] [payload]
 
 func (c *EventCommand) Run(args []string) int {
 	var name, node, service, tag string
+	var durable bool
 
 	f := c.Command.NewFlagSet(c)
 	f.StringVar(&name, "name", "",
@@ -40,6 +43,9 @@ func (c *EventCommand) Run(args []string) int {
 		"Regular expression to filter on service instances.")
 	f.StringVar(&tag, "tag", "",
 		"Regular expression to filter on service tags. Must be used with -service.")
+	f.BoolVar(&durable, "durable", false,
+		"Record the event on the servers so agents that miss it can catch up "+
+			"and acknowledge it later.")
 
 	if err := c.Command.Parse(args); err != nil {
 		return 1
@@ -111,6 +117,7 @@ func (c *EventCommand) Run(args []string) int {
 		NodeFilter:    node,
 		ServiceFilter: service,
 		TagFilter:     tag,
+		Durable:       durable,
 	}
 
 	// Fire the event
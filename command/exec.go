@@ -8,6 +8,7 @@ import (
 	"os"
 	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -16,6 +17,7 @@ import (
 	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/base"
 	"github.com/mitchellh/cli"
+	"github.com/ryanuber/columnize"
 )
 
 const (
@@ -49,6 +51,10 @@ const (
 	// rExecRenewInterval is how often we renew the session TTL
 	// when doing an exec in a foreign DC.
 	rExecRenewInterval = 5 * time.Second
+
+	// rExecTimeoutExitCode is the exit code agents report when they kill
+	// a command for running past its timeout.
+	rExecTimeoutExitCode = 124
 )
 
 // rExecConf is used to pass around configuration
@@ -65,6 +71,10 @@ type rExecConf struct {
 
 	wait     time.Duration
 	replWait time.Duration
+	timeout  time.Duration
+
+	batchSize   string
+	maxFailures int
 
 	cmd    string
 	script []byte
@@ -76,6 +86,10 @@ type rExecConf struct {
 type rExecEvent struct {
 	Prefix  string
 	Session string
+
+	// Batch is the 1-based index of the batch in the spec that this
+	// event is for. Zero means every node matching the filters.
+	Batch int `json:",omitempty"`
 }
 
 // rExecSpec is the file we upload to specify the parameters
@@ -89,6 +103,15 @@ type rExecSpec struct {
 
 	// Wait is how long we are waiting on a quiet period to terminate
 	Wait time.Duration
+
+	// Timeout is how long the command can run on each node before it
+	// is killed. Zero means no limit.
+	Timeout time.Duration `json:",omitempty"`
+
+	// Batches are the names of the nodes in each batch of a rolling
+	// execution. These are kept here rather than in the event, since
+	// they won't fit in a gossiped event for larger clusters.
+	Batches [][]string `json:",omitempty"`
 }
 
 // rExecAck is used to transmit an acknowledgement
@@ -140,6 +163,17 @@ func (c *ExecCommand) Run(args []string) int {
 	f.DurationVar(&c.conf.replWait, "wait-repl", rExecReplicationWait,
 		"Period to wait for replication before firing event. This is an "+
 			"optimization to allow stale reads to be performed.")
+	f.DurationVar(&c.conf.timeout, "timeout", 0,
+		"Hard limit on how long the command can run on each node before it "+
+			"is killed and reported with exit code 124. The default of 0 means "+
+			"no limit.")
+	f.StringVar(&c.conf.batchSize, "batch-size", "",
+		"Runs the command on the matching nodes in batches of this many "+
+			"nodes, or a percentage of them such as \"25%\", waiting for each "+
+			"batch to finish before starting the next.")
+	f.IntVar(&c.conf.maxFailures, "max-failures", 0,
+		"Aborts the remaining batches once this many nodes have failed. "+
+			"Must be used with -batch-size. The default of 0 never aborts.")
 	f.BoolVar(&c.conf.verbose, "verbose", false,
 		"Enables verbose output.")
 
@@ -201,8 +235,30 @@ func (c *ExecCommand) Run(args []string) int {
 		c.conf.localNode = info["Config"]["NodeName"].(string)
 	}
 
+	// Work out the batches of nodes to run on if this is a rolling
+	// execution. These go in the job spec so the agents can tell which
+	// batch they're in. Otherwise there's a single batch that targets
+	// every node matching the filters.
+	var batches [][]string
+	if c.conf.batchSize != "" {
+		nodes, err := c.resolveNodes()
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Failed to find matching nodes: %s", err))
+			return 1
+		}
+		if len(nodes) == 0 {
+			c.UI.Error("No nodes match the given filters")
+			return 1
+		}
+		batches = c.conf.makeBatches(nodes)
+		if c.conf.verbose {
+			c.UI.Info(fmt.Sprintf("Running on %d node(s) in %d batch(es)",
+				len(nodes), len(batches)))
+		}
+	}
+
 	// Create the job spec
-	spec, err := c.makeRExecSpec()
+	spec, err := c.makeRExecSpec(batches)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Failed to create job spec: %s", err))
 		return 1
@@ -239,22 +295,17 @@ func (c *ExecCommand) Run(args []string) int {
 		return 1
 	}
 
-	// Fire the event
-	id, err := c.fireEvent()
-	if err != nil {
-		c.UI.Error(fmt.Sprintf("Failed to fire event: %s", err))
-		return 1
-	}
-	if c.conf.verbose {
-		c.UI.Info(fmt.Sprintf("Fired remote execution event: %s", id))
-	}
-
 	// Wait for the job to finish now
-	return c.waitForJob()
+	if batches == nil {
+		batches = [][]string{nil}
+	}
+	return c.waitForJob(batches)
 }
 
-// waitForJob is used to poll for results and wait until the job is terminated
-func (c *ExecCommand) waitForJob() int {
+// waitForJob fires the event for each batch of nodes in turn, polling for
+// results and waiting until each batch is terminated. A nil batch targets
+// every node matching the filters.
+func (c *ExecCommand) waitForJob(batches [][]string) int {
 	// Although the session destroy is already deferred, we do it again here,
 	// because invalidation of the session before destroyData() ensures there is
 	// no race condition allowing an agent to upload data (the acquire will fail).
@@ -270,67 +321,161 @@ func (c *ExecCommand) waitForJob() int {
 	go c.streamResults(doneCh, ackCh, heartCh, outputCh, exitCh, errCh)
 	target := &TargetedUI{UI: c.UI}
 
-	var ackCount, exitCount, badExit int
-OUTER:
-	for {
-		// Determine wait time. We provide a larger window if we know about
-		// nodes which are still working.
-		waitIntv := c.conf.wait
-		if ackCount > exitCount {
-			waitIntv *= 2
+	acks := make(map[string]struct{})
+	exits := make(map[string]int)
+	var expected []string
+	var failures int
+	for i, batch := range batches {
+		// Stop starting new batches once too many nodes have failed.
+		if c.conf.maxFailures > 0 && failures >= c.conf.maxFailures {
+			c.UI.Error(fmt.Sprintf("Aborting after %d node(s) failed, skipping %d batch(es)",
+				failures, len(batches)-i))
+			break
 		}
 
-		select {
-		case e := <-ackCh:
-			ackCount++
-			if c.conf.verbose {
-				target.Target = e.Node
-				target.Info("acknowledged")
+		if len(batches) > 1 {
+			c.UI.Info(fmt.Sprintf("Starting batch %d / %d with %d node(s)",
+				i+1, len(batches), len(batch)))
+		}
+		var num int
+		if batch != nil {
+			num = i + 1
+		}
+		id, err := c.fireEvent(num)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Failed to fire event: %s", err))
+			return 1
+		}
+		if c.conf.verbose {
+			c.UI.Info(fmt.Sprintf("Fired remote execution event: %s", id))
+		}
+		expected = append(expected, batch...)
+
+	WAIT:
+		for {
+			// If we know which nodes are in the batch then we can move
+			// on as soon as they have all finished.
+			if batch != nil && allExited(batch, exits) {
+				break WAIT
 			}
 
-		case h := <-heartCh:
-			if c.conf.verbose {
-				target.Target = h.Node
-				target.Info("heartbeat received")
+			// Determine wait time. We provide a larger window if we know
+			// about nodes which are still working.
+			waitIntv := c.conf.wait
+			if len(acks) > len(exits) {
+				waitIntv *= 2
 			}
 
-		case e := <-outputCh:
-			target.Target = e.Node
-			target.Output(string(e.Output))
+			select {
+			case e := <-ackCh:
+				acks[e.Node] = struct{}{}
+				if c.conf.verbose {
+					target.Target = e.Node
+					target.Info("acknowledged")
+				}
 
-		case e := <-exitCh:
-			exitCount++
-			target.Target = e.Node
-			target.Info(fmt.Sprintf("finished with exit code %d", e.Code))
-			if e.Code != 0 {
-				badExit++
-			}
+			case h := <-heartCh:
+				if c.conf.verbose {
+					target.Target = h.Node
+					target.Info("heartbeat received")
+				}
 
-		case <-time.After(waitIntv):
-			c.UI.Info(fmt.Sprintf("%d / %d node(s) completed / acknowledged", exitCount, ackCount))
-			if c.conf.verbose {
-				c.UI.Info(fmt.Sprintf("Completed in %0.2f seconds",
-					float64(time.Now().Sub(start))/float64(time.Second)))
-			}
-			if exitCount < ackCount {
-				badExit++
-			}
-			break OUTER
+			case e := <-outputCh:
+				target.Target = e.Node
+				target.Output(string(e.Output))
 
-		case <-errCh:
-			return 1
+			case e := <-exitCh:
+				exits[e.Node] = e.Code
+				target.Target = e.Node
+				target.Info(fmt.Sprintf("finished with exit code %d", e.Code))
 
-		case <-c.ShutdownCh:
-			return 1
+			case <-time.After(waitIntv):
+				break WAIT
+
+			case <-errCh:
+				return 1
+
+			case <-c.ShutdownCh:
+				return 1
+			}
 		}
+
+		failures = len(c.summarize(acks, exits, expected, false))
 	}
 
-	if badExit > 0 {
+	c.UI.Info(fmt.Sprintf("%d / %d node(s) completed / acknowledged", len(exits), len(acks)))
+	if c.conf.verbose {
+		c.UI.Info(fmt.Sprintf("Completed in %0.2f seconds",
+			float64(time.Now().Sub(start))/float64(time.Second)))
+	}
+
+	// Show how each node did. Any node that failed, didn't finish, or was
+	// expected to run the command but never acknowledged it counts as bad.
+	if len(acks) > 0 || len(expected) > 0 {
+		c.UI.Output("")
+		c.UI.Output(columnize.SimpleFormat(c.summarize(acks, exits, expected, true)))
+	}
+	if failures > 0 {
 		return 2
 	}
 	return 0
 }
 
+// summarize returns a "Node|Result" row for each node. If all is false,
+// only the rows for nodes that failed are returned, without a header.
+func (c *ExecCommand) summarize(acks map[string]struct{}, exits map[string]int, expected []string, all bool) []string {
+	nodes := make(map[string]struct{})
+	for node := range acks {
+		nodes[node] = struct{}{}
+	}
+	for node := range exits {
+		nodes[node] = struct{}{}
+	}
+	for _, node := range expected {
+		nodes[node] = struct{}{}
+	}
+	var names []string
+	for node := range nodes {
+		names = append(names, node)
+	}
+	sort.Strings(names)
+
+	var rows []string
+	if all {
+		rows = append(rows, "Node|Result")
+	}
+	for _, node := range names {
+		var result string
+		failed := true
+		if code, ok := exits[node]; ok {
+			result = fmt.Sprintf("exit code %d", code)
+			if code == 0 {
+				failed = false
+			} else if code == rExecTimeoutExitCode && c.conf.timeout > 0 {
+				result += " (timed out)"
+			}
+		} else if _, ok := acks[node]; ok {
+			result = "did not finish"
+		} else {
+			result = "did not acknowledge"
+		}
+		if all || failed {
+			rows = append(rows, fmt.Sprintf("%s|%s", node, result))
+		}
+	}
+	return rows
+}
+
+// allExited returns true if all the given nodes have reported an exit code.
+func allExited(nodes []string, exits map[string]int) bool {
+	for _, node := range nodes {
+		if _, ok := exits[node]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 // streamResults is used to perform blocking queries against the KV endpoint and stream in
 // notice of various events into waitForJob
 func (c *ExecCommand) streamResults(doneCh chan struct{}, ackCh chan rExecAck, heartCh chan rExecHeart,
@@ -444,9 +589,128 @@ func (conf *rExecConf) validate() error {
 	if conf.tag != "" && conf.service == "" {
 		return fmt.Errorf("Cannot provide tag filter without service filter.")
 	}
+
+	// Validate the rolling options
+	if conf.batchSize != "" {
+		if _, _, err := parseBatchSize(conf.batchSize); err != nil {
+			return err
+		}
+	}
+	if conf.maxFailures < 0 {
+		return fmt.Errorf("Max failures must not be negative.")
+	}
+	if conf.maxFailures > 0 && conf.batchSize == "" {
+		return fmt.Errorf("Cannot provide max failures without a batch size.")
+	}
+	if conf.timeout < 0 {
+		return fmt.Errorf("Timeout must not be negative.")
+	}
 	return nil
 }
 
+// parseBatchSize parses a batch size, which is either a number of nodes or a
+// percentage of them such as "25%".
+func parseBatchSize(size string) (int, bool, error) {
+	percent := strings.HasSuffix(size, "%")
+	n, err := strconv.Atoi(strings.TrimSuffix(size, "%"))
+	if err != nil || n < 1 || (percent && n > 100) {
+		return 0, false, fmt.Errorf("Invalid batch size %q, must be a positive number of nodes or a percentage", size)
+	}
+	return n, percent, nil
+}
+
+// makeBatches splits the given nodes into batches. There is always at least
+// one node in each batch.
+func (conf *rExecConf) makeBatches(nodes []string) [][]string {
+	size, percent, _ := parseBatchSize(conf.batchSize)
+	if percent {
+		size = (len(nodes)*size + 99) / 100
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	var batches [][]string
+	for len(nodes) > 0 {
+		n := size
+		if n > len(nodes) {
+			n = len(nodes)
+		}
+		batches = append(batches, nodes[:n])
+		nodes = nodes[n:]
+	}
+	return batches
+}
+
+// resolveNodes looks up the names of the nodes matching the filters in the
+// catalog, so they can be split into batches. The agents still apply the
+// filters themselves when they get the event.
+func (c *ExecCommand) resolveNodes() ([]string, error) {
+	catalog := c.client.Catalog()
+
+	var nodeRe *regexp.Regexp
+	if c.conf.node != "" {
+		nodeRe = regexp.MustCompile(c.conf.node)
+	}
+	nodes, _, err := catalog.Nodes(nil)
+	if err != nil {
+		return nil, err
+	}
+	matches := make(map[string]bool)
+	for _, node := range nodes {
+		if nodeRe == nil || nodeRe.MatchString(node.Node) {
+			matches[node.Node] = c.conf.service == ""
+		}
+	}
+
+	// Narrow it down to the nodes with a matching service.
+	if c.conf.service != "" {
+		serviceRe := regexp.MustCompile(c.conf.service)
+		var tagRe *regexp.Regexp
+		if c.conf.tag != "" {
+			tagRe = regexp.MustCompile(c.conf.tag)
+		}
+
+		services, _, err := catalog.Services(nil)
+		if err != nil {
+			return nil, err
+		}
+		for name := range services {
+			if !serviceRe.MatchString(name) {
+				continue
+			}
+			instances, _, err := catalog.Service(name, "", nil)
+			if err != nil {
+				return nil, err
+			}
+			for _, instance := range instances {
+				if _, ok := matches[instance.Node]; !ok {
+					continue
+				}
+				if tagRe == nil {
+					matches[instance.Node] = true
+					continue
+				}
+				for _, tag := range instance.ServiceTags {
+					if tagRe.MatchString(tag) {
+						matches[instance.Node] = true
+						break
+					}
+				}
+			}
+		}
+	}
+
+	var names []string
+	for node, ok := range matches {
+		if ok {
+			names = append(names, node)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
 // createSession is used to create a new session for this command
 func (c *ExecCommand) createSession() (string, error) {
 	var id string
@@ -543,11 +807,13 @@ func (c *ExecCommand) destroySession() error {
 // makeRExecSpec creates a serialized job specification
 // that can be uploaded which will be parsed by agents to
 // determine what to do.
-func (c *ExecCommand) makeRExecSpec() ([]byte, error) {
+func (c *ExecCommand) makeRExecSpec(batches [][]string) ([]byte, error) {
 	spec := &rExecSpec{
 		Command: c.conf.cmd,
 		Script:  c.conf.script,
 		Wait:    c.conf.wait,
+		Timeout: c.conf.timeout,
+		Batches: batches,
 	}
 	return json.Marshal(spec)
 }
@@ -581,12 +847,15 @@ func (c *ExecCommand) destroyData() error {
 }
 
 // fireEvent is used to fire the event that will notify nodes
-// about the remote execution. Returns the event ID or error
-func (c *ExecCommand) fireEvent() (string, error) {
+// about the remote execution. If batch is non-zero then only
+// the nodes in that batch of the spec will run the command.
+// Returns the event ID or error
+func (c *ExecCommand) fireEvent(batch int) (string, error) {
 	// Create the user event payload
 	msg := &rExecEvent{
 		Prefix:  c.conf.prefix,
 		Session: c.sessionID,
+		Batch:   batch,
 	}
 	buf, err := json.Marshal(msg)
 	if err != nil {
@@ -621,6 +890,11 @@ Usage: consul exec [options] [-|command...]
   definitions. If a command is '-', stdin will be read until EOF
   and used as a script input.
 
+  By default the command is run on all the matching nodes at once. Use
+  -batch-size to roll through them in batches instead, and -max-failures
+  to stop once too many nodes have failed. A summary of each node's exit
+  code is shown at the end, and the exit status is 2 if any node failed.
+
 ` + c.Command.Help()
 
 	return strings.TrimSpace(helpText)
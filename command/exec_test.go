@@ -2,6 +2,7 @@ package command
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/hashicorp/consul/command/agent"
 	"github.com/hashicorp/consul/command/base"
 	"github.com/hashicorp/consul/testutil/retry"
+	"github.com/hashicorp/serf/serf"
 	"github.com/mitchellh/cli"
 )
 
@@ -140,6 +142,181 @@ func TestExecCommand_Validate(t *testing.T) {
 	if err == nil {
 		t.Fatalf("err: %v", err)
 	}
+
+	conf.tag = ""
+	for _, size := range []string{"0", "-1", "0%", "101%", "nope"} {
+		conf.batchSize = size
+		if err := conf.validate(); err == nil {
+			t.Fatalf("should fail for batch size %q", size)
+		}
+	}
+
+	conf.batchSize = ""
+	conf.maxFailures = 1
+	err = conf.validate()
+	if err == nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	conf.batchSize = "25%"
+	err = conf.validate()
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	conf.timeout = -time.Second
+	err = conf.validate()
+	if err == nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestExecCommand_makeBatches(t *testing.T) {
+	nodes := []string{"a", "b", "c", "d", "e"}
+	cases := []struct {
+		size     string
+		expected [][]string
+	}{
+		{"1", [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{"2", [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+		{"10", [][]string{{"a", "b", "c", "d", "e"}}},
+		{"50%", [][]string{{"a", "b", "c"}, {"d", "e"}}},
+		{"1%", [][]string{{"a"}, {"b"}, {"c"}, {"d"}, {"e"}}},
+		{"100%", [][]string{{"a", "b", "c", "d", "e"}}},
+	}
+	for _, tc := range cases {
+		conf := &rExecConf{batchSize: tc.size}
+		batches := conf.makeBatches(nodes)
+		if !reflect.DeepEqual(batches, tc.expected) {
+			t.Fatalf("%s: bad: %v", tc.size, batches)
+		}
+	}
+}
+
+func TestExecCommandRun_Batches(t *testing.T) {
+	a1 := testAgentWithConfig(t, func(c *agent.Config) {
+		c.DisableRemoteExec = agent.Bool(false)
+	})
+	defer a1.Shutdown()
+	waitForLeader(t, a1.httpAddr)
+
+	// Register a node that won't ever run the command. It sorts after
+	// the agent's node so it will be in the second batch.
+	client, err := httpClient(a1.httpAddr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = client.Catalog().Register(&consulapi.CatalogRegistration{
+		Node:    "zz-fake",
+		Address: "127.0.0.2",
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The node that never acknowledges should count as a failure.
+	ui, c := testExecCommand(t)
+	args := []string{"-http-addr=" + a1.httpAddr, "-wait=500ms", "-batch-size=1", "uptime"}
+	code := c.Run(args)
+	if code != 2 {
+		t.Fatalf("bad: %d. Error:%#v  (std)Output:%#v", code, ui.ErrorWriter.String(), ui.OutputWriter.String())
+	}
+	output := ui.OutputWriter.String()
+	for _, want := range []string{
+		"Starting batch 1 / 2",
+		"Starting batch 2 / 2",
+		"load",
+		a1.config.NodeName,
+		"exit code 0",
+		"zz-fake",
+		"did not acknowledge",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("bad: %#v missing %q", output, want)
+		}
+	}
+
+	// A failure in the first batch should stop the second one.
+	ui, c = testExecCommand(t)
+	args = []string{"-http-addr=" + a1.httpAddr, "-wait=500ms", "-batch-size=1",
+		"-max-failures=1", "exit 3"}
+	code = c.Run(args)
+	if code != 2 {
+		t.Fatalf("bad: %d. Error:%#v  (std)Output:%#v", code, ui.ErrorWriter.String(), ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Aborting after 1 node(s) failed") {
+		t.Fatalf("bad: %#v", ui.ErrorWriter.String())
+	}
+	output = ui.OutputWriter.String()
+	if strings.Contains(output, "batch 2") || strings.Contains(output, "zz-fake") ||
+		!strings.Contains(output, "exit code 3") {
+		t.Fatalf("bad: %#v", output)
+	}
+}
+
+func TestExecCommandRun_LargeBatch(t *testing.T) {
+	a1 := testAgentWithConfig(t, func(c *agent.Config) {
+		c.DisableRemoteExec = agent.Bool(false)
+	})
+	defer a1.Shutdown()
+	waitForLeader(t, a1.httpAddr)
+
+	// Register enough nodes with long names that a filter listing all
+	// of them wouldn't fit in a user event.
+	client, err := httpClient(a1.httpAddr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var size int
+	for i := 0; size <= serf.UserEventSizeLimit; i++ {
+		node := fmt.Sprintf("zz-fake-node-with-a-long-name-%03d", i)
+		_, err = client.Catalog().Register(&consulapi.CatalogRegistration{
+			Node:    node,
+			Address: "127.0.0.2",
+		}, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		size += len(node) + 1
+	}
+
+	// Everything goes in a single batch, so the agent should run the
+	// command and the fake nodes should count as failures.
+	ui, c := testExecCommand(t)
+	args := []string{"-http-addr=" + a1.httpAddr, "-wait=500ms", "-batch-size=100%", "uptime"}
+	code := c.Run(args)
+	if code != 2 {
+		t.Fatalf("bad: %d. Error:%#v  (std)Output:%#v", code, ui.ErrorWriter.String(), ui.OutputWriter.String())
+	}
+	output := ui.OutputWriter.String()
+	for _, want := range []string{
+		"load",
+		a1.config.NodeName,
+		"exit code 0",
+		"did not acknowledge",
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("bad: %#v missing %q", output, want)
+		}
+	}
+}
+
+func TestExecCommandRun_Timeout(t *testing.T) {
+	a1 := testAgentWithConfig(t, func(c *agent.Config) {
+		c.DisableRemoteExec = agent.Bool(false)
+	})
+	defer a1.Shutdown()
+	waitForLeader(t, a1.httpAddr)
+
+	ui, c := testExecCommand(t)
+	args := []string{"-http-addr=" + a1.httpAddr, "-wait=2s", "-timeout=500ms", "sleep 10"}
+	code := c.Run(args)
+	if code != 2 {
+		t.Fatalf("bad: %d. Error:%#v  (std)Output:%#v", code, ui.ErrorWriter.String(), ui.OutputWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), "exit code 124 (timed out)") {
+		t.Fatalf("bad: %#v", ui.OutputWriter.String())
+	}
 }
 
 func TestExecCommand_Sessions(t *testing.T) {
@@ -263,7 +440,7 @@ func TestExecCommand_UploadDestroy(t *testing.T) {
 	c.conf.cmd = "uptime"
 	c.conf.wait = time.Second
 
-	buf, err := c.makeRExecSpec()
+	buf, err := c.makeRExecSpec(nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
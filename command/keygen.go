@@ -21,19 +21,28 @@ func (c *KeygenCommand) Run(args []string) int {
 		return 1
 	}
 
+	key, err := generateKey()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Output(key)
+	return 0
+}
+
+// generateKey returns a new random gossip encryption key, base64 encoded in
+// the form the agent and the keyring command expect.
+func generateKey() (string, error) {
 	key := make([]byte, 16)
 	n, err := rand.Reader.Read(key)
 	if err != nil {
-		c.UI.Error(fmt.Sprintf("Error reading random data: %s", err))
-		return 1
+		return "", fmt.Errorf("Error reading random data: %s", err)
 	}
 	if n != 16 {
-		c.UI.Error(fmt.Sprintf("Couldn't read enough entropy. Generate more entropy!"))
-		return 1
+		return "", fmt.Errorf("Couldn't read enough entropy. Generate more entropy!")
 	}
-
-	c.UI.Output(base64.StdEncoding.EncodeToString(key))
-	return 0
+	return base64.StdEncoding.EncodeToString(key), nil
 }
 
 func (c *KeygenCommand) Synopsis() string {
@@ -2,6 +2,7 @@ package command
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 
 	consulapi "github.com/hashicorp/consul/api"
@@ -18,7 +19,7 @@ type KeyringCommand struct {
 
 func (c *KeyringCommand) Run(args []string) int {
 	var installKey, useKey, removeKey string
-	var listKeys bool
+	var listKeys, rotate bool
 	var relay int
 
 	f := c.Command.NewFlagSet(c)
@@ -35,6 +36,10 @@ func (c *KeyringCommand) Run(args []string) int {
 			"performed on keys which are not currently the primary key.")
 	f.BoolVar(&listKeys, "list", false,
 		"List all keys currently in use within the cluster.")
+	f.BoolVar(&rotate, "rotate", false,
+		"Rotate to a newly generated encryption key. The new key is installed, "+
+			"checked on every node, and made primary, and then the keys that "+
+			"were installed beforehand are removed.")
 	f.IntVar(&relay, "relay-factor", 0,
 		"Setting this to a non-zero value will cause nodes to relay their response "+
 			"to the operation through this many randomly-chosen other nodes in the "+
@@ -53,6 +58,11 @@ func (c *KeyringCommand) Run(args []string) int {
 
 	// Only accept a single argument
 	found := listKeys
+	if found && rotate {
+		c.UI.Error("Only a single action is allowed")
+		return 1
+	}
+	found = found || rotate
 	for _, arg := range []string{installKey, useKey, removeKey} {
 		if found && len(arg) > 0 {
 			c.UI.Error("Only a single action is allowed")
@@ -92,6 +102,10 @@ func (c *KeyringCommand) Run(args []string) int {
 		return 0
 	}
 
+	if rotate {
+		return c.rotateKey(client, relayFactor)
+	}
+
 	opts := &consulapi.WriteOptions{RelayFactor: relayFactor}
 	if installKey != "" {
 		c.UI.Info("Installing new gossip encryption key...")
@@ -127,15 +141,154 @@ func (c *KeyringCommand) Run(args []string) int {
 	return 0
 }
 
-func (c *KeyringCommand) handleList(responses []*consulapi.KeyringResponse) {
+// rotateKey replaces the cluster's gossip encryption keys with a newly
+// generated one. If any node is missing the new key after it's installed or
+// made primary, the rotation stops before the old keys are removed, so the
+// lagging nodes can still talk to the rest of the cluster.
+func (c *KeyringCommand) rotateKey(client *consulapi.Client, relayFactor uint8) int {
+	operator := client.Operator()
+	queryOpts := &consulapi.QueryOptions{RelayFactor: relayFactor}
+	writeOpts := &consulapi.WriteOptions{RelayFactor: relayFactor}
+
+	// Note the keys that are installed now, which are the ones to retire.
+	c.UI.Info("Gathering installed encryption keys...")
+	responses, err := operator.KeyringList(queryOpts)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("error: %s", err))
+		return 1
+	}
+	var oldKeys []string
+	seen := make(map[string]bool)
+	for _, response := range responses {
+		for key := range response.Keys {
+			if !seen[key] {
+				seen[key] = true
+				oldKeys = append(oldKeys, key)
+			}
+		}
+	}
+	sort.Strings(oldKeys)
+
+	newKey, err := generateKey()
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	c.UI.Info(fmt.Sprintf("Installing new gossip encryption key %s...", newKey))
+	if err := operator.KeyringInstall(newKey, writeOpts); err != nil {
+		c.UI.Error(fmt.Sprintf("error: %s", err))
+		return 1
+	}
+	if !c.verifyKey(operator, newKey, queryOpts) {
+		return 1
+	}
+
+	// The servers only report success once every node in every pool has
+	// switched, so any error means some nodes may still be using an old
+	// key and none of them can be removed yet.
+	c.UI.Info("Changing primary gossip encryption key...")
+	if err := operator.KeyringUse(newKey, writeOpts); err != nil {
+		c.UI.Error("")
+		c.UI.Error(fmt.Sprintf("Aborting rotation, key %s couldn't be made primary on some nodes:", newKey))
+		for _, line := range keyringErrorLines(err) {
+			c.UI.Error("  " + line)
+		}
+		c.UI.Error("")
+		c.UI.Error("No keys have been removed. Once the failed nodes are fixed, finish " +
+			"the rotation with -use and -remove.")
+		return 1
+	}
+
+	for _, key := range oldKeys {
+		c.UI.Info(fmt.Sprintf("Removing gossip encryption key %s...", key))
+		if err := operator.KeyringRemove(key, writeOpts); err != nil {
+			c.UI.Error(fmt.Sprintf("error: %s", err))
+			return 1
+		}
+	}
+
+	c.UI.Output(fmt.Sprintf("Rotated gossip encryption key to %s", newKey))
+	return 0
+}
+
+// verifyKey checks that every node in every pool has the given key
+// installed. If any don't, it reports how far behind each pool is.
+func (c *KeyringCommand) verifyKey(operator *consulapi.Operator, key string, q *consulapi.QueryOptions) bool {
+	c.UI.Info("Verifying the key is installed on all nodes...")
+	responses, err := operator.KeyringList(q)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("error: %s", err))
+		return false
+	}
+
+	lagging := laggingPools(responses, key)
+	if len(lagging) == 0 {
+		return true
+	}
+
+	c.UI.Error("")
+	c.UI.Error(fmt.Sprintf("Aborting rotation, key %s is missing from some nodes:", key))
+	for _, response := range lagging {
+		c.UI.Error(fmt.Sprintf("  %s: installed on %d/%d nodes",
+			keyringPool(response), response.Keys[key], response.NumNodes))
+	}
+	c.UI.Error("")
+	c.UI.Error("No keys have been removed. Once every node has the new key, finish " +
+		"the rotation with -use and -remove, or back it out with -remove.")
+	return false
+}
+
+// keyringErrorLines splits the error from a keyring operation into the
+// errors the servers reported for each gossip pool and node.
+func keyringErrorLines(err error) []string {
+	msg := err.Error()
+	if strings.HasPrefix(msg, "Unexpected response code: ") {
+		msg = strings.TrimSuffix(msg, ")")
+	}
+
+	var lines []string
+	for _, line := range strings.Split(msg, "\n") {
+		if strings.HasPrefix(line, "* ") {
+			lines = append(lines, strings.TrimPrefix(line, "* "))
+		}
+	}
+	if len(lines) == 0 {
+		return []string{err.Error()}
+	}
+	return lines
+}
+
+// laggingPools returns the responses for the pools where some nodes don't
+// have the given key installed, ordered by datacenter with the WAN pool last.
+func laggingPools(responses []*consulapi.KeyringResponse, key string) []*consulapi.KeyringResponse {
+	var lagging []*consulapi.KeyringResponse
 	for _, response := range responses {
-		pool := response.Datacenter + " (LAN)"
-		if response.WAN {
-			pool = "WAN"
+		if response.Keys[key] < response.NumNodes {
+			lagging = append(lagging, response)
+		}
+	}
+	sort.Slice(lagging, func(i, j int) bool {
+		if lagging[i].WAN != lagging[j].WAN {
+			return !lagging[i].WAN
 		}
+		return lagging[i].Datacenter < lagging[j].Datacenter
+	})
+	return lagging
+}
+
+// keyringPool returns the name of the gossip pool a response is for.
+func keyringPool(response *consulapi.KeyringResponse) string {
+	if response.WAN {
+		return "WAN"
+	}
+	return response.Datacenter + " (LAN)"
+}
 
+func (c *KeyringCommand) handleList(responses []*consulapi.KeyringResponse) {
+	for _, response := range responses {
 		c.UI.Output("")
-		c.UI.Output(pool + ":")
+		c.UI.Output(keyringPool(response) + ":")
 		for key, num := range response.Keys {
 			c.UI.Output(fmt.Sprintf("  %s [%d/%d]", key, num, response.NumNodes))
 		}
@@ -155,6 +308,10 @@ Usage: consul keyring [options]
   All operations performed by this command can only be run against server nodes,
   and affect both the LAN and WAN keyrings in lock-step.
 
+  The -rotate option performs a whole key rotation in one go. It stops before
+  retiring the old keys if any node fails to pick up the new one or to switch
+  to it, reporting which gossip pools have problems.
+
   All variations of the keyring command return 0 if all nodes reply and there
   are no errors. If any node fails to reply or reports failure, the exit code
   will be 1.
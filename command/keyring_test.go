@@ -1,9 +1,12 @@
 package command
 
 import (
+	"fmt"
+	"reflect"
 	"strings"
 	"testing"
 
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/agent"
 	"github.com/hashicorp/consul/command/base"
 	"github.com/mitchellh/cli"
@@ -73,6 +76,96 @@ func TestKeyringCommandRun(t *testing.T) {
 	}
 }
 
+func TestKeyringCommandRun_rotate(t *testing.T) {
+	key1 := "HS5lJ+XuTlYKWaeGYyG+/A=="
+	key2 := "kZyFABeAmc64UMTrm9XuKA=="
+
+	a1 := testAgentWithConfig(t, func(c *agent.Config) {
+		c.EncryptKey = key1
+	})
+	defer a1.Shutdown()
+
+	// Leave a second key installed alongside the primary.
+	installKey(t, a1.httpAddr, key2)
+
+	ui, c := testKeyringCommand(t)
+	args := []string{"-rotate", "-http-addr=" + a1.httpAddr}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	// Both of the old keys were retired in favor of a single new one.
+	out := listKeys(t, a1.httpAddr)
+	for _, key := range []string{key1, key2} {
+		if strings.Contains(out, key) {
+			t.Fatalf("bad: %#v", out)
+		}
+	}
+	lines := strings.Split(strings.TrimSpace(ui.OutputWriter.String()), "\n")
+	last := lines[len(lines)-1]
+	if !strings.HasPrefix(last, "Rotated gossip encryption key to ") {
+		t.Fatalf("bad: %#v", ui.OutputWriter.String())
+	}
+	newKey := strings.TrimPrefix(last, "Rotated gossip encryption key to ")
+	if !strings.Contains(out, "dc1 (LAN):\n  "+newKey+" [1/1]") {
+		t.Fatalf("bad: %#v", out)
+	}
+	if !strings.Contains(out, "WAN:\n  "+newKey+" [1/1]") {
+		t.Fatalf("bad: %#v", out)
+	}
+}
+
+func TestKeyringCommandRun_rotateWithOtherAction(t *testing.T) {
+	ui, c := testKeyringCommand(t)
+	args := []string{"-rotate", "-list"}
+	if code := c.Run(args); code != 1 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Only a single action is allowed") {
+		t.Fatalf("bad: %#v", ui.ErrorWriter.String())
+	}
+}
+
+func TestKeyringCommand_laggingPools(t *testing.T) {
+	key := "kZyFABeAmc64UMTrm9XuKA=="
+	responses := []*consulapi.KeyringResponse{
+		{WAN: true, Datacenter: "dc1", Keys: map[string]int{key: 2}, NumNodes: 3},
+		{Datacenter: "dc2", Keys: map[string]int{key: 4}, NumNodes: 5},
+		{Datacenter: "dc1", Keys: map[string]int{key: 3}, NumNodes: 3},
+		{Datacenter: "dc3", Keys: map[string]int{}, NumNodes: 2},
+	}
+
+	var pools []string
+	for _, response := range laggingPools(responses, key) {
+		pools = append(pools, keyringPool(response))
+	}
+	expected := []string{"dc2 (LAN)", "dc3 (LAN)", "WAN"}
+	if !reflect.DeepEqual(pools, expected) {
+		t.Fatalf("bad: %v", pools)
+	}
+}
+
+func TestKeyringCommand_keyringErrorLines(t *testing.T) {
+	err := fmt.Errorf("Unexpected response code: 500 (3 error(s) occurred:\n\n" +
+		"* dc2 (LAN) error: 1/2 nodes reported failure\n" +
+		"* node2: key (kZyFABeAmc64UMTrm9XuKA==) not installed (test)\n" +
+		"* WAN error: 1/2 nodes reported success)")
+	expected := []string{
+		"dc2 (LAN) error: 1/2 nodes reported failure",
+		"node2: key (kZyFABeAmc64UMTrm9XuKA==) not installed (test)",
+		"WAN error: 1/2 nodes reported success",
+	}
+	if lines := keyringErrorLines(err); !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("bad: %#v", lines)
+	}
+
+	// Other errors are passed through whole.
+	err = fmt.Errorf("connection refused")
+	if lines := keyringErrorLines(err); !reflect.DeepEqual(lines, []string{"connection refused"}) {
+		t.Fatalf("bad: %#v", lines)
+	}
+}
+
 func TestKeyringCommandRun_help(t *testing.T) {
 	ui, c := testKeyringCommand(t)
 	code := c.Run(nil)
@@ -36,9 +36,11 @@ Usage: consul monitor [options]
 
 func (c *MonitorCommand) Run(args []string) int {
 	var logLevel string
+	var logJSON bool
 
 	f := c.Command.NewFlagSet(c)
 	f.StringVar(&logLevel, "log-level", "INFO", "Log level of the agent.")
+	f.BoolVar(&logJSON, "log-json", false, "Output logs in JSON format.")
 
 	if err := c.Command.Parse(args); err != nil {
 		return 1
@@ -51,7 +53,11 @@ func (c *MonitorCommand) Run(args []string) int {
 	}
 
 	eventDoneCh := make(chan struct{})
-	logCh, err := client.Agent().Monitor(logLevel, eventDoneCh, nil)
+	monitor := client.Agent().Monitor
+	if logJSON {
+		monitor = client.Agent().MonitorJSON
+	}
+	logCh, err := monitor(logLevel, eventDoneCh, nil)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error starting monitor: %s", err))
 		return 1
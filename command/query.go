@@ -0,0 +1,134 @@
+package command
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/hashicorp/hcl"
+	"github.com/mitchellh/cli"
+)
+
+// QueryCommand is a Command implementation that just shows help for
+// the subcommands nested below it.
+type QueryCommand struct {
+	base.Command
+}
+
+func (c *QueryCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+func (c *QueryCommand) Help() string {
+	helpText := `
+Usage: consul query <subcommand> [options] [args]
+
+  This command has subcommands for managing and executing prepared queries.
+  Here are some simple examples, and more detailed examples are available in
+  the subcommands or the documentation.
+
+  Create a prepared query from a JSON or HCL definition:
+
+      $ consul query create redis.hcl
+
+  List the prepared queries:
+
+      $ consul query list
+
+  Execute a query by name and show the results:
+
+      $ consul query execute redis
+
+  See how a query or template would be executed:
+
+      $ consul query explain redis-prod
+
+  Finally, delete the query:
+
+      $ consul query delete redis
+
+  For more examples, ask for subcommand help or view the documentation.
+
+`
+	return strings.TrimSpace(helpText)
+}
+
+func (c *QueryCommand) Synopsis() string {
+	return "Manage and execute prepared queries"
+}
+
+// parseQueryDefinition reads a prepared query definition in JSON or HCL from
+// the given file, or from stdin if the file is "-". If testStdin is non-nil it
+// is used in place of stdin.
+func parseQueryDefinition(file string, testStdin io.Reader) (*api.PreparedQueryDefinition, error) {
+	var data []byte
+	var err error
+	if file == "-" {
+		var stdin io.Reader = os.Stdin
+		if testStdin != nil {
+			stdin = testStdin
+		}
+
+		var b bytes.Buffer
+		if _, err := io.Copy(&b, stdin); err != nil {
+			return nil, fmt.Errorf("Failed to read stdin: %v", err)
+		}
+		data = b.Bytes()
+	} else {
+		data, err = ioutil.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read file: %v", err)
+		}
+	}
+
+	var def api.PreparedQueryDefinition
+	if err := hcl.Decode(&def, string(data)); err != nil {
+		return nil, fmt.Errorf("Failed to parse query definition: %v", err)
+	}
+	return &def, nil
+}
+
+// lookupQuery finds a prepared query given its ID or name. Templates can only
+// be found by their exact name here, since this doesn't render them.
+func lookupQuery(client *api.Client, idOrName string, q *api.QueryOptions) (*api.PreparedQueryDefinition, error) {
+	defs, _, err := client.PreparedQuery().List(q)
+	if err != nil {
+		return nil, err
+	}
+	for _, def := range defs {
+		if def.ID == idOrName {
+			return def, nil
+		}
+	}
+	for _, def := range defs {
+		if def.Name == idOrName {
+			return def, nil
+		}
+	}
+	return nil, fmt.Errorf("No prepared query with ID or name %q", idOrName)
+}
+
+// validateQueryFormat makes sure the given output format is supported.
+func validateQueryFormat(format string) error {
+	switch format {
+	case "table", "json":
+		return nil
+	default:
+		return fmt.Errorf("Invalid format %q, must be \"table\" or \"json\"", format)
+	}
+}
+
+// queryJSON formats the given object as indented JSON.
+func queryJSON(v interface{}) (string, error) {
+	marshaled, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	return string(marshaled), nil
+}
@@ -0,0 +1,85 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/consul/command/base"
+)
+
+// QueryCreateCommand is a Command implementation that is used to create a
+// prepared query from a definition file.
+type QueryCreateCommand struct {
+	base.Command
+
+	// testStdin is the input for testing.
+	testStdin io.Reader
+}
+
+func (c *QueryCreateCommand) Help() string {
+	helpText := `
+Usage: consul query create [options] FILE
+
+  Creates a new prepared query from a JSON or HCL definition in FILE, and
+  prints the ID of the new query. The definition has the same structure as the
+  body of the /v1/query HTTP endpoint. Use "-" as the file name to read the
+  definition from stdin.
+
+      $ consul query create redis.hcl
+
+  For a full list of options and examples, please see the Consul documentation.
+
+` + c.Command.Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *QueryCreateCommand) Run(args []string) int {
+	f := c.Command.NewFlagSet(c)
+	if err := c.Command.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check for arg validation
+	args = f.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Missing FILE argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	def, err := parseQueryDefinition(args[0], c.testStdin)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	if def.ID != "" {
+		c.UI.Error("The query definition can't have an ID when creating a query; use 'consul query update' instead")
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.Command.HTTPClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	id, _, err := client.PreparedQuery().Create(def, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error creating prepared query: %s", err))
+		return 1
+	}
+
+	c.UI.Info(id)
+	return 0
+}
+
+func (c *QueryCreateCommand) Synopsis() string {
+	return "Creates a prepared query"
+}
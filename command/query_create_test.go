@@ -0,0 +1,109 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/command/base"
+	"github.com/mitchellh/cli"
+)
+
+func testQueryCreateCommand(t *testing.T) (*cli.MockUi, *QueryCreateCommand) {
+	ui := new(cli.MockUi)
+	return ui, &QueryCreateCommand{
+		Command: base.Command{
+			UI:    ui,
+			Flags: base.FlagSetHTTP,
+		},
+	}
+}
+
+func TestQueryCreateCommand_implements(t *testing.T) {
+	var _ cli.Command = &QueryCreateCommand{}
+}
+
+func TestQueryCreateCommand_noTabs(t *testing.T) {
+	assertNoTabs(t, new(QueryCreateCommand))
+}
+
+func TestQueryCreateCommand_Validation(t *testing.T) {
+	ui, c := testQueryCreateCommand(t)
+
+	cases := map[string]struct {
+		args   []string
+		stdin  string
+		output string
+	}{
+		"no file": {
+			[]string{},
+			"",
+			"Missing FILE argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar"},
+			"",
+			"Too many arguments",
+		},
+		"has ID": {
+			[]string{"-"},
+			`{"ID": "8f246b77-f3e1-ff88-5b48-8ec93abf3e05", "Service": {"Service": "redis"}}`,
+			"can't have an ID",
+		},
+	}
+
+	for name, tc := range cases {
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		c.testStdin = strings.NewReader(tc.stdin)
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestQueryCreateCommand_Run(t *testing.T) {
+	srv, client := testAgentWithAPIClient(t)
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	ui, c := testQueryCreateCommand(t)
+	c.testStdin = strings.NewReader(`
+Name = "redis"
+Service {
+  Service = "redis"
+  Tags = ["primary"]
+}
+`)
+
+	args := []string{
+		"-http-addr=" + srv.httpAddr,
+		"-",
+	}
+
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	id := strings.TrimSpace(ui.OutputWriter.String())
+	defs, _, err := client.PreparedQuery().Get(id, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(defs) != 1 || defs[0].Name != "redis" || defs[0].Service.Service != "redis" ||
+		len(defs[0].Service.Tags) != 1 || defs[0].Service.Tags[0] != "primary" {
+		t.Fatalf("bad: %#v", defs)
+	}
+}
@@ -0,0 +1,76 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+)
+
+// QueryDeleteCommand is a Command implementation that is used to delete a
+// prepared query.
+type QueryDeleteCommand struct {
+	base.Command
+}
+
+func (c *QueryDeleteCommand) Help() string {
+	helpText := `
+Usage: consul query delete [options] ID_OR_NAME
+
+  Deletes the prepared query with the given ID or name.
+
+      $ consul query delete redis
+
+  For a full list of options and examples, please see the Consul documentation.
+
+` + c.Command.Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *QueryDeleteCommand) Run(args []string) int {
+	f := c.Command.NewFlagSet(c)
+	if err := c.Command.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check for arg validation
+	args = f.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Missing ID_OR_NAME argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.Command.HTTPClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	def, err := lookupQuery(client, args[0], &api.QueryOptions{
+		AllowStale: c.Command.HTTPStale(),
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error looking up prepared query: %s", err))
+		return 1
+	}
+
+	if _, err := client.PreparedQuery().Delete(def.ID, nil); err != nil {
+		c.UI.Error(fmt.Sprintf("Error deleting prepared query: %s", err))
+		return 1
+	}
+
+	c.UI.Info(fmt.Sprintf("Deleted prepared query %s", def.ID))
+	return 0
+}
+
+func (c *QueryDeleteCommand) Synopsis() string {
+	return "Deletes a prepared query"
+}
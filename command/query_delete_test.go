@@ -0,0 +1,95 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/command/base"
+	"github.com/mitchellh/cli"
+)
+
+func testQueryDeleteCommand(t *testing.T) (*cli.MockUi, *QueryDeleteCommand) {
+	ui := new(cli.MockUi)
+	return ui, &QueryDeleteCommand{
+		Command: base.Command{
+			UI:    ui,
+			Flags: base.FlagSetHTTP,
+		},
+	}
+}
+
+func TestQueryDeleteCommand_implements(t *testing.T) {
+	var _ cli.Command = &QueryDeleteCommand{}
+}
+
+func TestQueryDeleteCommand_noTabs(t *testing.T) {
+	assertNoTabs(t, new(QueryDeleteCommand))
+}
+
+func TestQueryDeleteCommand_Validation(t *testing.T) {
+	ui, c := testQueryDeleteCommand(t)
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no query": {
+			[]string{},
+			"Missing ID_OR_NAME argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar"},
+			"Too many arguments",
+		},
+	}
+
+	for name, tc := range cases {
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestQueryDeleteCommand_Run(t *testing.T) {
+	srv, client := testAgentWithAPIClient(t)
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	testQueryCreate(t, client, "redis", "redis")
+	memcached := testQueryCreate(t, client, "memcached", "memcached")
+
+	// Delete one by name and the other by ID.
+	for _, idOrName := range []string{"redis", memcached} {
+		ui, c := testQueryDeleteCommand(t)
+		args := []string{
+			"-http-addr=" + srv.httpAddr,
+			idOrName,
+		}
+		code := c.Run(args)
+		if code != 0 {
+			t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+		}
+	}
+
+	defs, _, err := client.PreparedQuery().List(nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(defs) != 0 {
+		t.Fatalf("bad: %#v", defs)
+	}
+}
@@ -0,0 +1,119 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/ryanuber/columnize"
+)
+
+// QueryExecuteCommand is a Command implementation that is used to execute a
+// prepared query.
+type QueryExecuteCommand struct {
+	base.Command
+}
+
+func (c *QueryExecuteCommand) Help() string {
+	helpText := `
+Usage: consul query execute [options] ID_OR_NAME
+
+  Executes the prepared query with the given ID or name, which can also be a
+  name matching a query template, and shows the healthy nodes it returned.
+
+      $ consul query execute redis
+
+  To sort the results by network distance from the agent, use the -near
+  option with the special "_agent" value:
+
+      $ consul query execute -near=_agent redis
+
+  For a full list of options and examples, please see the Consul documentation.
+
+` + c.Command.Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *QueryExecuteCommand) Run(args []string) int {
+	f := c.Command.NewFlagSet(c)
+	format := f.String("format", "table",
+		"Output format, either \"table\" or \"json\".")
+	near := f.String("near", "",
+		"Node to sort the results near, by network distance. The special "+
+			"\"_agent\" value sorts near the agent servicing the request.")
+	if err := c.Command.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check for arg validation
+	args = f.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Missing ID_OR_NAME argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+	if err := validateQueryFormat(*format); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.Command.HTTPClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	results, _, err := client.PreparedQuery().Execute(args[0], &api.QueryOptions{
+		AllowStale: c.Command.HTTPStale(),
+		Near:       *near,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error executing prepared query: %s", err))
+		return 1
+	}
+
+	if *format == "json" {
+		out, err := queryJSON(results)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error formatting results: %s", err))
+			return 1
+		}
+		c.UI.Output(out)
+		return 0
+	}
+
+	c.UI.Output(columnize.SimpleFormat([]string{
+		fmt.Sprintf("Service:|%s", results.Service),
+		fmt.Sprintf("Datacenter:|%s", results.Datacenter),
+		fmt.Sprintf("Failovers:|%d", results.Failovers),
+	}))
+	c.UI.Output("")
+
+	if len(results.Nodes) == 0 {
+		c.UI.Output("No healthy nodes found")
+		return 0
+	}
+	result := []string{"Node|Address|Service ID|Port|Tags"}
+	for _, entry := range results.Nodes {
+		addr := entry.Node.Address
+		if entry.Service.Address != "" {
+			addr = entry.Service.Address
+		}
+		result = append(result, fmt.Sprintf("%s|%s|%s|%d|%s",
+			entry.Node.Node, addr, entry.Service.ID, entry.Service.Port,
+			strings.Join(entry.Service.Tags, ",")))
+	}
+	c.UI.Output(columnize.SimpleFormat(result))
+	return 0
+}
+
+func (c *QueryExecuteCommand) Synopsis() string {
+	return "Executes a prepared query"
+}
@@ -0,0 +1,143 @@
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/mitchellh/cli"
+)
+
+func testQueryExecuteCommand(t *testing.T) (*cli.MockUi, *QueryExecuteCommand) {
+	ui := new(cli.MockUi)
+	return ui, &QueryExecuteCommand{
+		Command: base.Command{
+			UI:    ui,
+			Flags: base.FlagSetHTTP,
+		},
+	}
+}
+
+func TestQueryExecuteCommand_implements(t *testing.T) {
+	var _ cli.Command = &QueryExecuteCommand{}
+}
+
+func TestQueryExecuteCommand_noTabs(t *testing.T) {
+	assertNoTabs(t, new(QueryExecuteCommand))
+}
+
+func TestQueryExecuteCommand_Validation(t *testing.T) {
+	ui, c := testQueryExecuteCommand(t)
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no query": {
+			[]string{},
+			"Missing ID_OR_NAME argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar"},
+			"Too many arguments",
+		},
+		"bad format": {
+			[]string{"-format=xml", "foo"},
+			"Invalid format",
+		},
+	}
+
+	for name, tc := range cases {
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestQueryExecuteCommand_Run(t *testing.T) {
+	srv, client := testAgentWithAPIClient(t)
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	_, err := client.Catalog().Register(&api.CatalogRegistration{
+		Node:    "foo",
+		Address: "127.0.0.2",
+		Service: &api.AgentService{
+			ID:      "redis1",
+			Service: "redis",
+			Tags:    []string{"primary"},
+			Port:    6379,
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	testQueryCreate(t, client, "redis", "redis")
+
+	// Check the table output.
+	ui, c := testQueryExecuteCommand(t)
+	args := []string{
+		"-http-addr=" + srv.httpAddr,
+		"-near=_agent",
+		"redis",
+	}
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	output := ui.OutputWriter.String()
+	for _, want := range []string{"redis", "dc1", "foo", "127.0.0.2", "redis1", "6379", "primary"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("bad: %#v missing %q", output, want)
+		}
+	}
+
+	// Check the JSON output.
+	ui, c = testQueryExecuteCommand(t)
+	args = []string{
+		"-http-addr=" + srv.httpAddr,
+		"-format=json",
+		"redis",
+	}
+	code = c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	var results api.PreparedQueryExecuteResponse
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &results); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if results.Service != "redis" || len(results.Nodes) != 1 ||
+		results.Nodes[0].Node.Node != "foo" {
+		t.Fatalf("bad: %#v", results)
+	}
+
+	// An unknown query should fail.
+	ui, c = testQueryExecuteCommand(t)
+	args = []string{
+		"-http-addr=" + srv.httpAddr,
+		"nope",
+	}
+	if code := c.Run(args); code == 0 {
+		t.Fatalf("bad: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "Error executing prepared query") {
+		t.Fatalf("bad: %#v", ui.ErrorWriter.String())
+	}
+}
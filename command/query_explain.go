@@ -0,0 +1,111 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/ryanuber/columnize"
+)
+
+// QueryExplainCommand is a Command implementation that is used to show how a
+// prepared query would be executed.
+type QueryExplainCommand struct {
+	base.Command
+}
+
+func (c *QueryExplainCommand) Help() string {
+	helpText := `
+Usage: consul query explain [options] ID_OR_NAME
+
+  Shows the fully-rendered prepared query for the given ID or name, which is
+  useful for seeing how a name matches a query template. This also shows the
+  targets that were tried when running the query's failover policy, and which
+  one was chosen and why.
+
+      $ consul query explain redis-prod
+
+  For a full list of options and examples, please see the Consul documentation.
+
+` + c.Command.Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *QueryExplainCommand) Run(args []string) int {
+	f := c.Command.NewFlagSet(c)
+	format := f.String("format", "table",
+		"Output format, either \"table\" or \"json\". The rendered query is "+
+			"always shown as JSON.")
+	if err := c.Command.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check for arg validation
+	args = f.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Missing ID_OR_NAME argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+	if err := validateQueryFormat(*format); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.Command.HTTPClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	explain, _, err := client.PreparedQuery().Explain(args[0], &api.QueryOptions{
+		AllowStale: c.Command.HTTPStale(),
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error explaining prepared query: %s", err))
+		return 1
+	}
+
+	if *format == "json" {
+		out, err := queryJSON(explain)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error formatting explanation: %s", err))
+			return 1
+		}
+		c.UI.Output(out)
+		return 0
+	}
+
+	query, err := queryJSON(explain.Query)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error formatting prepared query: %s", err))
+		return 1
+	}
+	c.UI.Output("Query:")
+	c.UI.Output(query)
+	c.UI.Output("")
+
+	result := []string{"Datacenter|Service|Healthy|Chosen|Reason"}
+	for _, target := range explain.Targets {
+		chosen := ""
+		if target.Chosen {
+			chosen = "*"
+		}
+		result = append(result, fmt.Sprintf("%s|%s|%d|%s|%s",
+			target.Datacenter, target.Service, target.Healthy, chosen, target.Reason))
+	}
+	c.UI.Output("Targets:")
+	c.UI.Output(columnize.SimpleFormat(result))
+	return 0
+}
+
+func (c *QueryExplainCommand) Synopsis() string {
+	return "Shows how a prepared query would be executed"
+}
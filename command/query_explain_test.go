@@ -0,0 +1,128 @@
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/mitchellh/cli"
+)
+
+func testQueryExplainCommand(t *testing.T) (*cli.MockUi, *QueryExplainCommand) {
+	ui := new(cli.MockUi)
+	return ui, &QueryExplainCommand{
+		Command: base.Command{
+			UI:    ui,
+			Flags: base.FlagSetHTTP,
+		},
+	}
+}
+
+func TestQueryExplainCommand_implements(t *testing.T) {
+	var _ cli.Command = &QueryExplainCommand{}
+}
+
+func TestQueryExplainCommand_noTabs(t *testing.T) {
+	assertNoTabs(t, new(QueryExplainCommand))
+}
+
+func TestQueryExplainCommand_Validation(t *testing.T) {
+	ui, c := testQueryExplainCommand(t)
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no query": {
+			[]string{},
+			"Missing ID_OR_NAME argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar"},
+			"Too many arguments",
+		},
+		"bad format": {
+			[]string{"-format=xml", "foo"},
+			"Invalid format",
+		},
+	}
+
+	for name, tc := range cases {
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestQueryExplainCommand_Run(t *testing.T) {
+	srv, client := testAgentWithAPIClient(t)
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	_, _, err := client.PreparedQuery().Create(&api.PreparedQueryDefinition{
+		Name: "geo-db",
+		Template: api.QueryTemplate{
+			Type:   "name_prefix_match",
+			Regexp: "^geo-db-(.*?)$",
+		},
+		Service: api.ServiceQuery{
+			Service: "mysql-${match(1)}",
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The table output should show the rendered query and the target
+	// that was tried.
+	ui, c := testQueryExplainCommand(t)
+	args := []string{
+		"-http-addr=" + srv.httpAddr,
+		"geo-db-customer",
+	}
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	output := ui.OutputWriter.String()
+	for _, want := range []string{"mysql-customer", "Targets:", "dc1"} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("bad: %#v missing %q", output, want)
+		}
+	}
+
+	// Check the JSON output.
+	ui, c = testQueryExplainCommand(t)
+	args = []string{
+		"-http-addr=" + srv.httpAddr,
+		"-format=json",
+		"geo-db-customer",
+	}
+	code = c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	var explain api.PreparedQueryExplainResponse
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &explain); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if explain.Query.Service.Service != "mysql-customer" || len(explain.Targets) == 0 {
+		t.Fatalf("bad: %#v", explain)
+	}
+}
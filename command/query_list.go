@@ -0,0 +1,101 @@
+package command
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/ryanuber/columnize"
+)
+
+// QueryListCommand is a Command implementation that is used to list the
+// prepared queries.
+type QueryListCommand struct {
+	base.Command
+}
+
+func (c *QueryListCommand) Help() string {
+	helpText := `
+Usage: consul query list [options]
+
+  Lists the prepared queries that the token can see.
+
+      $ consul query list
+
+  For a full list of options and examples, please see the Consul documentation.
+
+` + c.Command.Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *QueryListCommand) Run(args []string) int {
+	f := c.Command.NewFlagSet(c)
+	format := f.String("format", "table",
+		"Output format, either \"table\" or \"json\".")
+	if err := c.Command.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check for arg validation
+	if args = f.Args(); len(args) > 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(args)))
+		return 1
+	}
+	if err := validateQueryFormat(*format); err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.Command.HTTPClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	defs, _, err := client.PreparedQuery().List(&api.QueryOptions{
+		AllowStale: c.Command.HTTPStale(),
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error listing prepared queries: %s", err))
+		return 1
+	}
+	sort.Sort(byQueryName(defs))
+
+	if *format == "json" {
+		out, err := queryJSON(defs)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error formatting prepared queries: %s", err))
+			return 1
+		}
+		c.UI.Output(out)
+		return 0
+	}
+
+	result := []string{"ID|Name|Service|Template"}
+	for _, def := range defs {
+		result = append(result, fmt.Sprintf("%s|%s|%s|%s",
+			def.ID, def.Name, def.Service.Service, def.Template.Type))
+	}
+	c.UI.Output(columnize.SimpleFormat(result))
+	return 0
+}
+
+func (c *QueryListCommand) Synopsis() string {
+	return "Lists prepared queries"
+}
+
+// byQueryName sorts prepared queries by name, and then by ID.
+type byQueryName []*api.PreparedQueryDefinition
+
+func (b byQueryName) Len() int      { return len(b) }
+func (b byQueryName) Swap(i, j int) { b[i], b[j] = b[j], b[i] }
+func (b byQueryName) Less(i, j int) bool {
+	if b[i].Name != b[j].Name {
+		return b[i].Name < b[j].Name
+	}
+	return b[i].ID < b[j].ID
+}
@@ -0,0 +1,111 @@
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/mitchellh/cli"
+)
+
+func testQueryListCommand(t *testing.T) (*cli.MockUi, *QueryListCommand) {
+	ui := new(cli.MockUi)
+	return ui, &QueryListCommand{
+		Command: base.Command{
+			UI:    ui,
+			Flags: base.FlagSetHTTP,
+		},
+	}
+}
+
+func TestQueryListCommand_implements(t *testing.T) {
+	var _ cli.Command = &QueryListCommand{}
+}
+
+func TestQueryListCommand_noTabs(t *testing.T) {
+	assertNoTabs(t, new(QueryListCommand))
+}
+
+func TestQueryListCommand_Validation(t *testing.T) {
+	ui, c := testQueryListCommand(t)
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"extra args": {
+			[]string{"foo"},
+			"Too many arguments",
+		},
+		"bad format": {
+			[]string{"-format=xml"},
+			"Invalid format",
+		},
+	}
+
+	for name, tc := range cases {
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestQueryListCommand_Run(t *testing.T) {
+	srv, client := testAgentWithAPIClient(t)
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	redis := testQueryCreate(t, client, "redis", "redis")
+	memcached := testQueryCreate(t, client, "memcached", "memcached")
+
+	// The table should be sorted by name.
+	ui, c := testQueryListCommand(t)
+	args := []string{
+		"-http-addr=" + srv.httpAddr,
+	}
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	lines := strings.Split(strings.TrimSpace(ui.OutputWriter.String()), "\n")
+	if len(lines) != 3 ||
+		!strings.HasPrefix(lines[0], "ID") ||
+		!strings.HasPrefix(lines[1], memcached) ||
+		!strings.HasPrefix(lines[2], redis) {
+		t.Fatalf("bad: %#v", lines)
+	}
+
+	// Try the JSON format.
+	ui, c = testQueryListCommand(t)
+	args = []string{
+		"-http-addr=" + srv.httpAddr,
+		"-format=json",
+	}
+	code = c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	var defs []*api.PreparedQueryDefinition
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &defs); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(defs) != 2 || defs[0].ID != memcached || defs[1].ID != redis {
+		t.Fatalf("bad: %#v", defs)
+	}
+}
@@ -0,0 +1,77 @@
+package command
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+)
+
+// QueryReadCommand is a Command implementation that is used to show the
+// definition of a prepared query.
+type QueryReadCommand struct {
+	base.Command
+}
+
+func (c *QueryReadCommand) Help() string {
+	helpText := `
+Usage: consul query read [options] ID_OR_NAME
+
+  Shows the definition of the prepared query with the given ID or name as
+  JSON, which can be edited and passed to "consul query update".
+
+      $ consul query read redis
+
+  For a full list of options and examples, please see the Consul documentation.
+
+` + c.Command.Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *QueryReadCommand) Run(args []string) int {
+	f := c.Command.NewFlagSet(c)
+	if err := c.Command.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check for arg validation
+	args = f.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Missing ID_OR_NAME argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.Command.HTTPClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	def, err := lookupQuery(client, args[0], &api.QueryOptions{
+		AllowStale: c.Command.HTTPStale(),
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error looking up prepared query: %s", err))
+		return 1
+	}
+
+	out, err := queryJSON(def)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error formatting prepared query: %s", err))
+		return 1
+	}
+	c.UI.Output(out)
+	return 0
+}
+
+func (c *QueryReadCommand) Synopsis() string {
+	return "Shows a prepared query definition"
+}
@@ -0,0 +1,94 @@
+package command
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/mitchellh/cli"
+)
+
+func testQueryReadCommand(t *testing.T) (*cli.MockUi, *QueryReadCommand) {
+	ui := new(cli.MockUi)
+	return ui, &QueryReadCommand{
+		Command: base.Command{
+			UI:    ui,
+			Flags: base.FlagSetHTTP,
+		},
+	}
+}
+
+func TestQueryReadCommand_implements(t *testing.T) {
+	var _ cli.Command = &QueryReadCommand{}
+}
+
+func TestQueryReadCommand_noTabs(t *testing.T) {
+	assertNoTabs(t, new(QueryReadCommand))
+}
+
+func TestQueryReadCommand_Validation(t *testing.T) {
+	ui, c := testQueryReadCommand(t)
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no query": {
+			[]string{},
+			"Missing ID_OR_NAME argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar"},
+			"Too many arguments",
+		},
+	}
+
+	for name, tc := range cases {
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestQueryReadCommand_Run(t *testing.T) {
+	srv, client := testAgentWithAPIClient(t)
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	id := testQueryCreate(t, client, "redis", "redis")
+
+	// The output should round trip into a definition we can feed back
+	// into an update.
+	ui, c := testQueryReadCommand(t)
+	args := []string{
+		"-http-addr=" + srv.httpAddr,
+		"redis",
+	}
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	var def api.PreparedQueryDefinition
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &def); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if def.ID != id || def.Name != "redis" || def.Service.Service != "redis" {
+		t.Fatalf("bad: %#v", def)
+	}
+}
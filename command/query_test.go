@@ -0,0 +1,88 @@
+package command
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/mitchellh/cli"
+)
+
+func TestQueryCommand_implements(t *testing.T) {
+	var _ cli.Command = &QueryCommand{}
+}
+
+func TestQueryCommand_noTabs(t *testing.T) {
+	assertNoTabs(t, new(QueryCommand))
+}
+
+func TestQueryCommand_parseQueryDefinition(t *testing.T) {
+	// JSON from stdin.
+	def, err := parseQueryDefinition("-", strings.NewReader(`{
+		"Name": "redis",
+		"Service": {"Service": "redis", "OnlyPassing": true}
+	}`))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if def.Name != "redis" || def.Service.Service != "redis" || !def.Service.OnlyPassing {
+		t.Fatalf("bad: %#v", def)
+	}
+
+	// HCL from a file.
+	f, err := ioutil.TempFile("", "consul")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(`
+Name = "geo-db"
+Template {
+  Type = "name_prefix_match"
+}
+Service {
+  Service = "${name.suffix}"
+  Failover {
+    NearestN = 2
+  }
+}
+`); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	f.Close()
+	def, err = parseQueryDefinition(f.Name(), nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if def.Name != "geo-db" || def.Template.Type != "name_prefix_match" ||
+		def.Service.Service != "${name.suffix}" || def.Service.Failover.NearestN != 2 {
+		t.Fatalf("bad: %#v", def)
+	}
+
+	// Bad input.
+	if _, err := parseQueryDefinition("-", strings.NewReader(`Name = [`)); err == nil ||
+		!strings.Contains(err.Error(), "Failed to parse") {
+		t.Fatalf("bad: %v", err)
+	}
+	if _, err := parseQueryDefinition("/nope/nope", nil); err == nil ||
+		!strings.Contains(err.Error(), "Failed to read file") {
+		t.Fatalf("bad: %v", err)
+	}
+}
+
+// testQueryCreate creates a prepared query for the given service and returns
+// its ID.
+func testQueryCreate(t *testing.T, client *api.Client, name, service string) string {
+	id, _, err := client.PreparedQuery().Create(&api.PreparedQueryDefinition{
+		Name: name,
+		Service: api.ServiceQuery{
+			Service: service,
+		},
+	}, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return id
+}
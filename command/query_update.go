@@ -0,0 +1,100 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+)
+
+// QueryUpdateCommand is a Command implementation that is used to update an
+// existing prepared query from a definition file.
+type QueryUpdateCommand struct {
+	base.Command
+
+	// testStdin is the input for testing.
+	testStdin io.Reader
+}
+
+func (c *QueryUpdateCommand) Help() string {
+	helpText := `
+Usage: consul query update [options] FILE
+
+  Replaces an existing prepared query with the JSON or HCL definition in FILE.
+  The query to update is given by the ID in the definition, or by the -id
+  option, which also accepts the name of the query. Use "-" as the file name
+  to read the definition from stdin.
+
+      $ consul query update -id=redis redis.hcl
+
+  For a full list of options and examples, please see the Consul documentation.
+
+` + c.Command.Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *QueryUpdateCommand) Run(args []string) int {
+	f := c.Command.NewFlagSet(c)
+	id := f.String("id", "",
+		"ID or name of the query to update. This overrides any ID in the "+
+			"query definition.")
+	if err := c.Command.Parse(args); err != nil {
+		return 1
+	}
+
+	// Check for arg validation
+	args = f.Args()
+	switch len(args) {
+	case 0:
+		c.UI.Error("Missing FILE argument")
+		return 1
+	case 1:
+	default:
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 1, got %d)", len(args)))
+		return 1
+	}
+
+	def, err := parseQueryDefinition(args[0], c.testStdin)
+	if err != nil {
+		c.UI.Error(err.Error())
+		return 1
+	}
+	if *id == "" && def.ID == "" {
+		c.UI.Error("Must give the query's ID in the definition or with -id")
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.Command.HTTPClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	// Resolve a name given via -id to the query's actual ID.
+	if *id != "" {
+		existing, err := lookupQuery(client, *id, &api.QueryOptions{
+			AllowStale: c.Command.HTTPStale(),
+		})
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error looking up prepared query: %s", err))
+			return 1
+		}
+		def.ID = existing.ID
+	}
+
+	if _, err := client.PreparedQuery().Update(def, nil); err != nil {
+		c.UI.Error(fmt.Sprintf("Error updating prepared query: %s", err))
+		return 1
+	}
+
+	c.UI.Info(fmt.Sprintf("Updated prepared query %s", def.ID))
+	return 0
+}
+
+func (c *QueryUpdateCommand) Synopsis() string {
+	return "Updates a prepared query"
+}
@@ -0,0 +1,141 @@
+package command
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/command/base"
+	"github.com/mitchellh/cli"
+)
+
+func testQueryUpdateCommand(t *testing.T) (*cli.MockUi, *QueryUpdateCommand) {
+	ui := new(cli.MockUi)
+	return ui, &QueryUpdateCommand{
+		Command: base.Command{
+			UI:    ui,
+			Flags: base.FlagSetHTTP,
+		},
+	}
+}
+
+func TestQueryUpdateCommand_implements(t *testing.T) {
+	var _ cli.Command = &QueryUpdateCommand{}
+}
+
+func TestQueryUpdateCommand_noTabs(t *testing.T) {
+	assertNoTabs(t, new(QueryUpdateCommand))
+}
+
+func TestQueryUpdateCommand_Validation(t *testing.T) {
+	ui, c := testQueryUpdateCommand(t)
+
+	cases := map[string]struct {
+		args   []string
+		stdin  string
+		output string
+	}{
+		"no file": {
+			[]string{},
+			"",
+			"Missing FILE argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar"},
+			"",
+			"Too many arguments",
+		},
+		"no ID": {
+			[]string{"-"},
+			`{"Service": {"Service": "redis"}}`,
+			"Must give the query's ID",
+		},
+	}
+
+	for name, tc := range cases {
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		c.testStdin = strings.NewReader(tc.stdin)
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestQueryUpdateCommand_Run(t *testing.T) {
+	srv, client := testAgentWithAPIClient(t)
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	id := testQueryCreate(t, client, "redis", "redis")
+
+	// Update it by name, using -id.
+	ui, c := testQueryUpdateCommand(t)
+	c.testStdin = strings.NewReader(`{"Name": "redis", "Service": {"Service": "memcached"}}`)
+	args := []string{
+		"-http-addr=" + srv.httpAddr,
+		"-id=redis",
+		"-",
+	}
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	if !strings.Contains(ui.OutputWriter.String(), id) {
+		t.Fatalf("bad: %#v", ui.OutputWriter.String())
+	}
+
+	defs, _, err := client.PreparedQuery().Get(id, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(defs) != 1 || defs[0].Service.Service != "memcached" {
+		t.Fatalf("bad: %#v", defs)
+	}
+
+	// Update it using the ID in the definition.
+	ui, c = testQueryUpdateCommand(t)
+	c.testStdin = strings.NewReader(`{"ID": "` + id + `", "Name": "redis", "Service": {"Service": "redis"}}`)
+	args = []string{
+		"-http-addr=" + srv.httpAddr,
+		"-",
+	}
+	code = c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	defs, _, err = client.PreparedQuery().Get(id, nil)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(defs) != 1 || defs[0].Service.Service != "redis" {
+		t.Fatalf("bad: %#v", defs)
+	}
+
+	// Asking for a query that doesn't exist should fail.
+	ui, c = testQueryUpdateCommand(t)
+	c.testStdin = strings.NewReader(`{"Service": {"Service": "redis"}}`)
+	args = []string{
+		"-http-addr=" + srv.httpAddr,
+		"-id=nope",
+		"-",
+	}
+	if code := c.Run(args); code == 0 {
+		t.Fatalf("bad: %d", code)
+	}
+	if !strings.Contains(ui.ErrorWriter.String(), "No prepared query") {
+		t.Fatalf("bad: %#v", ui.ErrorWriter.String())
+	}
+}
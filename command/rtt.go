@@ -2,10 +2,15 @@ package command
 
 import (
 	"fmt"
+	"math"
+	"sort"
 	"strings"
+	"time"
 
+	consulapi "github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/base"
 	"github.com/hashicorp/serf/coordinate"
+	"github.com/ryanuber/columnize"
 )
 
 // RTTCommand is a Command implementation that allows users to query the
@@ -17,6 +22,7 @@ type RTTCommand struct {
 func (c *RTTCommand) Help() string {
 	helpText := `
 Usage: consul rtt [options] node1 [node2]
+       consul rtt [options] -service=<name> [node]
 
   Estimates the round trip time between two nodes using Consul's network
   coordinate model of the cluster.
@@ -34,6 +40,11 @@ Usage: consul rtt [options] node1 [node2]
   because they are maintained by independent Serf gossip areas, so they are
   not compatible.
 
+  If the -service option is given, all instances of that service in the local
+  datacenter are ranked by their estimated round trip time from the given node,
+  which defaults to the agent's node. Instances on nodes without a coordinate
+  are listed last.
+
 ` + c.Command.Help()
 
 	return strings.TrimSpace(helpText)
@@ -41,17 +52,42 @@ Usage: consul rtt [options] node1 [node2]
 
 func (c *RTTCommand) Run(args []string) int {
 	var wan bool
+	var service, tag string
 
 	f := c.Command.NewFlagSet(c)
 
 	f.BoolVar(&wan, "wan", false, "Use WAN coordinates instead of LAN coordinates.")
+	f.StringVar(&service, "service", "",
+		"Rank the instances of this service by their estimated round trip time "+
+			"from the node, instead of measuring between two nodes.")
+	f.StringVar(&tag, "tag", "",
+		"Only rank the instances of the service with this tag. Must be used "+
+			"with -service.")
 
 	if err := c.Command.Parse(args); err != nil {
 		return 1
 	}
 
-	// They must provide at least one node.
 	nodes := f.Args()
+	if tag != "" && service == "" {
+		c.UI.Error("The -tag option must be used with -service")
+		return 1
+	}
+	if service != "" {
+		if wan {
+			c.UI.Error("The -service option can't be used with -wan")
+			return 1
+		}
+		if len(nodes) > 1 {
+			c.UI.Error("At most one node name can be given with -service")
+			c.UI.Error("")
+			c.UI.Error(c.Help())
+			return 1
+		}
+		return c.rankService(service, tag, nodes)
+	}
+
+	// They must provide at least one node.
 	if len(nodes) < 1 || len(nodes) > 2 {
 		c.UI.Error("One or two node names must be specified")
 		c.UI.Error("")
@@ -179,6 +215,101 @@ SHOW_RTT:
 	return 0
 }
 
+// rankService prints the instances of a service sorted by their estimated
+// round trip time from the given node, or the agent's node if none is given.
+func (c *RTTCommand) rankService(service, tag string, nodes []string) int {
+	client, err := c.Command.HTTPClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	// Default the source to the agent if none was given.
+	var source string
+	if len(nodes) > 0 {
+		source = nodes[0]
+	} else {
+		source, err = client.Agent().NodeName()
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Unable to look up agent info: %s", err))
+			return 1
+		}
+	}
+
+	entries, _, err := client.Health().Service(service, tag, false, nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error getting service instances: %s", err))
+		return 1
+	}
+	if len(entries) == 0 {
+		c.UI.Error(fmt.Sprintf("No instances of service %q found", service))
+		return 1
+	}
+
+	// Pull all the LAN coordinates.
+	coords, _, err := client.Coordinate().Nodes(nil)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error getting coordinates: %s", err))
+		return 1
+	}
+	byNode := make(map[string]*coordinate.Coordinate)
+	for _, entry := range coords {
+		byNode[entry.Node] = entry.Coord
+	}
+	sourceCoord, ok := byNode[source]
+	if !ok {
+		c.UI.Error(fmt.Sprintf("Could not find a coordinate for node %q", source))
+		return 1
+	}
+
+	// Rank the instances, with unknown distances at the end.
+	ranked := make([]*rankedInstance, 0, len(entries))
+	for _, entry := range entries {
+		inst := &rankedInstance{entry: entry, dist: time.Duration(math.MaxInt64)}
+		if coord, ok := byNode[entry.Node.Node]; ok {
+			inst.dist = sourceCoord.DistanceTo(coord)
+			inst.known = true
+		}
+		ranked = append(ranked, inst)
+	}
+	sort.Stable(rankedInstances(ranked))
+
+	result := []string{"Node|Address|Service ID|Health|RTT"}
+	for _, inst := range ranked {
+		addr := inst.entry.Service.Address
+		if addr == "" {
+			addr = inst.entry.Node.Address
+		}
+		if inst.entry.Service.Port != 0 {
+			addr = fmt.Sprintf("%s:%d", addr, inst.entry.Service.Port)
+		}
+		rtt := "unknown"
+		if inst.known {
+			rtt = fmt.Sprintf("%.3f ms", inst.dist.Seconds()*1000.0)
+		}
+		result = append(result, fmt.Sprintf("%s|%s|%s|%s|%s",
+			inst.entry.Node.Node, addr, inst.entry.Service.ID,
+			inst.entry.Checks.AggregatedStatus(), rtt))
+	}
+	c.UI.Output(columnize.SimpleFormat(result))
+	return 0
+}
+
+// rankedInstance is a service instance along with its estimated distance
+// from the source node.
+type rankedInstance struct {
+	entry *consulapi.ServiceEntry
+	dist  time.Duration
+	known bool
+}
+
+// rankedInstances implements sort.Interface, sorting by distance.
+type rankedInstances []*rankedInstance
+
+func (r rankedInstances) Len() int           { return len(r) }
+func (r rankedInstances) Swap(i, j int)      { r[i], r[j] = r[j], r[i] }
+func (r rankedInstances) Less(i, j int) bool { return r[i].dist < r[j].dist }
+
 func (c *RTTCommand) Synopsis() string {
 	return "Estimates network round trip time between nodes"
 }
@@ -50,6 +50,18 @@ func TestRTTCommand_Run_BadArgs(t *testing.T) {
 	if code := c.Run([]string{"-wan", "node1", "node2.dc1"}); code != 1 {
 		t.Fatalf("expected return code 1, got %d", code)
 	}
+
+	if code := c.Run([]string{"-service", "web", "-wan"}); code != 1 {
+		t.Fatalf("expected return code 1, got %d", code)
+	}
+
+	if code := c.Run([]string{"-service", "web", "node1", "node2"}); code != 1 {
+		t.Fatalf("expected return code 1, got %d", code)
+	}
+
+	if code := c.Run([]string{"-tag", "v1", "node1"}); code != 1 {
+		t.Fatalf("expected return code 1, got %d", code)
+	}
 }
 
 func TestRTTCommand_Run_LAN(t *testing.T) {
@@ -154,6 +166,102 @@ func TestRTTCommand_Run_LAN(t *testing.T) {
 	}
 }
 
+func TestRTTCommand_Run_Service(t *testing.T) {
+	updatePeriod := 10 * time.Millisecond
+	a := testAgentWithConfig(t, func(c *agent.Config) {
+		c.ConsulConfig.CoordinateUpdatePeriod = updatePeriod
+	})
+	defer a.Shutdown()
+	waitForLeader(t, a.httpAddr)
+
+	// Register the service on a near node, a far node, and a node with no
+	// coordinate.
+	register := func(node, addr string) {
+		req := structs.RegisterRequest{
+			Datacenter: a.config.Datacenter,
+			Node:       node,
+			Address:    addr,
+			Service: &structs.NodeService{
+				ID:      "web",
+				Service: "web",
+				Port:    8080,
+			},
+		}
+		var reply struct{}
+		if err := a.agent.RPC("Catalog.Register", &req, &reply); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+	register("near", "127.0.0.2")
+	register("far", "127.0.0.3")
+	register("unknown", "127.0.0.4")
+
+	c1 := coordinate.NewCoordinate(coordinate.DefaultConfig())
+	c2 := c1.Clone()
+	c2.Vec[0] = 0.001
+	c3 := c1.Clone()
+	c3.Vec[0] = 0.123
+	coords := map[string]*coordinate.Coordinate{
+		a.config.NodeName: c1,
+		"near":            c2,
+		"far":             c3,
+	}
+	for node, coord := range coords {
+		req := structs.CoordinateUpdateRequest{
+			Datacenter: a.config.Datacenter,
+			Node:       node,
+			Coord:      coord,
+		}
+		var reply struct{}
+		if err := a.agent.RPC("Coordinate.Update", &req, &reply); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	retry.Run(t, func(r *retry.R) {
+		ui, c := testRTTCommand(t)
+		args := []string{
+			"-http-addr=" + a.httpAddr,
+			"-service=web",
+		}
+		code := c.Run(args)
+		if code != 0 {
+			r.Fatalf("bad: %d: %#v", code, ui.ErrorWriter.String())
+		}
+
+		lines := strings.Split(strings.TrimSpace(ui.OutputWriter.String()), "\n")
+		if len(lines) != 4 {
+			r.Fatalf("bad: %#v", lines)
+		}
+		expected := []struct {
+			node string
+			rtt  string
+		}{
+			{"near", fmt.Sprintf("%.3f ms", c1.DistanceTo(c2).Seconds()*1000.0)},
+			{"far", fmt.Sprintf("%.3f ms", c1.DistanceTo(c3).Seconds()*1000.0)},
+			{"unknown", "unknown"},
+		}
+		for i, exp := range expected {
+			line := lines[i+1]
+			if !strings.HasPrefix(line, exp.node+" ") || !strings.HasSuffix(line, exp.rtt) {
+				r.Fatalf("bad line %d: %q", i+1, line)
+			}
+		}
+	})
+
+	// Try an unknown service.
+	{
+		ui, c := testRTTCommand(t)
+		args := []string{
+			"-http-addr=" + a.httpAddr,
+			"-service=nope",
+		}
+		if code := c.Run(args); code != 1 {
+			t.Fatalf("bad: %d: %#v", code, ui.ErrorWriter.String())
+		}
+	}
+}
+
 func TestRTTCommand_Run_WAN(t *testing.T) {
 	a := testAgent(t)
 	defer a.Shutdown()
@@ -0,0 +1,396 @@
+package command
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/hashicorp/consul/snapshot"
+)
+
+const (
+	// snapshotAgentPrefix and snapshotAgentSuffix bracket the ID in the
+	// file name of every snapshot saved by the agent. Only files matching
+	// this pattern are considered when applying the retain policy.
+	snapshotAgentPrefix = "consul-"
+	snapshotAgentSuffix = ".snap"
+
+	// snapshotAgentRetryTime is how long we wait before trying to obtain
+	// leadership again after a failed attempt.
+	snapshotAgentRetryTime = 5 * time.Second
+)
+
+// SnapshotAgentCommand is a Command implementation that runs a long-running
+// process which periodically saves snapshots of the state of the Consul
+// servers to local disk.
+type SnapshotAgentCommand struct {
+	base.Command
+
+	ShutdownCh <-chan struct{}
+
+	interval    time.Duration
+	retain      int
+	lockKey     string
+	maxFailures int
+	localPath   string
+	statsite    string
+	statsd      string
+	key         []byte
+}
+
+func (c *SnapshotAgentCommand) Help() string {
+	helpText := `
+Usage: consul snapshot agent [options]
+
+  Starts a process that takes snapshots of the state of the Consul servers at
+  a regular interval and saves them to a local directory, removing the oldest
+  snapshots so that at most a configured number are retained. Every snapshot
+  is verified after it is written.
+
+  The agent performs a leader election using a lock in the KV store, so
+  multiple instances may be run for highly available operation; only the
+  instance holding the lock takes snapshots.
+
+  If ACLs are enabled, a management token must be supplied in order to perform
+  snapshot operations.
+
+  To take a snapshot every hour and keep the last 30 in /opt/consul-snapshots:
+
+    $ consul snapshot agent -local-path=/opt/consul-snapshots
+
+  To take a single snapshot and exit, without leader election:
+
+    $ consul snapshot agent -interval=0
+
+  For a full list of options and examples, please see the Consul documentation.
+
+` + c.Command.Help()
+
+	return strings.TrimSpace(helpText)
+}
+
+func (c *SnapshotAgentCommand) Run(args []string) int {
+	var keyFile string
+
+	flagSet := c.Command.NewFlagSet(c)
+	snapshotKeyFlag(flagSet, &keyFile)
+	flagSet.DurationVar(&c.interval, "interval", time.Hour,
+		"Interval at which to perform snapshots, specified as a duration like "+
+			"\"30m\" or \"1h\". If 0 is provided, the agent will take a single "+
+			"snapshot and then exit. The default value is 1h.")
+	flagSet.IntVar(&c.retain, "retain", 30,
+		"Number of snapshots to retain. After each snapshot is taken, the oldest "+
+			"snapshots are deleted so that at most this many remain. If 0, "+
+			"snapshots are never deleted. The default value is 30.")
+	flagSet.StringVar(&c.lockKey, "lock-key", "consul-snapshot/lock",
+		"Key in the KV store used to coordinate between instances of the "+
+			"snapshot agent so that only one takes snapshots at a time.")
+	flagSet.IntVar(&c.maxFailures, "max-failures", 3,
+		"Number of consecutive snapshot failures after which the agent gives up "+
+			"leadership, giving another instance a chance to take over. The "+
+			"default value is 3.")
+	flagSet.StringVar(&c.localPath, "local-path", ".",
+		"Directory in which to store snapshots. The default value is the "+
+			"current working directory.")
+	flagSet.StringVar(&c.statsite, "statsite-addr", "",
+		"Address of a statsite instance to send telemetry to.")
+	flagSet.StringVar(&c.statsd, "statsd-addr", "",
+		"Address of a statsd instance to send telemetry to.")
+
+	if err := c.Command.Parse(args); err != nil {
+		return 1
+	}
+
+	if len(flagSet.Args()) != 0 {
+		c.UI.Error(fmt.Sprintf("Too many arguments (expected 0, got %d)", len(flagSet.Args())))
+		return 1
+	}
+	if c.interval < 0 {
+		c.UI.Error("Interval must not be negative")
+		return 1
+	}
+	if c.retain < 0 {
+		c.UI.Error("Retain must not be negative")
+		return 1
+	}
+	if c.maxFailures < 1 {
+		c.UI.Error("Max failures must be positive")
+		return 1
+	}
+	if c.lockKey == "" {
+		c.UI.Error("Lock key must not be empty")
+		return 1
+	}
+	if fi, err := os.Stat(c.localPath); err != nil {
+		c.UI.Error(fmt.Sprintf("Error checking local path: %s", err))
+		return 1
+	} else if !fi.IsDir() {
+		c.UI.Error(fmt.Sprintf("Local path %q is not a directory", c.localPath))
+		return 1
+	}
+
+	key, err := snapshotKey(keyFile)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading snapshot encryption key: %s", err))
+		return 1
+	}
+	c.key = key
+
+	if err := c.setupTelemetry(); err != nil {
+		c.UI.Error(fmt.Sprintf("Error setting up telemetry: %s", err))
+		return 1
+	}
+
+	// Create and test the HTTP client
+	client, err := c.Command.HTTPClient()
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error connecting to Consul agent: %s", err))
+		return 1
+	}
+
+	c.UI.Info("Snapshot agent running")
+
+	// A zero interval is a one-shot run from a batch job, so there's no
+	// need for leader election.
+	if c.interval == 0 {
+		if err := c.snapshot(client); err != nil {
+			c.UI.Error(fmt.Sprintf("Error taking snapshot: %s", err))
+			return 1
+		}
+		return 0
+	}
+
+	lock, err := client.LockOpts(&api.LockOptions{
+		Key:              c.lockKey,
+		SessionName:      "Consul Snapshot Agent",
+		MonitorRetries:   defaultMonitorRetry,
+		MonitorRetryTime: defaultMonitorRetryTime,
+	})
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error setting up lock: %s", err))
+		return 1
+	}
+
+	for {
+		c.UI.Info("Waiting to obtain leadership...")
+		lockCh, err := lock.Lock(c.ShutdownCh)
+		if lockCh == nil {
+			if err == nil {
+				// A nil error means we were asked to shut down.
+				return 0
+			}
+
+			c.UI.Error(fmt.Sprintf("Error obtaining leadership: %s", err))
+			select {
+			case <-time.After(snapshotAgentRetryTime):
+				continue
+			case <-c.ShutdownCh:
+				return 0
+			}
+		}
+		c.UI.Info("Obtained leadership")
+
+		shutdown := c.lead(client, lockCh)
+		if err := lock.Unlock(); err != nil && err != api.ErrLockNotHeld {
+			c.UI.Error(fmt.Sprintf("Error releasing leadership: %s", err))
+		}
+		if shutdown {
+			return 0
+		}
+		c.UI.Info("Lost leadership")
+	}
+}
+
+// lead takes snapshots on the configured interval for as long as we hold the
+// lock. It returns true if we were asked to shut down, and false if we lost
+// leadership or gave it up after too many failures.
+func (c *SnapshotAgentCommand) lead(client *api.Client, lockCh <-chan struct{}) bool {
+	failures := 0
+	for {
+		if err := c.snapshot(client); err != nil {
+			failures++
+			c.UI.Error(fmt.Sprintf("Error taking snapshot (%d/%d failures): %s",
+				failures, c.maxFailures, err))
+			if failures >= c.maxFailures {
+				c.UI.Error("Too many snapshot failures, giving up leadership")
+				return false
+			}
+		} else {
+			failures = 0
+		}
+
+		select {
+		case <-time.After(c.interval):
+		case <-lockCh:
+			return false
+		case <-c.ShutdownCh:
+			return true
+		}
+	}
+}
+
+// snapshot saves a single verified snapshot into the local path and then
+// applies the retain policy.
+func (c *SnapshotAgentCommand) snapshot(client *api.Client) error {
+	start := time.Now()
+	id := start.UnixNano()
+
+	snap, _, err := client.Snapshot().Save(&api.QueryOptions{
+		AllowStale: c.Command.HTTPStale(),
+	})
+	if err != nil {
+		metrics.IncrCounter([]string{"consul", "snapshot", "agent", "failure"}, 1)
+		return fmt.Errorf("failed to save snapshot: %v", err)
+	}
+	defer snap.Close()
+
+	// Write to a temporary file first so a partial snapshot never shows up
+	// with a name that the retain policy or an operator would pick up.
+	final := filepath.Join(c.localPath, fmt.Sprintf("%s%d%s", snapshotAgentPrefix, id, snapshotAgentSuffix))
+	tmp := final + ".tmp"
+	if err := writeVerifiedSnapshot(tmp, snap, c.key); err != nil {
+		os.Remove(tmp)
+		metrics.IncrCounter([]string{"consul", "snapshot", "agent", "failure"}, 1)
+		return err
+	}
+	if err := os.Rename(tmp, final); err != nil {
+		os.Remove(tmp)
+		metrics.IncrCounter([]string{"consul", "snapshot", "agent", "failure"}, 1)
+		return fmt.Errorf("failed to rename snapshot file: %v", err)
+	}
+
+	metrics.MeasureSince([]string{"consul", "snapshot", "agent", "save"}, start)
+	metrics.SetGauge([]string{"consul", "snapshot", "agent", "last_success"}, float32(start.Unix()))
+	c.UI.Info(fmt.Sprintf("Saved snapshot %d", id))
+
+	if err := c.prune(); err != nil {
+		c.UI.Error(fmt.Sprintf("Error removing old snapshots: %s", err))
+	}
+	return nil
+}
+
+// prune deletes the oldest snapshots in the local path so that at most the
+// configured number are retained.
+func (c *SnapshotAgentCommand) prune() error {
+	if c.retain == 0 {
+		return nil
+	}
+
+	snaps, err := listAgentSnapshots(c.localPath)
+	if err != nil {
+		return err
+	}
+	if len(snaps) <= c.retain {
+		return nil
+	}
+
+	for _, name := range snaps[:len(snaps)-c.retain] {
+		if err := os.Remove(filepath.Join(c.localPath, name)); err != nil {
+			return err
+		}
+		c.UI.Info(fmt.Sprintf("Removed old snapshot %q", name))
+	}
+	return nil
+}
+
+// setupTelemetry configures the global metrics sink so the agent can report
+// on the snapshots it takes. The in-memory sink can be dumped with a signal,
+// same as the Consul agent.
+func (c *SnapshotAgentCommand) setupTelemetry() error {
+	inm := metrics.NewInmemSink(10*time.Second, time.Minute)
+	metrics.DefaultInmemSignal(inm)
+	conf := metrics.DefaultConfig("consul")
+
+	fanout := metrics.FanoutSink{inm}
+	if c.statsite != "" {
+		sink, err := metrics.NewStatsiteSink(c.statsite)
+		if err != nil {
+			return fmt.Errorf("failed to start statsite sink: %v", err)
+		}
+		fanout = append(fanout, sink)
+	}
+	if c.statsd != "" {
+		sink, err := metrics.NewStatsdSink(c.statsd)
+		if err != nil {
+			return fmt.Errorf("failed to start statsd sink: %v", err)
+		}
+		fanout = append(fanout, sink)
+	}
+
+	_, err := metrics.NewGlobal(conf, fanout)
+	return err
+}
+
+// writeVerifiedSnapshot copies the snapshot stream to the given file and then
+// reads it back to verify it. If a key is given, the snapshot is encrypted
+// with it unless the servers already encrypted it, in which case it's kept
+// as-is.
+func writeVerifiedSnapshot(file string, snap io.Reader, key []byte) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to create snapshot file: %v", err)
+	}
+	if key != nil {
+		err = snapshot.Encrypt(f, snap, key)
+	} else {
+		_, err = io.Copy(f, snap)
+	}
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write snapshot file: %v", err)
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to sync snapshot file: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to close snapshot file after writing: %v", err)
+	}
+
+	f, err = os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open snapshot file for verify: %v", err)
+	}
+	defer f.Close()
+	if err := verifySnapshot(f, key); err != nil {
+		return fmt.Errorf("failed to verify snapshot file: %v", err)
+	}
+	return nil
+}
+
+// listAgentSnapshots returns the names of the snapshot files saved by the
+// agent in the given directory, oldest first.
+func listAgentSnapshots(dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var snaps []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() ||
+			!strings.HasPrefix(name, snapshotAgentPrefix) ||
+			!strings.HasSuffix(name, snapshotAgentSuffix) {
+			continue
+		}
+		snaps = append(snaps, name)
+	}
+
+	// IDs are nanosecond timestamps which all have the same number of
+	// digits, so lexical order is also chronological.
+	sort.Strings(snaps)
+	return snaps, nil
+}
+
+func (c *SnapshotAgentCommand) Synopsis() string {
+	return "Periodically saves snapshots of Consul server state"
+}
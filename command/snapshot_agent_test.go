@@ -0,0 +1,194 @@
+package command
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/command/agent"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/hashicorp/consul/snapshot"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/mitchellh/cli"
+)
+
+func testSnapshotAgentCommand(t *testing.T) (*cli.MockUi, *SnapshotAgentCommand) {
+	ui := new(cli.MockUi)
+	return ui, &SnapshotAgentCommand{
+		Command: base.Command{
+			UI:    ui,
+			Flags: base.FlagSetHTTP,
+		},
+	}
+}
+
+func TestSnapshotAgentCommand_implements(t *testing.T) {
+	var _ cli.Command = &SnapshotAgentCommand{}
+}
+
+func TestSnapshotAgentCommand_noTabs(t *testing.T) {
+	assertNoTabs(t, new(SnapshotAgentCommand))
+}
+
+func TestSnapshotAgentCommand_Validation(t *testing.T) {
+	ui, c := testSnapshotAgentCommand(t)
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"extra args": {
+			[]string{"foo"},
+			"Too many arguments",
+		},
+		"negative interval": {
+			[]string{"-interval=-1s"},
+			"Interval must not be negative",
+		},
+		"negative retain": {
+			[]string{"-retain=-1"},
+			"Retain must not be negative",
+		},
+		"zero max failures": {
+			[]string{"-max-failures=0"},
+			"Max failures must be positive",
+		},
+		"missing local path": {
+			[]string{"-local-path=/does/not/exist"},
+			"Error checking local path",
+		},
+	}
+
+	for name, tc := range cases {
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestSnapshotAgentCommand_Run(t *testing.T) {
+	srv, client := testAgentWithAPIClient(t)
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	dir := testutil.TempDir(t, "snapshot")
+	defer os.RemoveAll(dir)
+
+	// Drop in some old snapshots, plus an unrelated file which should be
+	// left alone by the retain policy.
+	for i := 1; i <= 3; i++ {
+		name := fmt.Sprintf("consul-%019d.snap", i)
+		if err := ioutil.WriteFile(filepath.Join(dir, name), nil, 0600); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "other.snap"), nil, 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ui, c := testSnapshotAgentCommand(t)
+	args := []string{
+		"-http-addr=" + srv.httpAddr,
+		"-interval=0",
+		"-retain=2",
+		"-local-path=" + dir,
+	}
+
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	snaps, err := listAgentSnapshots(dir)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(snaps) != 2 || snaps[0] != "consul-0000000000000000003.snap" {
+		t.Fatalf("bad: %v", snaps)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.snap")); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Make sure the new snapshot is usable.
+	f, err := os.Open(filepath.Join(dir, snaps[1]))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	if err := client.Snapshot().Restore(nil, f); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestSnapshotAgentCommand_ServerEncrypted(t *testing.T) {
+	dir := testutil.TempDir(t, "snapshot")
+	defer os.RemoveAll(dir)
+
+	key := make([]byte, snapshot.EncryptKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keyFile := filepath.Join(dir, "snapshot.key")
+	if err := ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	srv := testAgentWithConfig(t, func(c *agent.Config) {
+		c.SnapshotEncryptionKeyFile = keyFile
+	})
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	// The agent doesn't have the servers' key, but should still save the
+	// snapshot they encrypted.
+	snapDir := filepath.Join(dir, "snaps")
+	if err := os.Mkdir(snapDir, 0700); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	ui, c := testSnapshotAgentCommand(t)
+	args := []string{
+		"-http-addr=" + srv.httpAddr,
+		"-interval=0",
+		"-local-path=" + snapDir,
+	}
+	if code := c.Run(args); code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	snaps, err := listAgentSnapshots(snapDir)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(snaps) != 1 {
+		t.Fatalf("bad: %v", snaps)
+	}
+	f, err := os.Open(filepath.Join(snapDir, snaps[0]))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+	if _, err := snapshot.Verify(f, key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
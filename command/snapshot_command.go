@@ -1,11 +1,18 @@
 package command
 
 import (
+	"flag"
+	"os"
 	"strings"
 
+	"github.com/hashicorp/consul/snapshot"
 	"github.com/mitchellh/cli"
 )
 
+// snapshotKeyEnvName is the environment variable that can hold the
+// base64-encoded key used to encrypt and decrypt snapshots.
+const snapshotKeyEnvName = "CONSUL_SNAPSHOT_ENCRYPTION_KEY"
+
 // SnapshotCommand is a Command implementation that just shows help for
 // the subcommands nested below it.
 type SnapshotCommand struct {
@@ -40,6 +47,14 @@ Usage: consul snapshot <subcommand> [options] [args]
 
       $ consul snapshot inspect backup.snap
 
+  Export a key/value tree from a snapshot:
+
+      $ consul snapshot export -prefix=vault/ backup.snap
+
+  Run a daemon process that locally saves a snapshot every hour:
+
+      $ consul snapshot agent
+
 
   For more examples, ask for subcommand help or view the documentation.
 
@@ -50,3 +65,26 @@ Usage: consul snapshot <subcommand> [options] [args]
 func (c *SnapshotCommand) Synopsis() string {
 	return "Saves, restores and inspects snapshots of Consul server state"
 }
+
+// snapshotKeyFlag adds the flag used to supply the snapshot encryption key to
+// the given flag set.
+func snapshotKeyFlag(f *flag.FlagSet, keyFile *string) {
+	f.StringVar(keyFile, "encryption-key-file", "",
+		"Path to a file holding the base64-encoded 32-byte key used to encrypt "+
+			"and decrypt snapshots. This can also be specified via the "+
+			snapshotKeyEnvName+" environment variable. Unencrypted snapshots "+
+			"can always be read.")
+}
+
+// snapshotKey loads the snapshot encryption key from the given file, or from
+// the environment if no file is given. This returns a nil key if neither is
+// set.
+func snapshotKey(keyFile string) ([]byte, error) {
+	if keyFile != "" {
+		return snapshot.ReadKeyFile(keyFile)
+	}
+	if encoded := os.Getenv(snapshotKeyEnvName); encoded != "" {
+		return snapshot.DecodeKey(encoded)
+	}
+	return nil, nil
+}
@@ -0,0 +1,188 @@
+package command
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/hashicorp/consul/command/base"
+	"github.com/hashicorp/consul/testutil"
+	"github.com/mitchellh/cli"
+)
+
+func testSnapshotExportCommand(t *testing.T) (*cli.MockUi, *SnapshotExportCommand) {
+	ui := new(cli.MockUi)
+	return ui, &SnapshotExportCommand{
+		Command: base.Command{
+			UI:    ui,
+			Flags: base.FlagSetNone,
+		},
+	}
+}
+
+func TestSnapshotExportCommand_implements(t *testing.T) {
+	var _ cli.Command = &SnapshotExportCommand{}
+}
+
+func TestSnapshotExportCommand_noTabs(t *testing.T) {
+	assertNoTabs(t, new(SnapshotExportCommand))
+}
+
+func TestSnapshotExportCommand_Validation(t *testing.T) {
+	ui, c := testSnapshotExportCommand(t)
+
+	cases := map[string]struct {
+		args   []string
+		output string
+	}{
+		"no file": {
+			[]string{},
+			"Missing FILE argument",
+		},
+		"extra args": {
+			[]string{"foo", "bar", "baz"},
+			"Too many arguments",
+		},
+		"bad type": {
+			[]string{"-type=nope", "foo"},
+			"Invalid type",
+		},
+		"prefix without kv": {
+			[]string{"-type=acl", "-prefix=foo", "foo"},
+			"Prefix is only valid",
+		},
+	}
+
+	for name, tc := range cases {
+		// Ensure our buffer is always clear
+		if ui.ErrorWriter != nil {
+			ui.ErrorWriter.Reset()
+		}
+		if ui.OutputWriter != nil {
+			ui.OutputWriter.Reset()
+		}
+
+		code := c.Run(tc.args)
+		if code == 0 {
+			t.Errorf("%s: expected non-zero exit", name)
+		}
+
+		output := ui.ErrorWriter.String()
+		if !strings.Contains(output, tc.output) {
+			t.Errorf("%s: expected %q to contain %q", name, output, tc.output)
+		}
+	}
+}
+
+func TestSnapshotExportCommand_Run(t *testing.T) {
+	srv, client := testAgentWithAPIClient(t)
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	kv := client.KV()
+	for _, key := range []string{"app/web/config", "app/web/flags", "app/db/config"} {
+		if _, err := kv.Put(&api.KVPair{Key: key, Flags: 42, Value: []byte(key)}, nil); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+	query := &api.PreparedQueryDefinition{
+		Name:    "web",
+		Service: api.ServiceQuery{Service: "web"},
+	}
+	if _, _, err := client.PreparedQuery().Create(query, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	dir := testutil.TempDir(t, "snapshot")
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "backup.tgz")
+
+	// Save a snapshot of the current Consul state
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	snap, _, err := client.Snapshot().Save(nil)
+	if err != nil {
+		f.Close()
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := io.Copy(f, snap); err != nil {
+		f.Close()
+		t.Fatalf("err: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Lose one of the trees.
+	if _, err := kv.DeleteTree("app/web/", nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Export the lost tree from the snapshot.
+	ui, c := testSnapshotExportCommand(t)
+	code := c.Run([]string{"-prefix=/app/web/", file})
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	exported := ui.OutputWriter.String()
+	if strings.Contains(exported, "app/db/config") {
+		t.Fatalf("bad: %s", exported)
+	}
+
+	// Import it back in.
+	importUI := new(cli.MockUi)
+	importCmd := &KVImportCommand{
+		Command: base.Command{
+			UI:    importUI,
+			Flags: base.FlagSetHTTP,
+		},
+		testStdin: strings.NewReader(exported),
+	}
+	code = importCmd.Run([]string{"-http-addr=" + srv.httpAddr, "-"})
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, importUI.ErrorWriter.String())
+	}
+
+	for _, key := range []string{"app/web/config", "app/web/flags"} {
+		pair, _, err := kv.Get(key, nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if pair == nil || string(pair.Value) != key || pair.Flags != 42 {
+			t.Fatalf("bad: %#v", pair)
+		}
+	}
+
+	// Export the prepared queries.
+	ui, c = testSnapshotExportCommand(t)
+	code = c.Run([]string{"-type=query", file})
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	var queries []*api.PreparedQueryDefinition
+	if err := json.Unmarshal(ui.OutputWriter.Bytes(), &queries); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(queries) != 1 || queries[0].Name != "web" || queries[0].Service.Service != "web" {
+		t.Fatalf("bad: %#v", queries)
+	}
+
+	// ACLs are disabled, so this should be an empty list.
+	ui, c = testSnapshotExportCommand(t)
+	code = c.Run([]string{"-type=acl", file})
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+	if out := strings.TrimSpace(ui.OutputWriter.String()); out != "[]" {
+		t.Fatalf("bad: %s", out)
+	}
+}
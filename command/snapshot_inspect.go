@@ -1,14 +1,21 @@
 package command
 
 import (
+	"bufio"
 	"bytes"
 	"fmt"
+	"io"
+	"log"
 	"os"
+	"sort"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/hashicorp/consul/command/base"
+	"github.com/hashicorp/consul/consul"
+	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/consul/snapshot"
+	"github.com/hashicorp/go-msgpack/codec"
 )
 
 // SnapshotInspectCommand is a Command implementation that is used to display
@@ -27,14 +34,34 @@ Usage: consul snapshot inspect [options] FILE
 
     $ consul snapshot inspect backup.snap
 
+  To also break down the contents of the snapshot by type of data, along with
+  the key/value prefixes using the most space:
+
+    $ consul snapshot inspect -detailed backup.snap
+
   For a full list of options and examples, please see the Consul documentation.
-`
+
+` + c.Command.Help()
 
 	return strings.TrimSpace(helpText)
 }
 
 func (c *SnapshotInspectCommand) Run(args []string) int {
+	var detailed bool
+	var kvDepth, kvTop int
+	var keyFile string
+
 	flagSet := c.Command.NewFlagSet(c)
+	snapshotKeyFlag(flagSet, &keyFile)
+	flagSet.BoolVar(&detailed, "detailed", false,
+		"Decode the snapshot data and display the count and size in bytes of "+
+			"each type of record it contains.")
+	flagSet.IntVar(&kvDepth, "kv-depth", 2,
+		"Number of path segments used to group key/value entries into prefixes "+
+			"for the detailed output. The default value is 2.")
+	flagSet.IntVar(&kvTop, "kv-top", 10,
+		"Number of the largest key/value prefixes to display in the detailed "+
+			"output. The default value is 10.")
 
 	if err := c.Command.Parse(args); err != nil {
 		return 1
@@ -54,6 +81,21 @@ func (c *SnapshotInspectCommand) Run(args []string) int {
 		return 1
 	}
 
+	if kvDepth < 1 {
+		c.UI.Error("KV depth must be positive")
+		return 1
+	}
+	if kvTop < 0 {
+		c.UI.Error("KV top must not be negative")
+		return 1
+	}
+
+	key, err := snapshotKey(keyFile)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading snapshot encryption key: %s", err))
+		return 1
+	}
+
 	// Open the file.
 	f, err := os.Open(file)
 	if err != nil {
@@ -62,18 +104,68 @@ func (c *SnapshotInspectCommand) Run(args []string) int {
 	}
 	defer f.Close()
 
-	meta, err := snapshot.Verify(f)
-	if err != nil {
-		c.UI.Error(fmt.Sprintf("Error verifying snapshot: %s", err))
-	}
-
 	var b bytes.Buffer
 	tw := tabwriter.NewWriter(&b, 0, 2, 6, ' ', 0)
-	fmt.Fprintf(tw, "ID\t%s\n", meta.ID)
-	fmt.Fprintf(tw, "Size\t%d\n", meta.Size)
-	fmt.Fprintf(tw, "Index\t%d\n", meta.Index)
-	fmt.Fprintf(tw, "Term\t%d\n", meta.Term)
-	fmt.Fprintf(tw, "Version\t%d\n", meta.Version)
+
+	if !detailed {
+		meta, err := snapshot.Verify(f, key)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error verifying snapshot: %s", err))
+			return 1
+		}
+
+		fmt.Fprintf(tw, "ID\t%s\n", meta.ID)
+		fmt.Fprintf(tw, "Size\t%d\n", meta.Size)
+		fmt.Fprintf(tw, "Index\t%d\n", meta.Index)
+		fmt.Fprintf(tw, "Term\t%d\n", meta.Term)
+		fmt.Fprintf(tw, "Version\t%d\n", meta.Version)
+	} else {
+		logger := log.New(os.Stderr, "", log.LstdFlags)
+		data, meta, err := snapshot.Read(logger, f, key)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error verifying snapshot: %s", err))
+			return 1
+		}
+		defer func() {
+			data.Close()
+			os.Remove(data.Name())
+		}()
+
+		stats, err := inspectSnapshotData(data, kvDepth)
+		if err != nil {
+			c.UI.Error(fmt.Sprintf("Error decoding snapshot data: %s", err))
+			return 1
+		}
+
+		fmt.Fprintf(tw, "ID\t%s\n", meta.ID)
+		fmt.Fprintf(tw, "Size\t%d\n", meta.Size)
+		fmt.Fprintf(tw, "Index\t%d\n", meta.Index)
+		fmt.Fprintf(tw, "Term\t%d\n", meta.Term)
+		fmt.Fprintf(tw, "Version\t%d\n", meta.Version)
+		fmt.Fprintf(tw, "\n")
+		fmt.Fprintf(tw, "Type\tCount\tSize\n")
+		var count, size int
+		for _, typ := range stats.types {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", typ.name, typ.count, typ.size)
+			count += typ.count
+			size += typ.size
+		}
+		fmt.Fprintf(tw, "Total\t%d\t%d\n", count, size)
+
+		if kvTop > 0 && len(stats.kvPrefixes) > 0 {
+			prefixes := stats.kvPrefixes
+			if len(prefixes) > kvTop {
+				prefixes = prefixes[:kvTop]
+			}
+
+			fmt.Fprintf(tw, "\n")
+			fmt.Fprintf(tw, "Key Prefix\tCount\tSize\n")
+			for _, p := range prefixes {
+				fmt.Fprintf(tw, "%s\t%d\t%d\n", p.name, p.count, p.size)
+			}
+		}
+	}
+
 	if err = tw.Flush(); err != nil {
 		c.UI.Error(fmt.Sprintf("Error rendering snapshot info: %s", err))
 	}
@@ -86,3 +178,213 @@ func (c *SnapshotInspectCommand) Run(args []string) int {
 func (c *SnapshotInspectCommand) Synopsis() string {
 	return "Displays information about a Consul snapshot file"
 }
+
+// snapshotStat holds the number of records and the number of bytes they take
+// up in the snapshot for a given type of data or key prefix.
+type snapshotStat struct {
+	name  string
+	count int
+	size  int
+}
+
+// snapshotStats is a breakdown of the contents of a snapshot.
+type snapshotStats struct {
+	// types is ordered the same way the FSM writes the snapshot.
+	types []*snapshotStat
+
+	// kvPrefixes is sorted by size, largest first.
+	kvPrefixes []*snapshotStat
+}
+
+// countingReader is an io.Reader that keeps track of how many bytes have been
+// read through it, so we can measure the size of each record.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += n
+	return n, err
+}
+
+// inspectSnapshotData walks the records in the given Raft snapshot data and
+// tallies them by type. Key/value entries are additionally grouped into
+// prefixes made of the first depth segments of their keys.
+func inspectSnapshotData(in io.Reader, depth int) (*snapshotStats, error) {
+	var types []*snapshotStat
+	byType := make(map[string]*snapshotStat)
+	byPrefix := make(map[string]*snapshotStat)
+	record := func(stats map[string]*snapshotStat, name string, size int) *snapshotStat {
+		s, ok := stats[name]
+		if !ok {
+			s = &snapshotStat{name: name}
+			stats[name] = s
+		}
+		s.count++
+		s.size += size
+		return s
+	}
+
+	cr := &countingReader{r: bufio.NewReader(in)}
+	handler := func(header *consul.SnapshotHeader, msg structs.MessageType, dec *codec.Decoder) error {
+		// The message type byte has already been consumed, so count it
+		// towards the record.
+		start := cr.n - 1
+
+		rec, err := decodeSnapshotRecord(msg, dec)
+		if err != nil {
+			return err
+		}
+
+		var name, key string
+		switch v := rec.(type) {
+		case *structs.RegisterRequest:
+			switch {
+			case v.Service != nil:
+				name = "Service"
+			case v.Check != nil:
+				name = "Check"
+			default:
+				name = "Node"
+			}
+		case *structs.DirEntry:
+			if msg == structs.TombstoneRequestType {
+				name = "Tombstone"
+			} else {
+				name, key = "KV", v.Key
+			}
+		case *structs.Session:
+			name = "Session"
+		case *structs.ACL:
+			name = "ACL"
+		case structs.Coordinates:
+			name = "Coordinates"
+		case *structs.PreparedQuery:
+			name = "Prepared Query"
+		case *structs.AutopilotConfig:
+			name = "Autopilot"
+		case *structs.DurableEvent:
+			name = "Durable Event"
+		case *structs.DurableEventAck:
+			name = "Durable Event Ack"
+		}
+
+		size := cr.n - start
+		if _, ok := byType[name]; !ok {
+			types = append(types, record(byType, name, size))
+		} else {
+			record(byType, name, size)
+		}
+		if name == "KV" {
+			record(byPrefix, kvPrefix(key, depth), size)
+		}
+		return nil
+	}
+	if err := consul.ReadSnapshot(cr, handler); err != nil {
+		return nil, err
+	}
+
+	stats := &snapshotStats{types: types}
+	for _, s := range byPrefix {
+		stats.kvPrefixes = append(stats.kvPrefixes, s)
+	}
+	sort.Sort(bySnapshotStatSize(stats.kvPrefixes))
+	return stats, nil
+}
+
+// bySnapshotStatSize sorts stats by size, largest first, and then by name.
+type bySnapshotStatSize []*snapshotStat
+
+func (s bySnapshotStatSize) Len() int      { return len(s) }
+func (s bySnapshotStatSize) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySnapshotStatSize) Less(i, j int) bool {
+	if s[i].size != s[j].size {
+		return s[i].size > s[j].size
+	}
+	return s[i].name < s[j].name
+}
+
+// decodeSnapshotRecord decodes the body of a single snapshot record of the
+// given type, mirroring the types the FSM uses when it restores a snapshot.
+// Tombstones are stored as *structs.DirEntry, same as key/value entries.
+func decodeSnapshotRecord(msg structs.MessageType, dec *codec.Decoder) (interface{}, error) {
+	switch msg {
+	case structs.RegisterRequestType:
+		var req structs.RegisterRequest
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case structs.KVSRequestType, structs.TombstoneRequestType:
+		var req structs.DirEntry
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case structs.SessionRequestType:
+		var req structs.Session
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case structs.ACLRequestType:
+		var req structs.ACL
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case structs.CoordinateBatchUpdateType:
+		var req structs.Coordinates
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		return req, nil
+
+	case structs.PreparedQueryRequestType:
+		var req structs.PreparedQuery
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case structs.AutopilotRequestType:
+		var req structs.AutopilotConfig
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case structs.DurableEventRequestType:
+		var req structs.DurableEvent
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	case structs.DurableEventAckRequestType:
+		var req structs.DurableEventAck
+		if err := dec.Decode(&req); err != nil {
+			return nil, err
+		}
+		return &req, nil
+
+	default:
+		return nil, fmt.Errorf("Unrecognized msg type: %v", msg)
+	}
+}
+
+// kvPrefix returns the first depth segments of the given key, keeping the
+// trailing slash if the key is longer than that.
+func kvPrefix(key string, depth int) string {
+	parts := strings.SplitAfter(key, "/")
+	if len(parts) <= depth {
+		return key
+	}
+	return strings.Join(parts[:depth], "")
+}
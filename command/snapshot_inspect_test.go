@@ -7,6 +7,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/command/base"
 	"github.com/hashicorp/consul/testutil"
 	"github.com/mitchellh/cli"
@@ -119,3 +120,89 @@ func TestSnapshotInspectCommand_Run(t *testing.T) {
 		}
 	}
 }
+
+func TestSnapshotInspectCommand_Detailed(t *testing.T) {
+	srv, client := testAgentWithAPIClient(t)
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	// Put some data in so there's something to break down.
+	kv := client.KV()
+	for _, key := range []string{"app/web/config", "app/web/flags", "app/db/config", "other"} {
+		if _, err := kv.Put(&api.KVPair{Key: key, Value: []byte("hello")}, nil); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	dir := testutil.TempDir(t, "snapshot")
+	defer os.RemoveAll(dir)
+
+	file := path.Join(dir, "backup.tgz")
+
+	// Save a snapshot of the current Consul state
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	snap, _, err := client.Snapshot().Save(nil)
+	if err != nil {
+		f.Close()
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := io.Copy(f, snap); err != nil {
+		f.Close()
+		t.Fatalf("err: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Inspect the snapshot
+	ui, c := testSnapshotInspectCommand(t)
+	args := []string{"-detailed", file}
+
+	code := c.Run(args)
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	output := ui.OutputWriter.String()
+	for _, key := range []string{
+		"ID",
+		"Index",
+		"Node",
+		"Service",
+		"Check",
+		"KV             4",
+		"Autopilot",
+		"Total",
+		"Key Prefix",
+		"app/web/        2",
+		"app/db/         1",
+		"other           1",
+	} {
+		if !strings.Contains(output, key) {
+			t.Fatalf("bad %#v, missing %q", output, key)
+		}
+	}
+}
+
+func TestSnapshotInspectCommand_kvPrefix(t *testing.T) {
+	cases := []struct {
+		key    string
+		depth  int
+		prefix string
+	}{
+		{"foo", 1, "foo"},
+		{"foo/bar/baz", 1, "foo/"},
+		{"foo/bar/baz", 2, "foo/bar/"},
+		{"foo/bar/baz", 3, "foo/bar/baz"},
+		{"foo/bar/", 2, "foo/bar/"},
+	}
+	for _, tc := range cases {
+		if prefix := kvPrefix(tc.key, tc.depth); prefix != tc.prefix {
+			t.Errorf("%q at depth %d: got %q, want %q", tc.key, tc.depth, prefix, tc.prefix)
+		}
+	}
+}
@@ -2,10 +2,12 @@ package command
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"strings"
 
 	"github.com/hashicorp/consul/command/base"
+	"github.com/hashicorp/consul/snapshot"
 )
 
 // SnapshotRestoreCommand is a Command implementation that is used to restore
@@ -34,6 +36,12 @@ Usage: consul snapshot restore [options] FILE
 
     $ consul snapshot restore backup.snap
 
+  Encrypted snapshots are decrypted by the servers if they are configured with
+  the key. Otherwise, the key can be given here to decrypt the snapshot before
+  it is sent:
+
+    $ consul snapshot restore -encryption-key-file=snapshot.key backup.snap
+
   For a full list of options and examples, please see the Consul documentation.
 
 ` + c.Command.Help()
@@ -42,7 +50,10 @@ Usage: consul snapshot restore [options] FILE
 }
 
 func (c *SnapshotRestoreCommand) Run(args []string) int {
+	var keyFile string
+
 	flagSet := c.Command.NewFlagSet(c)
+	snapshotKeyFlag(flagSet, &keyFile)
 
 	if err := c.Command.Parse(args); err != nil {
 		return 1
@@ -62,6 +73,12 @@ func (c *SnapshotRestoreCommand) Run(args []string) int {
 		return 1
 	}
 
+	key, err := snapshotKey(keyFile)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading snapshot encryption key: %s", err))
+		return 1
+	}
+
 	// Create and test the HTTP client
 	client, err := c.Command.HTTPClient()
 	if err != nil {
@@ -77,8 +94,20 @@ func (c *SnapshotRestoreCommand) Run(args []string) int {
 	}
 	defer f.Close()
 
+	// Decrypt the snapshot on the way out if we were given a key.
+	// Unencrypted snapshots pass through as-is.
+	var in io.Reader = f
+	if key != nil {
+		pr, pw := io.Pipe()
+		defer pr.Close()
+		go func() {
+			pw.CloseWithError(snapshot.Decrypt(pw, f, key))
+		}()
+		in = pr
+	}
+
 	// Restore the snapshot.
-	err = client.Snapshot().Restore(nil, f)
+	err = client.Snapshot().Restore(nil, in)
 	if err != nil {
 		c.UI.Error(fmt.Sprintf("Error restoring snapshot: %s", err))
 		return 1
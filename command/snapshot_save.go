@@ -37,6 +37,10 @@ Usage: consul snapshot save [options] FILE
 
     $ consul snapshot save -stale backup.snap
 
+  To encrypt the snapshot with a key, unless the servers already did:
+
+    $ consul snapshot save -encryption-key-file=snapshot.key backup.snap
+
   For a full list of options and examples, please see the Consul documentation.
 
 ` + c.Command.Help()
@@ -45,7 +49,10 @@ Usage: consul snapshot save [options] FILE
 }
 
 func (c *SnapshotSaveCommand) Run(args []string) int {
+	var keyFile string
+
 	flagSet := c.Command.NewFlagSet(c)
+	snapshotKeyFlag(flagSet, &keyFile)
 
 	if err := c.Command.Parse(args); err != nil {
 		return 1
@@ -65,6 +72,12 @@ func (c *SnapshotSaveCommand) Run(args []string) int {
 		return 1
 	}
 
+	key, err := snapshotKey(keyFile)
+	if err != nil {
+		c.UI.Error(fmt.Sprintf("Error loading snapshot encryption key: %s", err))
+		return 1
+	}
+
 	// Create and test the HTTP client
 	client, err := c.Command.HTTPClient()
 	if err != nil {
@@ -88,7 +101,12 @@ func (c *SnapshotSaveCommand) Run(args []string) int {
 		c.UI.Error(fmt.Sprintf("Error creating snapshot file: %s", err))
 		return 1
 	}
-	if _, err := io.Copy(f, snap); err != nil {
+	if key != nil {
+		err = snapshot.Encrypt(f, snap, key)
+	} else {
+		_, err = io.Copy(f, snap)
+	}
+	if err != nil {
 		f.Close()
 		c.UI.Error(fmt.Sprintf("Error writing snapshot file: %s", err))
 		return 1
@@ -104,7 +122,7 @@ func (c *SnapshotSaveCommand) Run(args []string) int {
 		c.UI.Error(fmt.Sprintf("Error opening snapshot file for verify: %s", err))
 		return 1
 	}
-	if _, err := snapshot.Verify(f); err != nil {
+	if err := verifySnapshot(f, key); err != nil {
 		f.Close()
 		c.UI.Error(fmt.Sprintf("Error verifying snapshot file: %s", err))
 		return 1
@@ -118,6 +136,20 @@ func (c *SnapshotSaveCommand) Run(args []string) int {
 	return 0
 }
 
+// verifySnapshot checks a saved snapshot. Snapshots the servers encrypted
+// with their own key can't be read without one, so when no key is given
+// only their encryption envelope is checked.
+func verifySnapshot(f io.ReadSeeker, key []byte) error {
+	_, err := snapshot.Verify(f, key)
+	if err != snapshot.ErrEncrypted {
+		return err
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return snapshot.VerifyEnvelope(f)
+}
+
 func (c *SnapshotSaveCommand) Synopsis() string {
 	return "Saves snapshot of Consul server state"
 }
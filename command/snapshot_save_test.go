@@ -1,12 +1,17 @@
 package command
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"io/ioutil"
 	"os"
 	"path"
 	"strings"
 	"testing"
 
+	"github.com/hashicorp/consul/command/agent"
 	"github.com/hashicorp/consul/command/base"
+	"github.com/hashicorp/consul/snapshot"
 	"github.com/hashicorp/consul/testutil"
 	"github.com/mitchellh/cli"
 )
@@ -98,3 +103,119 @@ func TestSnapshotSaveCommand_Run(t *testing.T) {
 		t.Fatalf("err: %v", err)
 	}
 }
+
+func TestSnapshotSaveCommand_Encrypted(t *testing.T) {
+	srv, _ := testAgentWithAPIClient(t)
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	dir := testutil.TempDir(t, "snapshot")
+	defer os.RemoveAll(dir)
+
+	key := make([]byte, snapshot.EncryptKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keyFile := path.Join(dir, "snapshot.key")
+	encoded := base64.StdEncoding.EncodeToString(key)
+	if err := ioutil.WriteFile(keyFile, []byte(encoded), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Save an encrypted snapshot.
+	ui, c := testSnapshotSaveCommand(t)
+	file := path.Join(dir, "backup.tgz")
+	code := c.Run([]string{
+		"-http-addr=" + srv.httpAddr,
+		"-encryption-key-file=" + keyFile,
+		file,
+	})
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, err = snapshot.Verify(f, nil)
+	f.Close()
+	if err != snapshot.ErrEncrypted {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Restoring without the key should fail, since the server doesn't have
+	// it either.
+	restoreUI, restore := testSnapshotRestoreCommand(t)
+	code = restore.Run([]string{"-http-addr=" + srv.httpAddr, file})
+	if code == 0 {
+		t.Fatalf("expected restore to fail")
+	}
+
+	// Restoring with the key should work.
+	restoreUI, restore = testSnapshotRestoreCommand(t)
+	code = restore.Run([]string{
+		"-http-addr=" + srv.httpAddr,
+		"-encryption-key-file=" + keyFile,
+		file,
+	})
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, restoreUI.ErrorWriter.String())
+	}
+
+	// The key can also come from the environment.
+	os.Setenv(snapshotKeyEnvName, encoded)
+	defer os.Unsetenv(snapshotKeyEnvName)
+	inspectUI, inspect := testSnapshotInspectCommand(t)
+	code = inspect.Run([]string{file})
+	if code != 0 {
+		t.Fatalf("bad: %d. %#v", code, inspectUI.ErrorWriter.String())
+	}
+}
+
+func TestSnapshotSaveCommand_ServerEncrypted(t *testing.T) {
+	dir := testutil.TempDir(t, "snapshot")
+	defer os.RemoveAll(dir)
+
+	key := make([]byte, snapshot.EncryptKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	keyFile := path.Join(dir, "snapshot.key")
+	if err := ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	srv := testAgentWithConfig(t, func(c *agent.Config) {
+		c.SnapshotEncryptionKeyFile = keyFile
+	})
+	defer srv.Shutdown()
+	waitForLeader(t, srv.httpAddr)
+
+	// Saving works without a key of our own, and with the same key the
+	// servers use. Either way the servers' encrypted archive is kept as-is.
+	for _, extra := range [][]string{nil, {"-encryption-key-file=" + keyFile}} {
+		ui, c := testSnapshotSaveCommand(t)
+		file := path.Join(dir, "backup.tgz")
+		args := append([]string{"-http-addr=" + srv.httpAddr}, extra...)
+		if code := c.Run(append(args, file)); code != 0 {
+			t.Fatalf("bad: %d. %#v", code, ui.ErrorWriter.String())
+		}
+
+		f, err := os.Open(file)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		_, err = snapshot.Verify(f, key)
+		f.Close()
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		// The servers can restore it with their key.
+		restoreUI, restore := testSnapshotRestoreCommand(t)
+		if code := restore.Run([]string{"-http-addr=" + srv.httpAddr, file}); code != 0 {
+			t.Fatalf("bad: %d. %#v", code, restoreUI.ErrorWriter.String())
+		}
+	}
+}
@@ -70,7 +70,7 @@ func testAgentWithConfigReload(t *testing.T, cb func(c *agent.Config), reloadCh
 	dir := testutil.TempDir(t, "agent")
 	conf.DataDir = dir
 
-	a, err := agent.Create(conf, lw, nil, reloadCh)
+	a, err := agent.Create(conf, lw, lw, reloadCh)
 	if err != nil {
 		os.RemoveAll(dir)
 		t.Fatalf(fmt.Sprintf("err: %v", err))
@@ -19,6 +19,24 @@ func init() {
 	ui := &cli.BasicUi{Writer: os.Stdout, ErrorWriter: os.Stderr}
 
 	Commands = map[string]cli.CommandFactory{
+		"acl": func() (cli.Command, error) {
+			return &command.ACLCommand{
+				Command: base.Command{
+					Flags: base.FlagSetNone,
+					UI:    ui,
+				},
+			}, nil
+		},
+
+		"acl set-agent-token": func() (cli.Command, error) {
+			return &command.ACLSetAgentTokenCommand{
+				Command: base.Command{
+					Flags: base.FlagSetClientHTTP,
+					UI:    ui,
+				},
+			}, nil
+		},
+
 		"agent": func() (cli.Command, error) {
 			return &agent.Command{
 				Command: base.Command{
@@ -42,6 +60,16 @@ func init() {
 			}, nil
 		},
 
+		"debug": func() (cli.Command, error) {
+			return &command.DebugCommand{
+				ShutdownCh: makeShutdownCh(),
+				Command: base.Command{
+					Flags: base.FlagSetClientHTTP,
+					UI:    ui,
+				},
+			}, nil
+		},
+
 		"event": func() (cli.Command, error) {
 			return &command.EventCommand{
 				Command: base.Command{
@@ -270,6 +298,78 @@ func init() {
 			}, nil
 		},
 
+		"query": func() (cli.Command, error) {
+			return &command.QueryCommand{
+				Command: base.Command{
+					Flags: base.FlagSetNone,
+					UI:    ui,
+				},
+			}, nil
+		},
+
+		"query create": func() (cli.Command, error) {
+			return &command.QueryCreateCommand{
+				Command: base.Command{
+					Flags: base.FlagSetHTTP,
+					UI:    ui,
+				},
+			}, nil
+		},
+
+		"query delete": func() (cli.Command, error) {
+			return &command.QueryDeleteCommand{
+				Command: base.Command{
+					Flags: base.FlagSetHTTP,
+					UI:    ui,
+				},
+			}, nil
+		},
+
+		"query execute": func() (cli.Command, error) {
+			return &command.QueryExecuteCommand{
+				Command: base.Command{
+					Flags: base.FlagSetHTTP,
+					UI:    ui,
+				},
+			}, nil
+		},
+
+		"query explain": func() (cli.Command, error) {
+			return &command.QueryExplainCommand{
+				Command: base.Command{
+					Flags: base.FlagSetHTTP,
+					UI:    ui,
+				},
+			}, nil
+		},
+
+		"query list": func() (cli.Command, error) {
+			return &command.QueryListCommand{
+				Command: base.Command{
+					Flags: base.FlagSetHTTP,
+					UI:    ui,
+				},
+			}, nil
+		},
+
+		"query read": func() (cli.Command, error) {
+			return &command.QueryReadCommand{
+				Command: base.Command{
+					Flags: base.FlagSetHTTP,
+					UI:    ui,
+				},
+			}, nil
+		},
+
+		"query update": func() (cli.Command, error) {
+			return &command.QueryUpdateCommand{
+				Command: base.Command{
+					Flags: base.FlagSetHTTP,
+					UI:    ui,
+				},
+			}, nil
+		},
+
 		"reload": func() (cli.Command, error) {
 			return &command.ReloadCommand{
 				Command: base.Command{
@@ -294,6 +394,25 @@ func init() {
 			}, nil
 		},
 
+		"snapshot agent": func() (cli.Command, error) {
+			return &command.SnapshotAgentCommand{
+				ShutdownCh: makeShutdownCh(),
+				Command: base.Command{
+					Flags: base.FlagSetHTTP,
+					UI:    ui,
+				},
+			}, nil
+		},
+
+		"snapshot export": func() (cli.Command, error) {
+			return &command.SnapshotExportCommand{
+				Command: base.Command{
+					Flags: base.FlagSetNone,
+					UI:    ui,
+				},
+			}, nil
+		},
+
 		"snapshot restore": func() (cli.Command, error) {
 			return &command.SnapshotRestoreCommand{
 				Command: base.Command{
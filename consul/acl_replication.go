@@ -154,7 +154,7 @@ func (s *Server) fetchRemoteACLs(lastRemoteIndex uint64) (*structs.IndexedACLs,
 	args := structs.DCSpecificRequest{
 		Datacenter: s.config.ACLDatacenter,
 		QueryOptions: structs.QueryOptions{
-			Token:         s.config.ACLReplicationToken,
+			Token:         s.tokens.ACLReplicationToken(),
 			MinQueryIndex: lastRemoteIndex,
 			AllowStale:    true,
 		},
@@ -243,11 +243,28 @@ func (s *Server) replicateACLs(lastRemoteIndex uint64) (uint64, error) {
 	return remote.QueryMeta.Index, nil
 }
 
-// IsACLReplicationEnabled returns true if ACL replication is enabled.
-func (s *Server) IsACLReplicationEnabled() bool {
+// isACLReplicationConfigured returns true if this server is outside of the
+// ACL datacenter, so it can replicate ACLs once it has a replication token.
+func (s *Server) isACLReplicationConfigured() bool {
 	authDC := s.config.ACLDatacenter
-	return len(authDC) > 0 && (authDC != s.config.Datacenter) &&
-		len(s.config.ACLReplicationToken) > 0
+	return len(authDC) > 0 && (authDC != s.config.Datacenter)
+}
+
+// IsACLReplicationEnabled returns true if ACL replication is enabled. The
+// replication token can be changed while the server is running, so this
+// can change over time.
+func (s *Server) IsACLReplicationEnabled() bool {
+	return s.isACLReplicationConfigured() &&
+		len(s.tokens.ACLReplicationToken()) > 0
+}
+
+// aclReplicatedFault looks up an ACL in the local state store, where it will
+// only be found if replication is enabled.
+func (s *Server) aclReplicatedFault(id string) (string, string, error) {
+	if !s.IsACLReplicationEnabled() {
+		return "", "", fmt.Errorf("ACL replication is not enabled")
+	}
+	return s.aclLocalFault(id)
 }
 
 // updateACLReplicationStatus safely updates the ACL replication status.
@@ -264,10 +281,11 @@ func (s *Server) updateACLReplicationStatus(status structs.ACLReplicationStatus)
 }
 
 // runACLReplication is a long-running goroutine that will attempt to replicate
-// ACLs while the server is the leader, until the shutdown channel closes.
+// ACLs while the server is the leader and has a replication token, until the
+// shutdown channel closes.
 func (s *Server) runACLReplication() {
 	var status structs.ACLReplicationStatus
-	status.Enabled = true
+	status.Enabled = s.IsACLReplicationEnabled()
 	status.SourceDatacenter = s.config.ACLDatacenter
 	s.updateACLReplicationStatus(status)
 
@@ -313,12 +331,12 @@ func (s *Server) runACLReplication() {
 			s.logger.Printf("[DEBUG] consul: ACL replication completed through remote index %d", index)
 		}
 	}
-	pause := func() {
+	pause := func(reason string) {
 		if status.Running {
 			lastRemoteIndex = 0 // Re-sync everything.
 			status.Running = false
 			s.updateACLReplicationStatus(status)
-			s.logger.Printf("[INFO] consul: ACL replication stopped (no longer leader)")
+			s.logger.Printf("[INFO] consul: ACL replication stopped (%s)", reason)
 		}
 	}
 
@@ -337,10 +355,17 @@ func (s *Server) runACLReplication() {
 			return
 
 		case <-time.After(s.config.ACLReplicationInterval):
-			if s.IsLeader() {
+			if enabled := s.IsACLReplicationEnabled(); enabled != status.Enabled {
+				status.Enabled = enabled
+				s.updateACLReplicationStatus(status)
+			}
+			switch {
+			case !status.Enabled:
+				pause("no replication token")
+			case !s.IsLeader():
+				pause("no longer leader")
+			default:
 				replicate()
-			} else {
-				pause()
 			}
 		}
 	}
@@ -291,6 +291,12 @@ func TestACLReplication_IsACLReplicationEnabled(t *testing.T) {
 		t.Fatalf("should not be enabled")
 	}
 
+	// Setting the token while the server is running enables replication.
+	s2.tokens.UpdateACLReplicationToken("secret")
+	if !s2.IsACLReplicationEnabled() {
+		t.Fatalf("should be enabled")
+	}
+
 	// ACLs enabled with replication.
 	dir3, s3 := testServerWithConfig(t, func(c *Config) {
 		c.Datacenter = "dc2"
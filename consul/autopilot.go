@@ -3,6 +3,7 @@ package consul
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -149,7 +150,9 @@ func (s *Server) pruneDeadServers(autopilotConfig *structs.AutopilotConfig) erro
 }
 
 // BasicAutopilot defines a policy for promoting non-voting servers in a way
-// that maintains an odd-numbered voter count.
+// that maintains an odd-numbered voter count. If a redundancy zone tag is
+// configured, it instead keeps a single voter per zone, and unless disabled
+// it migrates voting rights to newer servers during upgrades.
 type BasicAutopilot struct {
 	server *Server
 }
@@ -171,18 +174,36 @@ func (b *BasicAutopilot) PromoteNonVoters(autopilotConfig *structs.AutopilotConf
 		return fmt.Errorf("failed to get raft configuration: %v", err)
 	}
 
+	servers, err := b.server.autopilotServers(future.Configuration(), autopilotConfig)
+	if err != nil {
+		return err
+	}
+
+	// An upgrade in progress takes priority, since new servers are held
+	// back as non-voters until there are enough of them to take over.
+	// Once it's done, the old servers are never promoted again.
+	if !autopilotConfig.DisableUpgradeMigration {
+		migrating, err := b.server.handleUpgradeMigration(autopilotConfig, servers)
+		if err != nil || migrating {
+			return err
+		}
+		servers = withoutOutdatedNonVoters(servers)
+	}
+
+	if autopilotConfig.RedundancyZoneTag != "" {
+		return b.server.handleRedundancyZones(autopilotConfig, servers)
+	}
+
 	// Find any non-voters eligible for promotion
 	var promotions []raft.Server
 	voterCount := 0
-	for _, server := range future.Configuration().Servers {
+	now := time.Now()
+	for _, server := range servers {
 		// If this server has been stable and passing for long enough, promote it to a voter
-		if !isVoter(server.Suffrage) {
-			health := b.server.getServerHealth(string(server.ID))
-			if health.IsStable(time.Now(), autopilotConfig) {
-				promotions = append(promotions, server)
-			}
-		} else {
+		if server.isVoter() {
 			voterCount++
+		} else if server.canPromote(now, autopilotConfig) {
+			promotions = append(promotions, server.Server)
 		}
 	}
 
@@ -193,6 +214,348 @@ func (b *BasicAutopilot) PromoteNonVoters(autopilotConfig *structs.AutopilotConf
 	return nil
 }
 
+// autopilotServer is a server from the Raft configuration along with the
+// details autopilot uses to decide whether it should have a vote.
+type autopilotServer struct {
+	raft.Server
+
+	// parts is the server's gossiped information, or nil if it's not an
+	// alive member of the LAN pool.
+	parts *agent.Server
+
+	// health is the last known health of the server, which may be nil.
+	health *structs.ServerHealth
+
+	// zone is the server's redundancy zone, if any.
+	zone string
+}
+
+func (a *autopilotServer) isVoter() bool {
+	return isVoter(a.Suffrage)
+}
+
+// isReadReplica returns true if the server was started as a read replica,
+// which should never be given a vote.
+func (a *autopilotServer) isReadReplica() bool {
+	return a.parts != nil && a.parts.NonVoter
+}
+
+// canPromote returns true if the server is a non-voter that has been stable
+// and passing for long enough to be promoted.
+func (a *autopilotServer) canPromote(now time.Time, autopilotConfig *structs.AutopilotConfig) bool {
+	return !a.isVoter() && !a.isReadReplica() && a.health.IsStable(now, autopilotConfig)
+}
+
+// autopilotServers gathers the details for each server in the given Raft
+// configuration. Redundancy zones are looked up from the node metadata in
+// the catalog, using the configured tag.
+func (s *Server) autopilotServers(configuration raft.Configuration,
+	autopilotConfig *structs.AutopilotConfig) ([]*autopilotServer, error) {
+
+	serverMap := make(map[string]*agent.Server)
+	for _, member := range s.LANMembers() {
+		if member.Status != serf.StatusAlive {
+			continue
+		}
+
+		valid, parts := agent.IsConsulServer(member)
+		if valid {
+			serverMap[parts.ID] = parts
+		}
+	}
+
+	state := s.fsm.State()
+	var servers []*autopilotServer
+	for _, server := range configuration.Servers {
+		as := &autopilotServer{
+			Server: server,
+			parts:  serverMap[string(server.ID)],
+			health: s.getServerHealth(string(server.ID)),
+		}
+
+		if autopilotConfig.RedundancyZoneTag != "" && as.parts != nil {
+			_, node, err := state.GetNode(as.parts.Name)
+			if err != nil {
+				return nil, fmt.Errorf("error looking up node %q: %v", as.parts.Name, err)
+			}
+			if node != nil {
+				as.zone = node.Meta[autopilotConfig.RedundancyZoneTag]
+			}
+		}
+
+		servers = append(servers, as)
+	}
+	return servers, nil
+}
+
+// newestServerVersion returns the highest build version among the given
+// servers, or nil if none of them are known. Read replicas are ignored since
+// they can't take part in an upgrade migration.
+func newestServerVersion(servers []*autopilotServer) *version.Version {
+	var newest *version.Version
+	for _, server := range servers {
+		if server.parts == nil || server.isReadReplica() {
+			continue
+		}
+		if newest == nil || newest.LessThan(&server.parts.Build) {
+			newest = &server.parts.Build
+		}
+	}
+	return newest
+}
+
+// withoutOutdatedNonVoters filters out non-voters that are running an older
+// version than the newest server, so they don't get promoted once an upgrade
+// migration has moved the votes to the new servers.
+func withoutOutdatedNonVoters(servers []*autopilotServer) []*autopilotServer {
+	newest := newestServerVersion(servers)
+	if newest == nil {
+		return servers
+	}
+
+	var filtered []*autopilotServer
+	for _, server := range servers {
+		if !server.isVoter() && server.parts != nil && server.parts.Build.LessThan(newest) {
+			continue
+		}
+		filtered = append(filtered, server)
+	}
+	return filtered
+}
+
+// handleUpgradeMigration looks for voters running an older version than the
+// newest server in the cluster. If there are any, new servers are held back
+// as non-voters until there are enough stable ones to replace the old voters
+// (one per redundancy zone, if zones are configured), at which point they are
+// promoted together and the old voters are demoted. The leader is demoted
+// last, once it's the only old voter left. Returns true if an upgrade is in
+// progress, in which case no other promotions should be made.
+func (s *Server) handleUpgradeMigration(autopilotConfig *structs.AutopilotConfig,
+	servers []*autopilotServer) (bool, error) {
+
+	newest := newestServerVersion(servers)
+	if newest == nil {
+		return false, nil
+	}
+
+	now := time.Now()
+	var oldVoters, newServers []*autopilotServer
+	for _, server := range servers {
+		if server.parts == nil || server.isReadReplica() {
+			continue
+		}
+
+		if server.parts.Build.LessThan(newest) {
+			if server.isVoter() {
+				oldVoters = append(oldVoters, server)
+			}
+		} else if server.health.IsStable(now, autopilotConfig) {
+			newServers = append(newServers, server)
+		}
+	}
+	if len(oldVoters) == 0 {
+		return false, nil
+	}
+
+	// Work out which new servers should take over.
+	var promotions []*autopilotServer
+	if autopilotConfig.RedundancyZoneTag != "" {
+		// Every zone with an old voter needs a new server to replace it.
+		// Existing voters are preferred so we don't promote more than one
+		// server per zone. Servers without a zone are matched up by count,
+		// the same as when zones aren't configured.
+		replacements := make(map[string]*autopilotServer)
+		var ungrouped []*autopilotServer
+		for _, server := range newServers {
+			if server.zone == "" {
+				ungrouped = append(ungrouped, server)
+				continue
+			}
+			if current, ok := replacements[server.zone]; !ok || (!current.isVoter() && server.isVoter()) {
+				replacements[server.zone] = server
+			}
+		}
+		ungroupedOld := 0
+		for _, server := range oldVoters {
+			if server.zone == "" {
+				ungroupedOld++
+			} else if _, ok := replacements[server.zone]; !ok {
+				return true, nil
+			}
+		}
+		if len(ungrouped) < ungroupedOld {
+			return true, nil
+		}
+		for _, server := range append(ungrouped, sortedServers(replacements)...) {
+			if !server.isVoter() {
+				promotions = append(promotions, server)
+			}
+		}
+	} else {
+		if len(newServers) < len(oldVoters) {
+			return true, nil
+		}
+		for _, server := range newServers {
+			if !server.isVoter() {
+				promotions = append(promotions, server)
+			}
+		}
+	}
+
+	if len(promotions) > 0 {
+		s.logger.Printf("[INFO] autopilot: Enough servers running version %s are stable, starting upgrade migration", newest)
+	}
+	for _, server := range promotions {
+		if err := s.promoteVoter(server.Server); err != nil {
+			return true, err
+		}
+	}
+	if len(promotions) > 0 {
+		s.triggerDeadServerCleanup()
+	}
+
+	// Demote the old voters, leaving the leader until last so we don't
+	// churn leadership more than once.
+	leader := s.raft.Leader()
+	for _, server := range oldVoters {
+		if server.Address == leader && len(oldVoters) > 1 {
+			continue
+		}
+		if err := s.demoteVoter(server.Server); err != nil {
+			return true, err
+		}
+	}
+
+	return true, nil
+}
+
+// handleRedundancyZones keeps exactly one voter in each redundancy zone,
+// with the rest of the zone's servers left as non-voters. If a zone's voter
+// becomes unhealthy, a stable non-voter from the same zone is promoted in its
+// place. Servers without a zone are each treated as their own zone, so they
+// get promoted as usual. The leader is never demoted.
+func (s *Server) handleRedundancyZones(autopilotConfig *structs.AutopilotConfig,
+	servers []*autopilotServer) error {
+
+	var names []string
+	zones := make(map[string][]*autopilotServer)
+	for _, server := range servers {
+		zone := redundancyZone(server)
+		if _, ok := zones[zone]; !ok {
+			names = append(names, zone)
+		}
+		zones[zone] = append(zones[zone], server)
+	}
+	sort.Strings(names)
+
+	leader := s.raft.Leader()
+	now := time.Now()
+	promoted := false
+	for _, name := range names {
+		// Pick the server that should hold the zone's vote, preferring the
+		// leader, then any healthy voter, then a stable non-voter.
+		var keep, candidate *autopilotServer
+		for _, server := range zones[name] {
+			healthy := server.health != nil && server.health.Healthy
+			switch {
+			case server.isVoter() && server.Address == leader:
+				keep = server
+			case server.isVoter() && healthy:
+				if keep == nil {
+					keep = server
+				}
+			case server.canPromote(now, autopilotConfig):
+				if candidate == nil {
+					candidate = server
+				}
+			}
+		}
+		if keep == nil {
+			// If there's nothing healthy in the zone we leave it alone
+			// rather than demote its last voter.
+			if candidate == nil {
+				continue
+			}
+			s.logger.Printf("[INFO] autopilot: Promoting server %q to replace the voter in redundancy zone %q", candidate.ID, name)
+			if err := s.promoteVoter(candidate.Server); err != nil {
+				return err
+			}
+			keep = candidate
+			promoted = true
+		}
+
+		for _, server := range zones[name] {
+			if server == keep || !server.isVoter() || server.Address == leader {
+				continue
+			}
+			if err := s.demoteVoter(server.Server); err != nil {
+				return err
+			}
+		}
+	}
+
+	if promoted {
+		s.triggerDeadServerCleanup()
+	}
+	return nil
+}
+
+// sortedServers returns the servers in the given map, sorted by ID so
+// changes are made in a predictable order.
+func sortedServers(servers map[string]*autopilotServer) []*autopilotServer {
+	var sorted []*autopilotServer
+	for _, server := range servers {
+		sorted = append(sorted, server)
+	}
+	sort.Sort(byAutopilotServerID(sorted))
+	return sorted
+}
+
+// byAutopilotServerID sorts servers by their Raft ID.
+type byAutopilotServerID []*autopilotServer
+
+func (s byAutopilotServerID) Len() int           { return len(s) }
+func (s byAutopilotServerID) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byAutopilotServerID) Less(i, j int) bool { return s[i].ID < s[j].ID }
+
+// redundancyZone returns the name of the zone the server belongs to. Servers
+// without a zone get a zone of their own, named after their ID.
+func redundancyZone(server *autopilotServer) string {
+	if server.zone == "" {
+		return "id:" + string(server.ID)
+	}
+	return "zone:" + server.zone
+}
+
+// promoteVoter gives the given server a vote.
+func (s *Server) promoteVoter(server raft.Server) error {
+	s.logger.Printf("[INFO] autopilot: Promoting server %q to voter", server.ID)
+	addFuture := s.raft.AddVoter(server.ID, server.Address, 0, 0)
+	if err := addFuture.Error(); err != nil {
+		return fmt.Errorf("failed to add raft peer: %v", err)
+	}
+	return nil
+}
+
+// demoteVoter removes the given server's vote, leaving it as a non-voter.
+func (s *Server) demoteVoter(server raft.Server) error {
+	s.logger.Printf("[INFO] autopilot: Demoting server %q to non-voter", server.ID)
+	demoteFuture := s.raft.DemoteVoter(server.ID, 0, 0)
+	if err := demoteFuture.Error(); err != nil {
+		return fmt.Errorf("failed to demote raft peer: %v", err)
+	}
+	return nil
+}
+
+// triggerDeadServerCleanup kicks off a check to remove dead servers, which is
+// done after adding new voters.
+func (s *Server) triggerDeadServerCleanup() {
+	select {
+	case s.autopilotRemoveDeadCh <- struct{}{}:
+	default:
+	}
+}
+
 func (s *Server) handlePromotions(voterCount int, promotions []raft.Server) (bool, error) {
 	if len(promotions) == 0 {
 		return false, nil
@@ -225,10 +588,7 @@ func (s *Server) handlePromotions(voterCount int, promotions []raft.Server) (boo
 
 	// If we added a new server, trigger a check to remove dead servers
 	if newServers {
-		select {
-		case s.autopilotRemoveDeadCh <- struct{}{}:
-		default:
-		}
+		s.triggerDeadServerCleanup()
 	}
 
 	return newServers, nil
@@ -5,6 +5,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/consul/testutil/retry"
 	"github.com/hashicorp/raft"
@@ -247,3 +248,253 @@ func TestAutopilot_PromoteNonVoter(t *testing.T) {
 		}
 	})
 }
+
+// testAutopilotVoters returns the IDs of the voters in the given server's
+// Raft configuration.
+func testAutopilotVoters(r *retry.R, s *Server) map[raft.ServerID]bool {
+	future := s.raft.GetConfiguration()
+	if err := future.Error(); err != nil {
+		r.Fatal(err)
+	}
+
+	voters := make(map[raft.ServerID]bool)
+	for _, server := range future.Configuration().Servers {
+		if server.Suffrage == raft.Voter {
+			voters[server.ID] = true
+		}
+	}
+	return voters
+}
+
+func TestAutopilot_RedundancyZones(t *testing.T) {
+	conf := func(zone string) func(c *Config) {
+		return func(c *Config) {
+			c.Datacenter = "dc1"
+			c.Bootstrap = zone == "zone1"
+			c.RaftConfig.ProtocolVersion = 3
+			c.AutopilotConfig.RedundancyZoneTag = "zone"
+			c.AutopilotConfig.ServerStabilizationTime = 200 * time.Millisecond
+			c.ServerHealthInterval = 100 * time.Millisecond
+			c.AutopilotInterval = 100 * time.Millisecond
+		}
+	}
+
+	dir1, s1 := testServerWithConfig(t, conf("zone1"))
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Start the rest of the servers and tag them with their zones before
+	// they join, the same as an agent would with -node-meta.
+	var servers []*Server
+	for _, zone := range []string{"zone2", "zone3", "zone3"} {
+		dir, s := testServerWithConfig(t, conf(zone))
+		defer os.RemoveAll(dir)
+		defer s.Shutdown()
+
+		req := structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       s.config.NodeName,
+			ID:         s.config.NodeID,
+			Address:    "127.0.0.1",
+			NodeMeta:   map[string]string{"zone": zone},
+		}
+		var out struct{}
+		if err := s1.RPC("Catalog.Register", &req, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		joinLAN(t, s, s1)
+		servers = append(servers, s)
+	}
+	s2, s3, s4 := servers[0], servers[1], servers[2]
+
+	// There should be one voter per zone, with a standby in zone3.
+	var active, standby *Server
+	retry.Run(t, func(r *retry.R) {
+		voters := testAutopilotVoters(r, s1)
+		if len(voters) != 3 || !voters[raft.ServerID(s1.config.NodeID)] || !voters[raft.ServerID(s2.config.NodeID)] {
+			r.Fatalf("bad: %v", voters)
+		}
+
+		id3, id4 := raft.ServerID(s3.config.NodeID), raft.ServerID(s4.config.NodeID)
+		switch {
+		case voters[id3] && !voters[id4]:
+			active, standby = s3, s4
+		case voters[id4] && !voters[id3]:
+			active, standby = s4, s3
+		default:
+			r.Fatalf("bad: %v", voters)
+		}
+	})
+
+	// Make sure the standby stays that way even once it's stable.
+	time.Sleep(3 * s1.config.AutopilotConfig.ServerStabilizationTime)
+	retry.Run(t, func(r *retry.R) {
+		voters := testAutopilotVoters(r, s1)
+		if len(voters) != 3 || voters[raft.ServerID(standby.config.NodeID)] {
+			r.Fatalf("bad: %v", voters)
+		}
+	})
+
+	// Kill the active server in zone3 and make sure the standby takes over.
+	active.Shutdown()
+	retry.Run(t, func(r *retry.R) {
+		voters := testAutopilotVoters(r, s1)
+		if len(voters) != 3 || !voters[raft.ServerID(standby.config.NodeID)] {
+			r.Fatalf("bad: %v", voters)
+		}
+	})
+}
+
+func TestAutopilot_UpgradeMigration(t *testing.T) {
+	conf := func(build string, bootstrap bool) func(c *Config) {
+		return func(c *Config) {
+			c.Datacenter = "dc1"
+			c.Bootstrap = bootstrap
+			c.Build = build
+			c.RaftConfig.ProtocolVersion = 3
+			c.AutopilotConfig.ServerStabilizationTime = 200 * time.Millisecond
+			c.ServerHealthInterval = 100 * time.Millisecond
+			c.AutopilotInterval = 100 * time.Millisecond
+		}
+	}
+
+	dir1, s1 := testServerWithConfig(t, conf("0.8.0", true))
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	var dirs []string
+	defer func() {
+		for _, dir := range dirs {
+			os.RemoveAll(dir)
+		}
+	}()
+	start := func(build string) *Server {
+		dir, s := testServerWithConfig(t, conf(build, false))
+		dirs = append(dirs, dir)
+		joinLAN(t, s, s1)
+		return s
+	}
+
+	// Bring up a cluster of three old servers.
+	s2 := start("0.8.0")
+	defer s2.Shutdown()
+	s3 := start("0.8.0")
+	defer s3.Shutdown()
+	retry.Run(t, func(r *retry.R) {
+		if voters := testAutopilotVoters(r, s1); len(voters) != 3 {
+			r.Fatalf("bad: %v", voters)
+		}
+	})
+
+	// A single new server should be held back as a non-voter, even once
+	// it's stable.
+	s4 := start("0.8.1")
+	defer s4.Shutdown()
+	retry.Run(t, func(r *retry.R) {
+		health := s1.getServerHealth(string(s4.config.NodeID))
+		if !health.IsStable(time.Now(), s1.config.AutopilotConfig) {
+			r.Fatalf("bad: %v", health)
+		}
+	})
+	time.Sleep(3 * s1.config.AutopilotConfig.ServerStabilizationTime)
+	retry.Run(t, func(r *retry.R) {
+		voters := testAutopilotVoters(r, s1)
+		if len(voters) != 3 || voters[raft.ServerID(s4.config.NodeID)] {
+			r.Fatalf("bad: %v", voters)
+		}
+	})
+
+	// Once there are as many new servers as old voters, the new servers
+	// should take over all the votes, including the leader's.
+	s5 := start("0.8.1")
+	defer s5.Shutdown()
+	s6 := start("0.8.1")
+	defer s6.Shutdown()
+	retry.Run(t, func(r *retry.R) {
+		voters := testAutopilotVoters(r, s4)
+		if len(voters) != 3 {
+			r.Fatalf("bad: %v", voters)
+		}
+		for _, s := range []*Server{s4, s5, s6} {
+			if !voters[raft.ServerID(s.config.NodeID)] {
+				r.Fatalf("bad: %v", voters)
+			}
+		}
+	})
+}
+
+func TestAutopilot_ReadReplica(t *testing.T) {
+	conf := func(bootstrap, nonVoter bool) func(c *Config) {
+		return func(c *Config) {
+			c.Datacenter = "dc1"
+			c.Bootstrap = bootstrap
+			c.NonVoter = nonVoter
+			c.RaftConfig.ProtocolVersion = 3
+			c.AutopilotConfig.ServerStabilizationTime = 200 * time.Millisecond
+			c.ServerHealthInterval = 100 * time.Millisecond
+			c.AutopilotInterval = 100 * time.Millisecond
+		}
+	}
+
+	dir1, s1 := testServerWithConfig(t, conf(true, false))
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	dir2, s2 := testServerWithConfig(t, conf(false, false))
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	joinLAN(t, s2, s1)
+
+	dir3, s3 := testServerWithConfig(t, conf(false, false))
+	defer os.RemoveAll(dir3)
+	defer s3.Shutdown()
+	joinLAN(t, s3, s1)
+
+	dir4, s4 := testServerWithConfig(t, conf(false, true))
+	defer os.RemoveAll(dir4)
+	defer s4.Shutdown()
+	joinLAN(t, s4, s1)
+
+	// The regular servers should be promoted, and the read replica should
+	// stay a non-voter even once it's stable.
+	retry.Run(t, func(r *retry.R) {
+		if voters := testAutopilotVoters(r, s1); len(voters) != 3 {
+			r.Fatalf("bad: %v", voters)
+		}
+		health := s1.getServerHealth(string(s4.config.NodeID))
+		if !health.IsStable(time.Now(), s1.config.AutopilotConfig) {
+			r.Fatalf("bad: %v", health)
+		}
+	})
+	time.Sleep(3 * s1.config.AutopilotConfig.ServerStabilizationTime)
+	retry.Run(t, func(r *retry.R) {
+		voters := testAutopilotVoters(r, s1)
+		if len(voters) != 3 || voters[raft.ServerID(s4.config.NodeID)] {
+			r.Fatalf("bad: %v", voters)
+		}
+	})
+
+	// The read replica should still have all the data.
+	arg := structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+	}
+	var out struct{}
+	if err := s1.RPC("Catalog.Register", &arg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	retry.Run(t, func(r *retry.R) {
+		_, node, err := s4.fsm.State().GetNode("foo")
+		if err != nil {
+			r.Fatal(err)
+		}
+		if node == nil {
+			r.Fatal("missing node")
+		}
+	})
+}
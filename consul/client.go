@@ -15,6 +15,7 @@ import (
 	"github.com/hashicorp/consul/consul/servers"
 	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/consul/lib"
+	"github.com/hashicorp/consul/tlsutil"
 	"github.com/hashicorp/serf/coordinate"
 	"github.com/hashicorp/serf/serf"
 )
@@ -75,6 +76,10 @@ type Client struct {
 	// which contains all the DC nodes
 	serf *serf.Serf
 
+	// tlsConfigurator holds the TLS material used for outgoing
+	// connections, and is used to reload it.
+	tlsConfigurator *tlsutil.Configurator
+
 	shutdown     bool
 	shutdownCh   chan struct{}
 	shutdownLock sync.Mutex
@@ -103,11 +108,15 @@ func NewClient(config *Config) (*Client, error) {
 		config.LogOutput = os.Stderr
 	}
 
-	// Create the tls Wrapper
-	tlsWrap, err := config.tlsConfig().OutgoingTLSWrapper()
+	// Create the tls Wrapper. Clients don't serve RPC, so incoming
+	// verification doesn't apply here.
+	tlsConf := config.tlsConfig()
+	tlsConf.VerifyIncoming = false
+	tlsConfigurator, err := tlsutil.NewConfigurator(tlsConf)
 	if err != nil {
 		return nil, err
 	}
+	tlsWrap := tlsConfigurator.OutgoingTLSWrapper()
 
 	// Create a logger
 	logger := log.New(config.LogOutput, "", log.LstdFlags)
@@ -119,6 +128,8 @@ func NewClient(config *Config) (*Client, error) {
 		eventCh:    make(chan serf.Event, serfEventBacklog),
 		logger:     logger,
 		shutdownCh: make(chan struct{}),
+
+		tlsConfigurator: tlsConfigurator,
 	}
 
 	// Start lan event handlers before lan Serf setup to prevent deadlock
@@ -234,6 +245,12 @@ func (c *Client) Encrypted() bool {
 	return c.serf.EncryptionEnabled()
 }
 
+// ReloadTLS re-reads the configured CA, certificate and key files. New
+// connections use the reloaded material while existing ones are left alone.
+func (c *Client) ReloadTLS() error {
+	return c.tlsConfigurator.Reload()
+}
+
 // lanEventHandler is used to handle events from the lan Serf cluster
 func (c *Client) lanEventHandler() {
 	var numQueuedEvents int
@@ -328,7 +345,14 @@ func (c *Client) localEvent(event serf.UserEvent) {
 
 // RPC is used to forward an RPC call to a consul server, or fail if no servers
 func (c *Client) RPC(method string, args interface{}, reply interface{}) error {
-	server := c.servers.FindServer()
+	// Stale reads can be answered by any server, so send them to a read
+	// replica if there is one.
+	var server *agent.Server
+	if info, ok := args.(structs.RPCInfo); ok && info.IsRead() && info.AllowStaleRead() {
+		server = c.servers.FindReadServer()
+	} else {
+		server = c.servers.FindServer()
+	}
 	if server == nil {
 		return structs.ErrNoServers
 	}
@@ -81,10 +81,16 @@ type Config struct {
 	// RaftConfig is the configuration used for Raft in the local DC
 	RaftConfig *raft.Config
 
-	// (Enterprise-only) NonVoter is used to prevent this server from being added
-	// as a voting member of the Raft cluster.
+	// NonVoter is used to make this server a read replica. It will never be
+	// added as a voting member of the Raft cluster, but still receives the
+	// Raft log and serves stale reads, and clients prefer it for those.
 	NonVoter bool
 
+	// FollowerReads lets this server answer non-stale reads itself when it's
+	// not the leader. It gets the leader's read index and waits for its own
+	// state to catch up before running the read, rather than forwarding it.
+	FollowerReads bool
+
 	// RPCAddr is the RPC address used by Consul. This should be reachable
 	// by the WAN and LAN
 	RPCAddr *net.TCPAddr
@@ -270,6 +276,16 @@ type Config struct {
 	// Minimum Session TTL
 	SessionTTLMin time.Duration
 
+	// DurableEventRetention is how long durable user events are kept in the
+	// state store so that agents can catch up on ones they missed.
+	DurableEventRetention time.Duration
+
+	// SnapshotEncryptionKey is used to encrypt snapshots taken through the
+	// snapshot endpoint, and to decrypt encrypted snapshots being restored.
+	// Unencrypted snapshots can always be restored. If this is nil then
+	// snapshots are not encrypted.
+	SnapshotEncryptionKey []byte
+
 	// ServerUp callback can be used to trigger a notification that
 	// a Consul server is now up and known about.
 	ServerUp func()
@@ -369,6 +385,7 @@ func DefaultConfig() *Config {
 		TombstoneTTL:             15 * time.Minute,
 		TombstoneTTLGranularity:  30 * time.Second,
 		SessionTTLMin:            10 * time.Second,
+		DurableEventRetention:    72 * time.Hour,
 
 		// These are tuned to provide a total throughput of 128 updates
 		// per second. If you update these, you should update the client-
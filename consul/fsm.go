@@ -43,8 +43,8 @@ type consulSnapshot struct {
 	state *state.Snapshot
 }
 
-// snapshotHeader is the first entry in our snapshot
-type snapshotHeader struct {
+// SnapshotHeader is the first entry in our snapshot
+type SnapshotHeader struct {
 	// LastIndex is the last index that affects the data.
 	// This is used when we do the restore for watchers.
 	LastIndex uint64
@@ -107,6 +107,10 @@ func (c *consulFSM) Apply(log *raft.Log) interface{} {
 		return c.applyTxn(buf[1:], log.Index)
 	case structs.AutopilotRequestType:
 		return c.applyAutopilotUpdate(buf[1:], log.Index)
+	case structs.DurableEventRequestType:
+		return c.applyDurableEvent(buf[1:], log.Index)
+	case structs.DurableEventAckRequestType:
+		return c.applyDurableEventAck(buf[1:], log.Index)
 	default:
 		if ignoreUnknown {
 			c.logger.Printf("[WARN] consul.fsm: ignoring unknown message type (%d), upgrade to newer version", msgType)
@@ -325,6 +329,24 @@ func (c *consulFSM) applyAutopilotUpdate(buf []byte, index uint64) interface{} {
 	return c.state.AutopilotSetConfig(index, &req.Config)
 }
 
+func (c *consulFSM) applyDurableEvent(buf []byte, index uint64) interface{} {
+	var req structs.DurableEventRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+	defer metrics.MeasureSince([]string{"consul", "fsm", "durable_event"}, time.Now())
+	return c.state.DurableEventCreate(index, req.Event)
+}
+
+func (c *consulFSM) applyDurableEventAck(buf []byte, index uint64) interface{} {
+	var req structs.DurableEventAckRequest
+	if err := structs.Decode(buf, &req); err != nil {
+		panic(fmt.Errorf("failed to decode request: %v", err))
+	}
+	defer metrics.MeasureSince([]string{"consul", "fsm", "durable_event_ack"}, time.Now())
+	return c.state.DurableEventAck(index, &req.Ack)
+}
+
 func (c *consulFSM) Snapshot() (raft.FSMSnapshot, error) {
 	defer func(start time.Time) {
 		c.logger.Printf("[INFO] consul.fsm: snapshot created in %v", time.Now().Sub(start))
@@ -348,28 +370,9 @@ func (c *consulFSM) Restore(old io.ReadCloser) error {
 	restore := stateNew.Restore()
 	defer restore.Abort()
 
-	// Create a decoder
-	dec := codec.NewDecoder(old, msgpackHandle)
-
-	// Read in the header
-	var header snapshotHeader
-	if err := dec.Decode(&header); err != nil {
-		return err
-	}
-
 	// Populate the new state
-	msgType := make([]byte, 1)
-	for {
-		// Read the message type
-		_, err := old.Read(msgType)
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
-		}
-
-		// Decode
-		switch structs.MessageType(msgType[0]) {
+	handler := func(header *SnapshotHeader, msg structs.MessageType, dec *codec.Decoder) error {
+		switch msg {
 		case structs.RegisterRequestType:
 			var req structs.RegisterRequest
 			if err := dec.Decode(&req); err != nil {
@@ -451,9 +454,31 @@ func (c *consulFSM) Restore(old io.ReadCloser) error {
 				return err
 			}
 
+		case structs.DurableEventRequestType:
+			var req structs.DurableEvent
+			if err := dec.Decode(&req); err != nil {
+				return err
+			}
+			if err := restore.DurableEvent(&req); err != nil {
+				return err
+			}
+
+		case structs.DurableEventAckRequestType:
+			var req structs.DurableEventAck
+			if err := dec.Decode(&req); err != nil {
+				return err
+			}
+			if err := restore.DurableEventAck(&req); err != nil {
+				return err
+			}
+
 		default:
-			return fmt.Errorf("Unrecognized msg type: %v", msgType)
+			return fmt.Errorf("Unrecognized msg type: %v", msg)
 		}
+		return nil
+	}
+	if err := ReadSnapshot(old, handler); err != nil {
+		return err
 	}
 
 	restore.Commit()
@@ -472,6 +497,39 @@ func (c *consulFSM) Restore(old io.ReadCloser) error {
 	return nil
 }
 
+// ReadSnapshot decodes a snapshot written by consulSnapshot.Persist from the
+// given reader. The handler is called for each record with the message type
+// already consumed, and must use the given decoder to decode the record body.
+// This allows tools to walk the contents of a snapshot without restoring it
+// into a state store.
+func ReadSnapshot(r io.Reader, handler func(header *SnapshotHeader, msg structs.MessageType, dec *codec.Decoder) error) error {
+	// Create a decoder
+	dec := codec.NewDecoder(r, msgpackHandle)
+
+	// Read in the header
+	var header SnapshotHeader
+	if err := dec.Decode(&header); err != nil {
+		return err
+	}
+
+	// Read in the records
+	msgType := make([]byte, 1)
+	for {
+		// Read the message type
+		_, err := r.Read(msgType)
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		// Decode
+		if err := handler(&header, structs.MessageType(msgType[0]), dec); err != nil {
+			return err
+		}
+	}
+}
+
 func (s *consulSnapshot) Persist(sink raft.SnapshotSink) error {
 	defer metrics.MeasureSince([]string{"consul", "fsm", "persist"}, time.Now())
 
@@ -479,7 +537,7 @@ func (s *consulSnapshot) Persist(sink raft.SnapshotSink) error {
 	encoder := codec.NewEncoder(sink, msgpackHandle)
 
 	// Write the header
-	header := snapshotHeader{
+	header := SnapshotHeader{
 		LastIndex: s.state.LastIndex(),
 	}
 	if err := encoder.Encode(&header); err != nil {
@@ -522,6 +580,11 @@ func (s *consulSnapshot) Persist(sink raft.SnapshotSink) error {
 		return err
 	}
 
+	if err := s.persistDurableEvents(sink, encoder); err != nil {
+		sink.Cancel()
+		return err
+	}
+
 	return nil
 }
 
@@ -700,6 +763,34 @@ func (s *consulSnapshot) persistAutopilot(sink raft.SnapshotSink,
 	return nil
 }
 
+func (s *consulSnapshot) persistDurableEvents(sink raft.SnapshotSink,
+	encoder *codec.Encoder) error {
+	events, err := s.state.DurableEvents()
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		sink.Write([]byte{byte(structs.DurableEventRequestType)})
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+
+	acks, err := s.state.DurableEventAcks()
+	if err != nil {
+		return err
+	}
+
+	for _, ack := range acks {
+		sink.Write([]byte{byte(structs.DurableEventAckRequestType)})
+		if err := encoder.Encode(ack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (s *consulSnapshot) Release() {
 	s.state.Close()
 }
@@ -435,6 +435,23 @@ func TestFSM_SnapshotRestore(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 
+	durableEvent := &structs.DurableEvent{
+		ID:         generateUUID(),
+		Name:       "deploy",
+		Payload:    []byte("hello"),
+		CreateTime: time.Now(),
+		Expires:    time.Now().Add(time.Hour),
+	}
+	if err := fsm.state.DurableEventCreate(16, durableEvent); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := fsm.state.DurableEventAck(17, &structs.DurableEventAck{
+		EventID: durableEvent.ID,
+		Node:    "foo",
+	}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
 	// Snapshot
 	snap, err := fsm.Snapshot()
 	if err != nil {
@@ -585,6 +602,21 @@ func TestFSM_SnapshotRestore(t *testing.T) {
 		t.Fatalf("bad: %#v, %#v", restoredConf, autopilotConf)
 	}
 
+	// Verify durable events are restored.
+	_, events, acks, err := fsm2.state.DurableEvents(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(events) != 1 || events[0].ID != durableEvent.ID ||
+		string(events[0].Payload) != "hello" ||
+		!events[0].Expires.Equal(durableEvent.Expires) ||
+		events[0].CreateIndex != 16 {
+		t.Fatalf("bad: %#v", events)
+	}
+	if len(acks) != 1 || acks[0].EventID != durableEvent.ID || acks[0].Node != "foo" {
+		t.Fatalf("bad: %#v", acks)
+	}
+
 	// Snapshot
 	snap, err = fsm2.Snapshot()
 	if err != nil {
@@ -1396,6 +1428,61 @@ func TestFSM_Autopilot(t *testing.T) {
 	}
 }
 
+func TestFSM_DurableEvent(t *testing.T) {
+	fsm, err := NewFSM(nil, os.Stderr)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Record an event.
+	req := structs.DurableEventRequest{
+		Datacenter: "dc1",
+		Event: &structs.DurableEvent{
+			ID:         generateUUID(),
+			Name:       "deploy",
+			CreateTime: time.Now(),
+			Expires:    time.Now().Add(time.Hour),
+		},
+	}
+	buf, err := structs.Encode(structs.DurableEventRequestType, req)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp := fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Acknowledge it.
+	ack := structs.DurableEventAckRequest{
+		Datacenter: "dc1",
+		Ack: structs.DurableEventAck{
+			EventID: req.Event.ID,
+			Node:    "foo",
+		},
+	}
+	buf, err = structs.Encode(structs.DurableEventAckRequestType, ack)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	resp = fsm.Apply(makeLog(buf))
+	if resp != nil {
+		t.Fatalf("resp: %v", resp)
+	}
+
+	// Verify it's in the state store.
+	_, events, acks, err := fsm.state.DurableEvents(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(events) != 1 || events[0].ID != req.Event.ID || events[0].Name != "deploy" {
+		t.Fatalf("bad: %#v", events)
+	}
+	if len(acks) != 1 || acks[0].Node != "foo" {
+		t.Fatalf("bad: %#v", acks)
+	}
+}
+
 func TestFSM_IgnoreUnknown(t *testing.T) {
 	fsm, err := NewFSM(nil, os.Stderr)
 	if err != nil {
@@ -2,13 +2,20 @@ package consul
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/consul/consul/state"
 	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/go-memdb"
+	"github.com/hashicorp/go-version"
 	"github.com/hashicorp/serf/serf"
 )
 
+// minDurableEventVersion is the version all the servers need to be running
+// before durable events can be recorded, since older servers can't apply the
+// Raft entries for them.
+var minDurableEventVersion = version.Must(version.NewVersion("0.8.4"))
+
 // Internal endpoint is used to query the miscellaneous info that
 // does not necessarily fit into the other systems. It is also
 // used to hold undocumented APIs that users should not rely on.
@@ -81,6 +88,36 @@ func (m *Internal) EventFire(args *structs.EventFireRequest,
 	// Set the query meta data
 	m.srv.setQueryMeta(&reply.QueryMeta)
 
+	// Record durable events before gossiping them, so that agents that
+	// miss the gossip can catch up later.
+	if args.Durable {
+		if args.ID == "" {
+			return fmt.Errorf("Durable events must have an ID")
+		}
+		if err := m.srv.checkDurableEventVersion(); err != nil {
+			return err
+		}
+		now := time.Now()
+		req := structs.DurableEventRequest{
+			Datacenter: args.Datacenter,
+			Event: &structs.DurableEvent{
+				ID:         args.ID,
+				Name:       args.Name,
+				Payload:    args.Payload,
+				CreateTime: now,
+				Expires:    now.Add(m.srv.config.DurableEventRetention),
+			},
+		}
+		resp, err := m.srv.raftApply(structs.DurableEventRequestType, &req)
+		if err != nil {
+			m.srv.logger.Printf("[ERR] consul: Recording durable event failed: %v", err)
+			return err
+		}
+		if respErr, ok := resp.(error); ok {
+			return respErr
+		}
+	}
+
 	// Add the consul prefix to the event name
 	eventName := userEventName(args.Name)
 
@@ -88,6 +125,97 @@ func (m *Internal) EventFire(args *structs.EventFireRequest,
 	return m.srv.serfLAN.UserEvent(eventName, args.Payload, false)
 }
 
+// EventAck is used by agents to acknowledge that they've processed a durable
+// user event.
+func (m *Internal) EventAck(args *structs.DurableEventAckRequest,
+	reply *struct{}) error {
+	if done, err := m.srv.forward("Internal.EventAck", args, args, reply); done {
+		return err
+	}
+
+	// Agents need node write privileges to ack on behalf of their node.
+	acl, err := m.srv.resolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+	if acl != nil && !acl.NodeWrite(args.Ack.Node) {
+		return errPermissionDenied
+	}
+	if err := m.srv.checkDurableEventVersion(); err != nil {
+		return err
+	}
+
+	resp, err := m.srv.raftApply(structs.DurableEventAckRequestType, args)
+	if err != nil {
+		m.srv.logger.Printf("[ERR] consul: Recording durable event ack failed: %v", err)
+		return err
+	}
+	if respErr, ok := resp.(error); ok {
+		return respErr
+	}
+	return nil
+}
+
+// checkDurableEventVersion returns an error if any of the servers are too old
+// to record durable events and their acknowledgements.
+func (s *Server) checkDurableEventVersion() error {
+	if !ServersMeetMinimumVersion(s.LANMembers(), minDurableEventVersion) {
+		return fmt.Errorf("Durable events require all servers to be running version %s or later",
+			minDurableEventVersion.String())
+	}
+	return nil
+}
+
+// DurableEventList returns the durable user events that are still within
+// their retention window, along with the nodes that have acknowledged them.
+func (m *Internal) DurableEventList(args *structs.DCSpecificRequest,
+	reply *structs.IndexedDurableEvents) error {
+	if done, err := m.srv.forward("Internal.DurableEventList", args, args, reply); done {
+		return err
+	}
+
+	acl, err := m.srv.resolveToken(args.Token)
+	if err != nil {
+		return err
+	}
+
+	return m.srv.blockingQuery(
+		&args.QueryOptions,
+		&reply.QueryMeta,
+		func(ws memdb.WatchSet, state *state.Store) error {
+			index, events, acks, err := state.DurableEvents(ws)
+			if err != nil {
+				return err
+			}
+
+			// Drop any events that have expired but haven't been
+			// pruned yet, or that the token can't read.
+			now := time.Now()
+			keep := make(map[string]struct{})
+			reply.Events = nil
+			for _, event := range events {
+				if event.Expires.Before(now) {
+					continue
+				}
+				if acl != nil && !acl.EventRead(event.Name) {
+					m.srv.logger.Printf("[DEBUG] consul: dropping event %q from result due to ACLs", event.Name)
+					continue
+				}
+				keep[event.ID] = struct{}{}
+				reply.Events = append(reply.Events, event)
+			}
+			reply.Acks = nil
+			for _, ack := range acks {
+				if _, ok := keep[ack.EventID]; ok {
+					reply.Acks = append(reply.Acks, ack)
+				}
+			}
+
+			reply.Index = index
+			return nil
+		})
+}
+
 // KeyringOperation will query the WAN and LAN gossip keyrings of all nodes.
 func (m *Internal) KeyringOperation(
 	args *structs.KeyringRequest,
@@ -3,12 +3,14 @@ package consul
 import (
 	"encoding/base64"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/consul/testrpc"
+	"github.com/hashicorp/consul/testutil/retry"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
 )
 
@@ -370,3 +372,176 @@ func TestInternal_EventFire_Token(t *testing.T) {
 		t.Fatalf("err: %s", err)
 	}
 }
+
+func TestInternal_DurableEvents(t *testing.T) {
+	dir, srv := testServerWithConfig(t, func(c *Config) {
+		c.Build = "0.8.4"
+	})
+	defer os.RemoveAll(dir)
+	defer srv.Shutdown()
+
+	codec := rpcClient(t, srv)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, srv.RPC, "dc1")
+
+	// Durable events need an ID.
+	event := structs.EventFireRequest{
+		Name:       "foo",
+		Datacenter: "dc1",
+		Payload:    []byte("hello"),
+		Durable:    true,
+	}
+	err := msgpackrpc.CallWithCodec(codec, "Internal.EventFire", &event, nil)
+	if err == nil || !strings.Contains(err.Error(), "must have an ID") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	event.ID = "9f0c3ad6-6cb3-4e0b-a0b5-d2a6b8f1c1e2"
+	if err := msgpackrpc.CallWithCodec(codec, "Internal.EventFire", &event, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	ack := structs.DurableEventAckRequest{
+		Datacenter: "dc1",
+		Ack: structs.DurableEventAck{
+			EventID: event.ID,
+			Node:    "node1",
+		},
+	}
+	if err := msgpackrpc.CallWithCodec(codec, "Internal.EventAck", &ack, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	req := structs.DCSpecificRequest{Datacenter: "dc1"}
+	var reply structs.IndexedDurableEvents
+	if err := msgpackrpc.CallWithCodec(codec, "Internal.DurableEventList", &req, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if reply.Index == 0 {
+		t.Fatalf("bad: %d", reply.Index)
+	}
+	if len(reply.Events) != 1 {
+		t.Fatalf("bad: %#v", reply.Events)
+	}
+	e := reply.Events[0]
+	if e.ID != event.ID || e.Name != "foo" || string(e.Payload) != "hello" {
+		t.Fatalf("bad: %#v", e)
+	}
+	if got, want := e.Expires.Sub(e.CreateTime), srv.config.DurableEventRetention; got != want {
+		t.Fatalf("got %v want %v", got, want)
+	}
+	if len(reply.Acks) != 1 || reply.Acks[0].Node != "node1" {
+		t.Fatalf("bad: %#v", reply.Acks)
+	}
+}
+
+func TestInternal_DurableEvents_OldServers(t *testing.T) {
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.Build = "0.8.4"
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	// Join a server that's still on an older version.
+	dir2, s2 := testServerDCBootstrap(t, "dc1", false)
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	joinLAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+	retry.Run(t, func(r *retry.R) {
+		if got, want := len(s1.LANMembers()), 2; got != want {
+			r.Fatalf("got %d LAN members want %d", got, want)
+		}
+	})
+
+	// Durable events and acks should be refused until it's upgraded.
+	event := structs.EventFireRequest{
+		Name:       "foo",
+		Datacenter: "dc1",
+		Durable:    true,
+		ID:         "9f0c3ad6-6cb3-4e0b-a0b5-d2a6b8f1c1e2",
+	}
+	err := msgpackrpc.CallWithCodec(codec, "Internal.EventFire", &event, nil)
+	if err == nil || !strings.Contains(err.Error(), "require all servers to be running version 0.8.4") {
+		t.Fatalf("bad: %v", err)
+	}
+	ack := structs.DurableEventAckRequest{
+		Datacenter: "dc1",
+		Ack: structs.DurableEventAck{
+			EventID: event.ID,
+			Node:    "node1",
+		},
+	}
+	err = msgpackrpc.CallWithCodec(codec, "Internal.EventAck", &ack, nil)
+	if err == nil || !strings.Contains(err.Error(), "require all servers to be running version 0.8.4") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Plain events still work.
+	event.Durable = false
+	if err := msgpackrpc.CallWithCodec(codec, "Internal.EventFire", &event, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestInternal_DurableEvents_ACL(t *testing.T) {
+	dir, srv := testServerWithConfig(t, func(c *Config) {
+		c.Build = "0.8.4"
+		c.ACLDatacenter = "dc1"
+		c.ACLMasterToken = "root"
+		c.ACLDownPolicy = "deny"
+		c.ACLDefaultPolicy = "deny"
+	})
+	defer os.RemoveAll(dir)
+	defer srv.Shutdown()
+
+	codec := rpcClient(t, srv)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, srv.RPC, "dc1")
+
+	event := structs.EventFireRequest{
+		Name:         "foo",
+		Datacenter:   "dc1",
+		Durable:      true,
+		ID:           "9f0c3ad6-6cb3-4e0b-a0b5-d2a6b8f1c1e2",
+		QueryOptions: structs.QueryOptions{Token: "root"},
+	}
+	if err := msgpackrpc.CallWithCodec(codec, "Internal.EventFire", &event, nil); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Acks need node write privileges.
+	ack := structs.DurableEventAckRequest{
+		Datacenter: "dc1",
+		Ack: structs.DurableEventAck{
+			EventID: event.ID,
+			Node:    "node1",
+		},
+	}
+	err := msgpackrpc.CallWithCodec(codec, "Internal.EventAck", &ack, nil)
+	if err == nil || err.Error() != permissionDenied {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Events are filtered without event read privileges.
+	req := structs.DCSpecificRequest{Datacenter: "dc1"}
+	var reply structs.IndexedDurableEvents
+	if err := msgpackrpc.CallWithCodec(codec, "Internal.DurableEventList", &req, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(reply.Events) != 0 {
+		t.Fatalf("bad: %#v", reply.Events)
+	}
+
+	req.Token = "root"
+	if err := msgpackrpc.CallWithCodec(codec, "Internal.DurableEventList", &req, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(reply.Events) != 1 {
+		t.Fatalf("bad: %#v", reply.Events)
+	}
+}
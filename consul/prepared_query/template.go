@@ -17,6 +17,22 @@ func IsTemplate(query *structs.PreparedQuery) bool {
 	return query.Template.Type != ""
 }
 
+// RenderContext has information about the request a template is being
+// rendered for, beyond the name that was looked up.
+type RenderContext struct {
+	// Source is the node the results are being sorted near, if any.
+	Source structs.QuerySource
+
+	// SourceMeta is the node metadata for Source, if it is known.
+	SourceMeta map[string]string
+
+	// Agent is the agent that initiated the request.
+	Agent structs.QuerySource
+
+	// AgentMeta is the node metadata for Agent, if it is known.
+	AgentMeta map[string]string
+}
+
 // CompiledTemplate is an opaque object that can be used later to render a
 // prepared query template.
 type CompiledTemplate struct {
@@ -84,21 +100,42 @@ func Compile(query *structs.PreparedQuery) (*CompiledTemplate, error) {
 	// prefix it will be expected to run with. The results might not make
 	// sense and create a valid service to lookup, but it should render
 	// without any errors.
-	if _, err = ct.Render(ct.query.Name); err != nil {
+	if _, err = ct.Render(ct.query.Name, nil); err != nil {
 		return nil, err
 	}
 
 	return ct, nil
 }
 
+// metaFunc returns a HIL function that looks up a key in the given metadata.
+// It can't fail at run time, and returns an empty string for missing keys.
+func metaFunc(meta map[string]string) ast.Function {
+	return ast.Function{
+		ArgTypes:   []ast.Type{ast.TypeString},
+		ReturnType: ast.TypeString,
+		Variadic:   false,
+		Callback: func(inputs []interface{}) (interface{}, error) {
+			key, ok := inputs[0].(string)
+			if ok {
+				return meta[key], nil
+			}
+			return "", nil
+		},
+	}
+}
+
 // Render takes a compiled template and renders it for the given name. For
 // example, if the user looks up foobar.query.consul via DNS then we will call
-// this function with "foobar" on the compiled template.
-func (ct *CompiledTemplate) Render(name string) (*structs.PreparedQuery, error) {
+// this function with "foobar" on the compiled template. The render context has
+// information about where the request came from, and can be nil.
+func (ct *CompiledTemplate) Render(name string, rc *RenderContext) (*structs.PreparedQuery, error) {
 	// Make it "safe" to render a default structure.
 	if ct == nil {
 		return nil, fmt.Errorf("Cannot render an uncompiled template")
 	}
+	if rc == nil {
+		rc = &RenderContext{}
+	}
 
 	// Start with a fresh, detached copy of the original so we don't disturb
 	// the prototype.
@@ -151,9 +188,27 @@ func (ct *CompiledTemplate) Render(name string) (*structs.PreparedQuery, error)
 					Type:  ast.TypeString,
 					Value: strings.TrimPrefix(name, query.Name),
 				},
+				"source.node": ast.Variable{
+					Type:  ast.TypeString,
+					Value: rc.Source.Node,
+				},
+				"source.datacenter": ast.Variable{
+					Type:  ast.TypeString,
+					Value: rc.Source.Datacenter,
+				},
+				"agent.node": ast.Variable{
+					Type:  ast.TypeString,
+					Value: rc.Agent.Node,
+				},
+				"agent.datacenter": ast.Variable{
+					Type:  ast.TypeString,
+					Value: rc.Agent.Datacenter,
+				},
 			},
 			FuncMap: map[string]ast.Function{
-				"match": match,
+				"match":       match,
+				"source_meta": metaFunc(rc.SourceMeta),
+				"agent_meta":  metaFunc(rc.AgentMeta),
 			},
 		},
 	}
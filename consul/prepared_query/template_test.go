@@ -82,7 +82,7 @@ func renderBench(b *testing.B, query *structs.PreparedQuery) {
 	}
 
 	for i := 0; i < b.N; i++ {
-		_, err := compiled.Render("hello-bench-mark")
+		_, err := compiled.Render("hello-bench-mark", nil)
 		if err != nil {
 			b.Fatalf("err: %v", err)
 		}
@@ -134,7 +134,7 @@ func TestTemplate_Compile(t *testing.T) {
 	}
 
 	// Do a sanity check render on it.
-	actual, err := ct.Render("hellothere")
+	actual, err := ct.Render("hellothere", nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -200,7 +200,7 @@ func TestTemplate_Render(t *testing.T) {
 			t.Fatalf("err: %v", err)
 		}
 
-		actual, err := ct.Render("unused")
+		actual, err := ct.Render("unused", nil)
 		if err != nil {
 			t.Fatalf("err: %v", err)
 		}
@@ -237,7 +237,7 @@ func TestTemplate_Render(t *testing.T) {
 
 	// Run a case that matches the regexp.
 	{
-		actual, err := ct.Render("hello-foo-bar-none")
+		actual, err := ct.Render("hello-foo-bar-none", nil)
 		if err != nil {
 			t.Fatalf("err: %v", err)
 		}
@@ -268,7 +268,7 @@ func TestTemplate_Render(t *testing.T) {
 
 	// Run a case that doesn't match the regexp
 	{
-		actual, err := ct.Render("hello-nope")
+		actual, err := ct.Render("hello-nope", nil)
 		if err != nil {
 			t.Fatalf("err: %v", err)
 		}
@@ -296,4 +296,98 @@ func TestTemplate_Render(t *testing.T) {
 			t.Fatalf("bad: %#v", actual)
 		}
 	}
+
+	// Try the variables and functions about where the request came from.
+	query = &structs.PreparedQuery{
+		Name: "",
+		Template: structs.QueryTemplateOptions{
+			Type:   structs.QueryTemplateTypeNamePrefixMatch,
+			Regexp: "^(.*?)-(.*?)-(.*)$",
+		},
+		Service: structs.ServiceQuery{
+			Service: "${match(1)}",
+			Near:    "${source.node}",
+			Failover: structs.QueryDatacenterOptions{
+				Datacenters: []string{"${agent.datacenter}", "${source.datacenter}"},
+			},
+			Tags: []string{"${match(2)}", "${agent.node}"},
+			NodeMeta: map[string]string{
+				"rack":  "${match(3)}",
+				"zone":  "${agent_meta(\"zone\")}",
+				"group": "${source_meta(\"group\")}",
+				"nope":  "${agent_meta(\"nope\")}",
+			},
+		},
+	}
+	ct, err = Compile(query)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Run a case with a full render context.
+	{
+		rc := &RenderContext{
+			Source:     structs.QuerySource{Datacenter: "dc2", Node: "foo"},
+			SourceMeta: map[string]string{"group": "blue"},
+			Agent:      structs.QuerySource{Datacenter: "dc1", Node: "bar"},
+			AgentMeta:  map[string]string{"zone": "us-east-1a"},
+		}
+		actual, err := ct.Render("redis-prod-r12", rc)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		expected := &structs.PreparedQuery{
+			Template: structs.QueryTemplateOptions{
+				Type:   structs.QueryTemplateTypeNamePrefixMatch,
+				Regexp: "^(.*?)-(.*?)-(.*)$",
+			},
+			Service: structs.ServiceQuery{
+				Service: "redis",
+				Near:    "foo",
+				Failover: structs.QueryDatacenterOptions{
+					Datacenters: []string{"dc1", "dc2"},
+				},
+				Tags: []string{"prod", "bar"},
+				NodeMeta: map[string]string{
+					"rack":  "r12",
+					"zone":  "us-east-1a",
+					"group": "blue",
+					"nope":  "",
+				},
+			},
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("bad: %#v", actual)
+		}
+	}
+
+	// A missing render context should render everything as blank.
+	{
+		actual, err := ct.Render("redis-prod-r12", nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		expected := &structs.PreparedQuery{
+			Template: structs.QueryTemplateOptions{
+				Type:   structs.QueryTemplateTypeNamePrefixMatch,
+				Regexp: "^(.*?)-(.*?)-(.*)$",
+			},
+			Service: structs.ServiceQuery{
+				Service: "redis",
+				Failover: structs.QueryDatacenterOptions{
+					Datacenters: []string{"", ""},
+				},
+				Tags: []string{"prod", ""},
+				NodeMeta: map[string]string{
+					"rack":  "r12",
+					"zone":  "",
+					"group": "",
+					"nope":  "",
+				},
+			},
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("bad: %#v", actual)
+		}
+	}
 }
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/armon/go-metrics"
+	"github.com/hashicorp/consul/consul/prepared_query"
 	"github.com/hashicorp/consul/consul/state"
 	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/go-memdb"
@@ -180,6 +181,25 @@ func parseService(svc *structs.ServiceQuery) error {
 		return fmt.Errorf("Bad NearestN '%d', must be >= 0", svc.Failover.NearestN)
 	}
 
+	// MinHealthy can be 0 which means "only fail over if there are no
+	// healthy nodes".
+	if svc.Failover.MinHealthy < 0 {
+		return fmt.Errorf("Bad MinHealthy '%d', must be >= 0", svc.Failover.MinHealthy)
+	}
+
+	// Targets give an explicit order, so they can't be mixed with the
+	// other ways of picking datacenters.
+	if len(svc.Failover.Targets) > 0 {
+		if svc.Failover.NearestN > 0 || len(svc.Failover.Datacenters) > 0 {
+			return fmt.Errorf("Failover Targets cannot be combined with NearestN or Datacenters")
+		}
+		for i, target := range svc.Failover.Targets {
+			if target.Datacenter == "" && target.Service == "" {
+				return fmt.Errorf("Failover target %d must provide a Datacenter or a Service", i)
+			}
+		}
+	}
+
 	// Make sure the metadata filters are valid
 	if err := structs.ValidateMetadata(svc.NodeMeta); err != nil {
 		return err
@@ -289,15 +309,17 @@ func (p *PreparedQuery) Explain(args *structs.PreparedQueryExecuteRequest,
 
 	// We have to do this ourselves since we are not doing a blocking RPC.
 	p.srv.setQueryMeta(&reply.QueryMeta)
-	if args.RequireConsistent {
-		if err := p.srv.consistentRead(); err != nil {
-			return err
-		}
+	if err := p.srv.checkConsistentRead(&args.QueryOptions); err != nil {
+		return err
 	}
 
 	// Try to locate the query.
 	state := p.srv.fsm.State()
-	_, query, err := state.PreparedQueryResolve(args.QueryIDOrName)
+	rc, err := p.renderContext(args)
+	if err != nil {
+		return err
+	}
+	_, query, err := state.PreparedQueryResolve(args.QueryIDOrName, rc)
 	if err != nil {
 		return err
 	}
@@ -321,9 +343,82 @@ func (p *PreparedQuery) Explain(args *structs.PreparedQueryExecuteRequest,
 	}
 
 	reply.Query = *(queries.Queries[0])
+	reply.Targets = p.explainTargets(query, args.QueryOptions)
 	return nil
 }
 
+// explainTargets runs the query's failover policy so operators can see which
+// target would serve the results and why. This mirrors Execute, but doesn't
+// need any of the sorting or limiting. Errors are reported in the explanation
+// rather than failing the whole Explain, since the query itself resolved fine.
+func (p *PreparedQuery) explainTargets(query *structs.PreparedQuery,
+	options structs.QueryOptions) []structs.QueryTargetExplanation {
+	var result structs.PreparedQueryExecuteResponse
+	err := p.execute(query, &result)
+	if err == nil {
+		token := options.Token
+		if query.Token != "" {
+			token = query.Token
+		}
+		err = p.srv.filterACL(token, &result.Nodes)
+	}
+	if err != nil {
+		return []structs.QueryTargetExplanation{
+			{
+				Datacenter: p.srv.config.Datacenter,
+				Service:    query.Service.Service,
+				Reason:     fmt.Sprintf("Query failed: %v", err),
+			},
+		}
+	}
+
+	wrapper := &queryServerWrapper{p.srv}
+	explanations, err := queryFailoverExplain(wrapper, query, 0, options, &result)
+	if err != nil {
+		explanations = append(explanations, structs.QueryTargetExplanation{
+			Reason: fmt.Sprintf("Failover failed: %v", err),
+		})
+	}
+	return explanations
+}
+
+// renderContext builds the information about where an execute request came
+// from that's made available to query templates. Node metadata is only known
+// for nodes in this datacenter.
+func (p *PreparedQuery) renderContext(args *structs.PreparedQueryExecuteRequest) (*prepared_query.RenderContext, error) {
+	rc := &prepared_query.RenderContext{
+		Source: args.Source,
+		Agent:  args.Agent,
+	}
+	if rc.Source.Datacenter == "" {
+		rc.Source.Datacenter = args.Agent.Datacenter
+	}
+	if rc.Source.Node == "_agent" {
+		rc.Source.Node = args.Agent.Node
+	}
+
+	state := p.srv.fsm.State()
+	lookup := func(qs structs.QuerySource) (map[string]string, error) {
+		if qs.Node == "" || qs.Datacenter != p.srv.config.Datacenter {
+			return nil, nil
+		}
+		_, node, err := state.GetNode(qs.Node)
+		if err != nil || node == nil {
+			return nil, err
+		}
+		return node.Meta, nil
+	}
+
+	var err error
+	if rc.SourceMeta, err = lookup(rc.Source); err != nil {
+		return nil, err
+	}
+	if rc.AgentMeta, err = lookup(rc.Agent); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
 // Execute runs a prepared query and returns the results. This will perform the
 // failover logic if no local results are available. This is typically called as
 // part of a DNS lookup, or when executing prepared queries from the HTTP API.
@@ -336,15 +431,17 @@ func (p *PreparedQuery) Execute(args *structs.PreparedQueryExecuteRequest,
 
 	// We have to do this ourselves since we are not doing a blocking RPC.
 	p.srv.setQueryMeta(&reply.QueryMeta)
-	if args.RequireConsistent {
-		if err := p.srv.consistentRead(); err != nil {
-			return err
-		}
+	if err := p.srv.checkConsistentRead(&args.QueryOptions); err != nil {
+		return err
 	}
 
 	// Try to locate the query.
 	state := p.srv.fsm.State()
-	_, query, err := state.PreparedQueryResolve(args.QueryIDOrName)
+	rc, err := p.renderContext(args)
+	if err != nil {
+		return err
+	}
+	_, query, err := state.PreparedQueryResolve(args.QueryIDOrName, rc)
 	if err != nil {
 		return err
 	}
@@ -414,21 +511,22 @@ func (p *PreparedQuery) Execute(args *structs.PreparedQueryExecuteRequest,
 		}
 	}
 
-	// Apply the limit if given.
-	if args.Limit > 0 && len(reply.Nodes) > args.Limit {
-		reply.Nodes = reply.Nodes[:args.Limit]
-	}
-
-	// In the happy path where we found some healthy nodes we go with that
-	// and bail out. Otherwise, we fail over and try remote DCs, as allowed
-	// by the query setup.
-	if len(reply.Nodes) == 0 {
+	// In the happy path where we found enough healthy nodes we go with
+	// that. Otherwise, we fail over and try the other targets, as allowed
+	// by the query setup. This is checked before applying the limit so a
+	// small limit doesn't trigger a failover by itself.
+	if len(reply.Nodes) < query.Service.Failover.HealthyThreshold() {
 		wrapper := &queryServerWrapper{p.srv}
 		if err := queryFailover(wrapper, query, args.Limit, args.QueryOptions, reply); err != nil {
 			return err
 		}
 	}
 
+	// Apply the limit if given.
+	if args.Limit > 0 && len(reply.Nodes) > args.Limit {
+		reply.Nodes = reply.Nodes[:args.Limit]
+	}
+
 	return nil
 }
 
@@ -446,10 +544,8 @@ func (p *PreparedQuery) ExecuteRemote(args *structs.PreparedQueryExecuteRemoteRe
 
 	// We have to do this ourselves since we are not doing a blocking RPC.
 	p.srv.setQueryMeta(&reply.QueryMeta)
-	if args.RequireConsistent {
-		if err := p.srv.consistentRead(); err != nil {
-			return err
-		}
+	if err := p.srv.checkConsistentRead(&args.QueryOptions); err != nil {
+		return err
 	}
 
 	// Run the query locally to see what we can find.
@@ -583,6 +679,7 @@ func nodeMetaFilter(filters map[string]string, nodes structs.CheckServiceNodes)
 // queryServer is a wrapper that makes it easier to test the failover logic.
 type queryServer interface {
 	GetLogger() *log.Logger
+	GetDatacenter() string
 	GetOtherDatacentersByDistance() ([]string, error)
 	ForwardDC(method, dc string, args interface{}, reply interface{}) error
 }
@@ -597,6 +694,11 @@ func (q *queryServerWrapper) GetLogger() *log.Logger {
 	return q.srv.logger
 }
 
+// GetDatacenter returns the server's datacenter.
+func (q *queryServerWrapper) GetDatacenter() string {
+	return q.srv.config.Datacenter
+}
+
 // GetOtherDatacentersByDistance calls into the server's fn and filters out the
 // server's own DC.
 func (q *queryServerWrapper) GetOtherDatacentersByDistance() ([]string, error) {
@@ -616,22 +718,34 @@ func (q *queryServerWrapper) GetOtherDatacentersByDistance() ([]string, error) {
 	return result, nil
 }
 
-// ForwardDC calls into the server's RPC forwarder.
+// ForwardDC calls into the server's RPC forwarder. Requests for the local
+// datacenter are handled in-process.
 func (q *queryServerWrapper) ForwardDC(method, dc string, args interface{}, reply interface{}) error {
+	if dc == q.srv.config.Datacenter {
+		return q.srv.RPC(method, args, reply)
+	}
 	return q.srv.forwardDC(method, dc, args, reply)
 }
 
-// queryFailover runs an algorithm to determine which DCs to try and then calls
-// them to try to locate alternative services.
+// queryFailover runs an algorithm to determine which targets to try and then
+// calls them to try to locate alternative services. The reply should hold the
+// local results on entry.
 func queryFailover(q queryServer, query *structs.PreparedQuery,
 	limit int, options structs.QueryOptions,
 	reply *structs.PreparedQueryExecuteResponse) error {
+	_, err := queryFailoverExplain(q, query, limit, options, reply)
+	return err
+}
 
-	// Pull the list of other DCs. This is sorted by RTT in case the user
-	// has selected that.
-	nearest, err := q.GetOtherDatacentersByDistance()
-	if err != nil {
-		return err
+// queryFailoverTargets builds the ordered list of targets to try, given the
+// list of other known datacenters sorted by RTT.
+func queryFailoverTargets(q queryServer, query *structs.PreparedQuery,
+	nearest []string) []structs.QueryFailoverTarget {
+
+	// Explicit targets are used as-is; they are validated when they are
+	// tried.
+	if len(query.Service.Failover.Targets) > 0 {
+		return query.Service.Failover.Targets
 	}
 
 	// This will help us filter unknown DCs supplied by the user.
@@ -642,7 +756,7 @@ func queryFailover(q queryServer, query *structs.PreparedQuery,
 
 	// Build a candidate list of DCs to try, starting with the nearest N
 	// from RTTs.
-	var dcs []string
+	var targets []structs.QueryFailoverTarget
 	index := make(map[string]struct{})
 	if query.Service.Failover.NearestN > 0 {
 		for i, dc := range nearest {
@@ -650,7 +764,7 @@ func queryFailover(q queryServer, query *structs.PreparedQuery,
 				break
 			}
 
-			dcs = append(dcs, dc)
+			targets = append(targets, structs.QueryFailoverTarget{Datacenter: dc})
 			index[dc] = struct{}{}
 		}
 	}
@@ -667,13 +781,86 @@ func queryFailover(q queryServer, query *structs.PreparedQuery,
 		// This will make sure we don't re-try something that fails
 		// from the NearestN list.
 		if _, ok := index[dc]; !ok {
-			dcs = append(dcs, dc)
+			targets = append(targets, structs.QueryFailoverTarget{Datacenter: dc})
 		}
 	}
 
-	// Now try the selected DCs in priority order.
+	return targets
+}
+
+// queryFailoverExplain is the guts of queryFailover, and also returns a record
+// of each target that was considered, starting with the local results.
+func queryFailoverExplain(q queryServer, query *structs.PreparedQuery,
+	limit int, options structs.QueryOptions,
+	reply *structs.PreparedQueryExecuteResponse) ([]structs.QueryTargetExplanation, error) {
+
+	// See if the local results are good enough on their own.
+	threshold := query.Service.Failover.HealthyThreshold()
+	local := structs.QueryTargetExplanation{
+		Datacenter: reply.Datacenter,
+		Service:    reply.Service,
+		Healthy:    len(reply.Nodes),
+	}
+	if local.Healthy >= threshold {
+		local.Chosen = true
+		local.Reason = fmt.Sprintf("Found %d healthy nodes", local.Healthy)
+		return []structs.QueryTargetExplanation{local}, nil
+	}
+	local.Reason = fmt.Sprintf("Found %d healthy nodes, needed %d", local.Healthy, threshold)
+	explanations := []structs.QueryTargetExplanation{local}
+
+	// Pull the list of other DCs. This is sorted by RTT in case the user
+	// has selected that.
+	nearest, err := q.GetOtherDatacentersByDistance()
+	if err != nil {
+		return explanations, err
+	}
+	known := make(map[string]struct{})
+	for _, dc := range nearest {
+		known[dc] = struct{}{}
+	}
+	known[q.GetDatacenter()] = struct{}{}
+
+	// If the limit would keep us from seeing enough nodes to satisfy the
+	// threshold then we apply it ourselves once we are done.
+	remoteLimit := limit
+	if limit > 0 && limit < threshold {
+		remoteLimit = 0
+	}
+
+	// Keep track of the best results so far, in case no target has enough
+	// healthy nodes.
+	var best structs.PreparedQueryExecuteResponse
+	bestIndex := -1
+	if local.Healthy > 0 {
+		best, bestIndex = *reply, 0
+	}
+
+	// Now try the selected targets in priority order.
+	chosen := false
 	failovers := 0
-	for _, dc := range dcs {
+	for _, target := range queryFailoverTargets(q, query, nearest) {
+		dc := target.Datacenter
+		if dc == "" {
+			dc = q.GetDatacenter()
+		}
+		service := target.Service
+		if service == "" {
+			service = query.Service.Service
+		}
+		explanation := structs.QueryTargetExplanation{
+			Datacenter: dc,
+			Service:    service,
+		}
+
+		// Skip explicit targets for DCs we don't know about.
+		if _, ok := known[dc]; !ok {
+			q.GetLogger().Printf("[DEBUG] consul.prepared_query: Skipping unknown datacenter '%s' in prepared query", dc)
+			explanation.Reason = "Unknown datacenter"
+			explanations = append(explanations, explanation)
+			continue
+		}
+
 		// This keeps track of how many iterations we actually run.
 		failovers++
 
@@ -692,23 +879,50 @@ func queryFailover(q queryServer, query *structs.PreparedQuery,
 		remote := &structs.PreparedQueryExecuteRemoteRequest{
 			Datacenter:   dc,
 			Query:        *query,
-			Limit:        limit,
+			Limit:        remoteLimit,
 			QueryOptions: options,
 		}
+		remote.Query.Service.Service = service
 		if err := q.ForwardDC("PreparedQuery.ExecuteRemote", dc, remote, reply); err != nil {
-			q.GetLogger().Printf("[WARN] consul.prepared_query: Failed querying for service '%s' in datacenter '%s': %s", query.Service.Service, dc, err)
+			q.GetLogger().Printf("[WARN] consul.prepared_query: Failed querying for service '%s' in datacenter '%s': %s", service, dc, err)
+			explanation.Reason = fmt.Sprintf("Query failed: %v", err)
+			explanations = append(explanations, explanation)
 			continue
 		}
 
-		// We can stop if we found some nodes.
-		if len(reply.Nodes) > 0 {
+		// We can stop if we found enough nodes.
+		explanation.Healthy = len(reply.Nodes)
+		if explanation.Healthy >= threshold {
+			explanation.Chosen = true
+			explanation.Reason = fmt.Sprintf("Found %d healthy nodes", explanation.Healthy)
+			explanations = append(explanations, explanation)
+			chosen = true
 			break
 		}
+
+		explanation.Reason = fmt.Sprintf("Found %d healthy nodes, needed %d", explanation.Healthy, threshold)
+		explanations = append(explanations, explanation)
+		if explanation.Healthy > len(best.Nodes) {
+			best, bestIndex = *reply, len(explanations)-1
+		}
+	}
+
+	// If nothing had enough healthy nodes then fall back to whichever
+	// target had the most, if any did.
+	if !chosen && bestIndex >= 0 {
+		*reply = best
+		explanations[bestIndex].Chosen = true
+		explanations[bestIndex].Reason += "; using the most healthy nodes found"
+	}
+
+	// Apply the limit ourselves if we couldn't send it along.
+	if limit > 0 && len(reply.Nodes) > limit {
+		reply.Nodes = reply.Nodes[:limit]
 	}
 
 	// Set this at the end because the response from the remote doesn't have
 	// this information.
 	reply.Failovers = failovers
 
-	return nil
+	return explanations, nil
 }
@@ -586,6 +586,34 @@ func TestPreparedQuery_parseQuery(t *testing.T) {
 			t.Fatalf("err: %v", err)
 		}
 
+		query.Service.Failover.MinHealthy = -1
+		err = parseQuery(query, version8)
+		if err == nil || !strings.Contains(err.Error(), "Bad MinHealthy") {
+			t.Fatalf("bad: %v", err)
+		}
+
+		query.Service.Failover.MinHealthy = 2
+		query.Service.Failover.Targets = []structs.QueryFailoverTarget{
+			{Service: "foo-backup"},
+		}
+		err = parseQuery(query, version8)
+		if err == nil || !strings.Contains(err.Error(), "cannot be combined") {
+			t.Fatalf("bad: %v", err)
+		}
+
+		query.Service.Failover.NearestN = 0
+		query.Service.Failover.Targets = append(query.Service.Failover.Targets,
+			structs.QueryFailoverTarget{})
+		err = parseQuery(query, version8)
+		if err == nil || !strings.Contains(err.Error(), "Failover target 1 must provide") {
+			t.Fatalf("bad: %v", err)
+		}
+
+		query.Service.Failover.Targets[1].Datacenter = "dc2"
+		if err := parseQuery(query, version8); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
 		query.DNS.TTL = "two fortnights"
 		err = parseQuery(query, version8)
 		if err == nil || !strings.Contains(err.Error(), "Bad DNS TTL") {
@@ -2570,6 +2598,216 @@ func TestPreparedQuery_Execute_ForwardLeader(t *testing.T) {
 	}
 }
 
+func TestPreparedQuery_Execute_FailoverTargets(t *testing.T) {
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Register one instance of the primary service and two of the backup.
+	for i, service := range []string{"redis", "redis-backup", "redis-backup"} {
+		req := structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       fmt.Sprintf("node%d", i+1),
+			Address:    fmt.Sprintf("127.0.0.%d", i+1),
+			Service: &structs.NodeService{
+				Service: service,
+				Port:    8000,
+			},
+		}
+		var reply struct{}
+		if err := msgpackrpc.CallWithCodec(codec, "Catalog.Register", &req, &reply); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Set up a query that wants at least two healthy instances, and falls
+	// back to the backup service.
+	query := structs.PreparedQueryRequest{
+		Datacenter: "dc1",
+		Op:         structs.PreparedQueryCreate,
+		Query: &structs.PreparedQuery{
+			Name: "test",
+			Service: structs.ServiceQuery{
+				Service: "redis",
+				Failover: structs.QueryDatacenterOptions{
+					MinHealthy: 2,
+					Targets: []structs.QueryFailoverTarget{
+						{Datacenter: "dc9"},
+						{Service: "redis-backup"},
+					},
+				},
+			},
+		},
+	}
+	if err := msgpackrpc.CallWithCodec(codec, "PreparedQuery.Apply", &query, &query.Query.ID); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Executing should fail over to the backup service, even though there's
+	// a healthy instance of the primary.
+	{
+		req := structs.PreparedQueryExecuteRequest{
+			Datacenter:    "dc1",
+			QueryIDOrName: query.Query.ID,
+		}
+		var reply structs.PreparedQueryExecuteResponse
+		if err := msgpackrpc.CallWithCodec(codec, "PreparedQuery.Execute", &req, &reply); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(reply.Nodes) != 2 || reply.Service != "redis-backup" ||
+			reply.Datacenter != "dc1" || reply.Failovers != 1 {
+			t.Fatalf("bad: %v", reply)
+		}
+		for _, node := range reply.Nodes {
+			if node.Service.Service != "redis-backup" {
+				t.Fatalf("bad: %v", node)
+			}
+		}
+	}
+
+	// Explain should show how we got there.
+	{
+		req := structs.PreparedQueryExecuteRequest{
+			Datacenter:    "dc1",
+			QueryIDOrName: query.Query.ID,
+		}
+		var reply structs.PreparedQueryExplainResponse
+		if err := msgpackrpc.CallWithCodec(codec, "PreparedQuery.Explain", &req, &reply); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		expected := []structs.QueryTargetExplanation{
+			{
+				Datacenter: "dc1",
+				Service:    "redis",
+				Healthy:    1,
+				Reason:     "Found 1 healthy nodes, needed 2",
+			},
+			{
+				Datacenter: "dc9",
+				Service:    "redis",
+				Reason:     "Unknown datacenter",
+			},
+			{
+				Datacenter: "dc1",
+				Service:    "redis-backup",
+				Healthy:    2,
+				Chosen:     true,
+				Reason:     "Found 2 healthy nodes",
+			},
+		}
+		if !reflect.DeepEqual(reply.Targets, expected) {
+			t.Fatalf("bad: %#v", reply.Targets)
+		}
+	}
+}
+
+func TestPreparedQuery_Execute_TemplateContext(t *testing.T) {
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec := rpcClient(t, s1)
+	defer codec.Close()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Set up an agent node with some metadata, and instances of the
+	// service spread over a few environments, racks and zones.
+	nodes := []struct {
+		name    string
+		tag     string
+		rack    string
+		zone    string
+		service bool
+	}{
+		{"agent", "", "", "zone1", false},
+		{"node1", "prod", "r1", "zone1", true},
+		{"node2", "prod", "r2", "zone1", true},
+		{"node3", "prod", "r1", "zone2", true},
+		{"node4", "dev", "r1", "zone1", true},
+	}
+	for i, n := range nodes {
+		req := structs.RegisterRequest{
+			Datacenter: "dc1",
+			Node:       n.name,
+			Address:    fmt.Sprintf("127.0.0.%d", i+1),
+			NodeMeta:   map[string]string{"zone": n.zone},
+		}
+		if n.rack != "" {
+			req.NodeMeta["rack"] = n.rack
+		}
+		if n.service {
+			req.Service = &structs.NodeService{
+				Service: "redis",
+				Port:    8000,
+				Tags:    []string{n.tag},
+			}
+		}
+		var reply struct{}
+		if err := msgpackrpc.CallWithCodec(codec, "Catalog.Register", &req, &reply); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	// Set up a template that captures parts of the name into the tag and
+	// node metadata filters, and stays in the same zone as the agent.
+	query := structs.PreparedQueryRequest{
+		Datacenter: "dc1",
+		Op:         structs.PreparedQueryCreate,
+		Query: &structs.PreparedQuery{
+			Name: "",
+			Template: structs.QueryTemplateOptions{
+				Type:   structs.QueryTemplateTypeNamePrefixMatch,
+				Regexp: "^(.+?)-(.+?)-(.+?)$",
+			},
+			Service: structs.ServiceQuery{
+				Service: "${match(1)}",
+				Tags:    []string{"${match(2)}"},
+				NodeMeta: map[string]string{
+					"rack": "${match(3)}",
+					"zone": "${agent_meta(\"zone\")}",
+				},
+			},
+		},
+	}
+	var id string
+	if err := msgpackrpc.CallWithCodec(codec, "PreparedQuery.Apply", &query, &id); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Run the query on behalf of the agent.
+	req := structs.PreparedQueryExecuteRequest{
+		Datacenter:    "dc1",
+		QueryIDOrName: "redis-prod-r1",
+		Agent: structs.QuerySource{
+			Datacenter: "dc1",
+			Node:       "agent",
+		},
+	}
+	var reply structs.PreparedQueryExecuteResponse
+	if err := msgpackrpc.CallWithCodec(codec, "PreparedQuery.Execute", &req, &reply); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(reply.Nodes) != 1 || reply.Nodes[0].Node.Node != "node1" {
+		t.Fatalf("bad: %v", reply)
+	}
+
+	// The explanation should show the rendered filters.
+	var explain structs.PreparedQueryExplainResponse
+	if err := msgpackrpc.CallWithCodec(codec, "PreparedQuery.Explain", &req, &explain); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	expected := map[string]string{"rack": "r1", "zone": "zone1"}
+	if svc := explain.Query.Service; svc.Service != "redis" ||
+		!reflect.DeepEqual(svc.Tags, []string{"prod"}) ||
+		!reflect.DeepEqual(svc.NodeMeta, expected) {
+		t.Fatalf("bad: %v", svc)
+	}
+}
+
 func TestPreparedQuery_tagFilter(t *testing.T) {
 	testNodes := func() structs.CheckServiceNodes {
 		return structs.CheckServiceNodes{
@@ -2712,6 +2950,7 @@ func TestPreparedQuery_Wrapper(t *testing.T) {
 }
 
 type mockQueryServer struct {
+	LocalDatacenter  string
 	Datacenters      []string
 	DatacentersError error
 	QueryLog         []string
@@ -2732,6 +2971,10 @@ func (m *mockQueryServer) GetLogger() *log.Logger {
 	return m.Logger
 }
 
+func (m *mockQueryServer) GetDatacenter() string {
+	return m.LocalDatacenter
+}
+
 func (m *mockQueryServer) GetOtherDatacentersByDistance() ([]string, error) {
 	return m.Datacenters, m.DatacentersError
 }
@@ -3086,4 +3329,131 @@ func TestPreparedQuery_queryFailover(t *testing.T) {
 			t.Fatalf("bad: %s", queries)
 		}
 	}
+
+	// Use explicit targets mixing datacenters and alternative services,
+	// including an unknown datacenter which gets skipped.
+	query.Service.Failover.Datacenters = nil
+	query.Service.Failover.Targets = []structs.QueryFailoverTarget{
+		{Service: "backup"},
+		{Datacenter: "nope"},
+		{Datacenter: "dc2", Service: "backup"},
+	}
+	{
+		mock := &mockQueryServer{
+			LocalDatacenter: "dc1",
+			Datacenters:     []string{"dc2", "dc3"},
+			QueryFn: func(dc string, args interface{}, reply interface{}) error {
+				inp := args.(*structs.PreparedQueryExecuteRemoteRequest)
+				ret := reply.(*structs.PreparedQueryExecuteResponse)
+				ret.Service = inp.Query.Service.Service
+				if dc == "dc2" && inp.Query.Service.Service == "backup" {
+					ret.Nodes = nodes()
+				}
+				return nil
+			},
+		}
+
+		var reply structs.PreparedQueryExecuteResponse
+		explanations, err := queryFailoverExplain(mock, query, 0, structs.QueryOptions{}, &reply)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(reply.Nodes) != 3 ||
+			reply.Datacenter != "dc2" || reply.Service != "backup" || reply.Failovers != 2 ||
+			!reflect.DeepEqual(reply.Nodes, nodes()) {
+			t.Fatalf("bad: %v", reply)
+		}
+		if queries := mock.JoinQueryLog(); queries != "dc1:PreparedQuery.ExecuteRemote|dc2:PreparedQuery.ExecuteRemote" {
+			t.Fatalf("bad: %s", queries)
+		}
+
+		var actual []string
+		for _, e := range explanations {
+			actual = append(actual, fmt.Sprintf("%s/%s/%d/%v", e.Datacenter, e.Service, e.Healthy, e.Chosen))
+		}
+		expected := []string{"//0/false", "dc1/backup/0/false", "nope//0/false", "dc2/backup/3/true"}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Fatalf("bad: %v", actual)
+		}
+		if explanations[2].Reason != "Unknown datacenter" {
+			t.Fatalf("bad: %v", explanations[2])
+		}
+	}
+
+	// Fail over before a total outage when there aren't enough healthy
+	// nodes locally. The limit is smaller than the threshold, so it gets
+	// applied once the target has been chosen.
+	query.Service.Failover.MinHealthy = 3
+	query.Service.Failover.Targets = []structs.QueryFailoverTarget{
+		{Datacenter: "dc2"},
+		{Datacenter: "dc3"},
+	}
+	{
+		mock := &mockQueryServer{
+			LocalDatacenter: "dc1",
+			Datacenters:     []string{"dc2", "dc3"},
+			QueryFn: func(dc string, args interface{}, reply interface{}) error {
+				inp := args.(*structs.PreparedQueryExecuteRemoteRequest)
+				ret := reply.(*structs.PreparedQueryExecuteResponse)
+				if inp.Limit != 0 {
+					t.Fatalf("bad: %d", inp.Limit)
+				}
+				if dc == "dc2" {
+					ret.Nodes = nodes()[:2]
+				} else {
+					ret.Nodes = nodes()
+				}
+				return nil
+			},
+		}
+
+		reply := structs.PreparedQueryExecuteResponse{
+			Datacenter: "dc1",
+			Nodes:      nodes()[:1],
+		}
+		explanations, err := queryFailoverExplain(mock, query, 2, structs.QueryOptions{}, &reply)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(reply.Nodes) != 2 || reply.Datacenter != "dc3" || reply.Failovers != 2 {
+			t.Fatalf("bad: %v", reply)
+		}
+		if len(explanations) != 3 || !explanations[2].Chosen ||
+			explanations[0].Reason != "Found 1 healthy nodes, needed 3" {
+			t.Fatalf("bad: %v", explanations)
+		}
+	}
+
+	// If no target has enough healthy nodes we go with the best one we
+	// saw, which can be the local datacenter.
+	{
+		mock := &mockQueryServer{
+			LocalDatacenter: "dc1",
+			Datacenters:     []string{"dc2", "dc3"},
+			QueryFn: func(dc string, args interface{}, reply interface{}) error {
+				ret := reply.(*structs.PreparedQueryExecuteResponse)
+				if dc == "dc3" {
+					ret.Nodes = nodes()[:1]
+				}
+				return nil
+			},
+		}
+
+		reply := structs.PreparedQueryExecuteResponse{
+			Datacenter: "dc1",
+			Nodes:      nodes()[:2],
+		}
+		explanations, err := queryFailoverExplain(mock, query, 0, structs.QueryOptions{}, &reply)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(reply.Nodes) != 2 || reply.Datacenter != "dc1" || reply.Failovers != 2 ||
+			!reflect.DeepEqual(reply.Nodes, nodes()[:2]) {
+			t.Fatalf("bad: %v", reply)
+		}
+		if len(explanations) != 3 || !explanations[0].Chosen ||
+			explanations[1].Chosen || explanations[2].Chosen {
+			t.Fatalf("bad: %v", explanations)
+		}
+	}
 }
@@ -211,6 +211,22 @@ CHECK_LEADER:
 
 	// Handle the case of a known leader
 	if remoteServer != nil {
+		// If follower reads are enabled we can serve the read ourselves
+		// once we've caught up with the leader. If anything goes wrong
+		// we just fall back to forwarding.
+		if info.IsRead() && s.config.FollowerReads && !leaderOnlyReads[method] {
+			err := s.readIndexBarrier(remoteServer)
+			if err == nil {
+				// Let the consistency check for this request know the
+				// barrier already ran, so it doesn't ask the leader twice.
+				if marker, ok := info.(readIndexMarker); ok {
+					marker.SetReadIndexChecked(true)
+				}
+				return false, nil
+			}
+			s.logger.Printf("[DEBUG] consul.rpc: Forwarding %s to leader, follower read failed: %v", method, err)
+		}
+
 		err := s.forwardLeader(remoteServer, method, args, reply)
 		return true, err
 	}
@@ -375,10 +391,8 @@ RUN_QUERY:
 	s.setQueryMeta(queryMeta)
 
 	// If the read must be consistent we verify that we are still the leader.
-	if queryOpts.RequireConsistent {
-		if err := s.consistentRead(); err != nil {
-			return err
-		}
+	if err := s.checkConsistentRead(queryOpts); err != nil {
+		return err
 	}
 
 	// Run the query.
@@ -430,9 +444,88 @@ func (s *Server) setQueryMeta(m *structs.QueryMeta) {
 }
 
 // consistentRead is used to ensure we do not perform a stale
-// read. This is done by verifying leadership before the read.
+// read. This is done by verifying leadership before the read. If follower
+// reads are enabled and we aren't the leader, we instead catch up with the
+// leader's read index.
 func (s *Server) consistentRead() error {
 	defer metrics.MeasureSince([]string{"consul", "rpc", "consistentRead"}, time.Now())
+	if s.config.FollowerReads {
+		if isLeader, leader := s.getLeader(); !isLeader {
+			return s.readIndexBarrier(leader)
+		}
+	}
 	future := s.raft.VerifyLeader()
 	return future.Error()
 }
+
+// checkConsistentRead runs consistentRead if the query asks for a consistent
+// read. If forward already caught up with the leader's read index for the
+// query, that counts for the first check, but later runs of a blocking query
+// check again.
+func (s *Server) checkConsistentRead(q *structs.QueryOptions) error {
+	if !q.RequireConsistent {
+		return nil
+	}
+	if q.ReadIndexChecked() {
+		q.SetReadIndexChecked(false)
+		return nil
+	}
+	return s.consistentRead()
+}
+
+// readIndexMarker is implemented by requests that embed QueryOptions, so
+// forward can record that it already ran the read index barrier.
+type readIndexMarker interface {
+	SetReadIndexChecked(checked bool)
+}
+
+// leaderOnlyReads are read RPCs that depend on state that only the leader
+// keeps, or that may need to write to Raft, so they are always forwarded to
+// the leader, even with follower reads enabled.
+var leaderOnlyReads = map[string]bool{
+	"ACL.ReplicationStatus": true,
+	"Internal.EventFire":    true,
+	"Operator.ServerHealth": true,
+	"Session.Renew":         true,
+}
+
+// readIndexBarrier lets a follower serve a read with the same consistency as
+// the leader. It asks the leader for its read index, which the leader only
+// hands out after confirming it's still the leader, and then waits for the
+// local state store to catch up to that index.
+func (s *Server) readIndexBarrier(leader *agent.Server) error {
+	defer metrics.MeasureSince([]string{"consul", "rpc", "followerRead"}, time.Now())
+	if leader == nil {
+		return structs.ErrNoLeader
+	}
+
+	var index uint64
+	if err := s.forwardLeader(leader, "Status.ReadIndex", struct{}{}, &index); err != nil {
+		return err
+	}
+	return s.waitForIndex(index)
+}
+
+// waitForIndex blocks until the local state store has applied the given
+// index, or until the RPC hold timeout expires.
+func (s *Server) waitForIndex(index uint64) error {
+	timeout := time.NewTimer(s.config.RPCHoldTimeout)
+	defer timeout.Stop()
+
+	for {
+		state := s.fsm.State()
+		ws := memdb.NewWatchSet()
+		ws.Add(state.AbandonCh())
+		latest, err := state.LatestIndex(ws)
+		if err != nil {
+			return err
+		}
+		if latest >= index {
+			return nil
+		}
+
+		if expired := ws.Watch(timeout.C); expired {
+			return fmt.Errorf("timed out waiting for index %d, at index %d", index, latest)
+		}
+	}
+}
@@ -2,10 +2,12 @@ package consul
 
 import (
 	"bytes"
+	"fmt"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/consul/state"
 	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/consul/testrpc"
@@ -163,3 +165,105 @@ func TestRPC_blockingQuery(t *testing.T) {
 		}
 	}
 }
+
+func TestRPC_FollowerReads(t *testing.T) {
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec1 := rpcClient(t, s1)
+	defer codec1.Close()
+
+	dir2, s2 := testServerWithConfig(t, func(c *Config) {
+		c.Bootstrap = false
+		c.FollowerReads = true
+	})
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	codec2 := rpcClient(t, s2)
+	defer codec2.Close()
+
+	joinLAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+	testrpc.WaitForLeader(t, s2.RPC, "dc1")
+
+	// Writes still go to the leader, and a read from the follower right
+	// afterwards should see them in both the default and consistent modes.
+	for i, consistent := range []bool{false, true} {
+		key := fmt.Sprintf("test%d", i)
+		arg := structs.KVSRequest{
+			Datacenter: "dc1",
+			Op:         api.KVSet,
+			DirEnt: structs.DirEntry{
+				Key:   key,
+				Value: []byte("hello"),
+			},
+		}
+		var ok bool
+		if err := msgpackrpc.CallWithCodec(codec2, "KVS.Apply", &arg, &ok); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		get := structs.KeyRequest{
+			Datacenter: "dc1",
+			Key:        key,
+			QueryOptions: structs.QueryOptions{
+				RequireConsistent: consistent,
+			},
+		}
+		var out structs.IndexedDirEntries
+		if err := msgpackrpc.CallWithCodec(codec2, "KVS.Get", &get, &out); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if len(out.Entries) != 1 || string(out.Entries[0].Value) != "hello" {
+			t.Fatalf("bad: %v", out.Entries)
+		}
+		if !out.KnownLeader {
+			t.Fatalf("bad: %v", out)
+		}
+	}
+
+	// Sneak an entry into the follower's local state to make sure reads
+	// are actually served there and not forwarded.
+	if err := s2.fsm.State().KVSSet(1000, &structs.DirEntry{Key: "local"}); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	get := structs.KeyRequest{
+		Datacenter: "dc1",
+		Key:        "local",
+	}
+	var out structs.IndexedDirEntries
+	if err := msgpackrpc.CallWithCodec(codec2, "KVS.Get", &get, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Entries) != 1 {
+		t.Fatalf("bad: %v", out.Entries)
+	}
+
+	// Reads from the leader are unaffected.
+	if err := msgpackrpc.CallWithCodec(codec1, "KVS.Get", &get, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if len(out.Entries) != 0 {
+		t.Fatalf("bad: %v", out.Entries)
+	}
+
+	// A consistent read that forward served locally has already caught up
+	// with the leader, so the consistency check doesn't ask it again.
+	get.RequireConsistent = true
+	if done, err := s2.forward("KVS.Get", &get, &get, &out); done || err != nil {
+		t.Fatalf("bad: %v %v", done, err)
+	}
+	if !get.ReadIndexChecked() {
+		t.Fatalf("barrier not recorded")
+	}
+	s1.Shutdown()
+	if err := s2.checkConsistentRead(&get.QueryOptions); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// That only covers the first check, so later runs of a blocking query
+	// need the leader, which is gone.
+	if err := s2.checkConsistentRead(&get.QueryOptions); err == nil {
+		t.Fatalf("should fail")
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/consul/lib"
 	"github.com/hashicorp/consul/tlsutil"
+	"github.com/hashicorp/consul/token"
 	"github.com/hashicorp/consul/types"
 	"github.com/hashicorp/raft"
 	"github.com/hashicorp/raft-boltdb"
@@ -152,6 +153,14 @@ type Server struct {
 	// rpcTLS is the TLS config for incoming TLS requests
 	rpcTLS *tls.Config
 
+	// tlsConfigurator holds the TLS material used by rpcTLS and the
+	// outgoing connection wrappers, and is used to reload it.
+	tlsConfigurator *tlsutil.Configurator
+
+	// tokens holds the ACL replication token, which can be updated at
+	// runtime.
+	tokens *token.Store
+
 	// serfLAN is the Serf cluster maintained inside the DC
 	// which contains all the DC nodes
 	serfLAN *serf.Serf
@@ -213,6 +222,15 @@ type endpoints struct {
 // NewServer is used to construct a new Consul server from the
 // configuration, potentially returning an error
 func NewServer(config *Config) (*Server, error) {
+	tokens := new(token.Store)
+	tokens.UpdateACLReplicationToken(config.ACLReplicationToken)
+	return NewServerTokens(config, tokens)
+}
+
+// NewServerTokens is used to construct a new Consul server that looks up
+// its ACL replication token in the given store, so the token can be
+// updated while the server is running.
+func NewServerTokens(config *Config, tokens *token.Store) (*Server, error) {
 	// Check the protocol version.
 	if err := config.CheckProtocolVersion(); err != nil {
 		return nil, err
@@ -239,18 +257,18 @@ func NewServer(config *Config) (*Server, error) {
 		config.UseTLS = true
 	}
 
-	// Create the TLS wrapper for outgoing connections.
-	tlsConf := config.tlsConfig()
-	tlsWrap, err := tlsConf.OutgoingTLSWrapper()
+	// Load the TLS material. The configurator lets it be reloaded later
+	// without tearing down the listeners or connection pools.
+	tlsConfigurator, err := tlsutil.NewConfigurator(config.tlsConfig())
 	if err != nil {
 		return nil, err
 	}
 
+	// Create the TLS wrapper for outgoing connections.
+	tlsWrap := tlsConfigurator.OutgoingTLSWrapper()
+
 	// Get the incoming TLS config.
-	incomingTLS, err := tlsConf.IncomingTLSConfig()
-	if err != nil {
-		return nil, err
-	}
+	incomingTLS := tlsConfigurator.IncomingTLSConfig()
 
 	// Create the tombstone GC.
 	gc, err := state.NewTombstoneGC(config.TombstoneTTL, config.TombstoneTTLGranularity)
@@ -275,6 +293,8 @@ func NewServer(config *Config) (*Server, error) {
 		router:                servers.NewRouter(logger, shutdownCh, config.Datacenter),
 		rpcServer:             rpc.NewServer(),
 		rpcTLS:                incomingTLS,
+		tlsConfigurator:       tlsConfigurator,
+		tokens:                tokens,
 		reassertLeaderCh:      make(chan chan error),
 		tombstoneGC:           gc,
 		shutdownCh:            make(chan struct{}),
@@ -294,10 +314,12 @@ func NewServer(config *Config) (*Server, error) {
 	}
 
 	// Set up the non-authoritative ACL cache. A nil local function is given
-	// if ACL replication isn't enabled.
+	// if this server can't replicate ACLs. Otherwise the local function
+	// checks if replication is enabled, since the replication token can be
+	// set later.
 	var local acl.FaultFunc
-	if s.IsACLReplicationEnabled() {
-		local = s.aclLocalFault
+	if s.isACLReplicationConfigured() {
+		local = s.aclReplicatedFault
 	}
 	if s.aclCache, err = newACLCache(config, logger, s.RPC, local); err != nil {
 		s.Shutdown()
@@ -353,8 +375,9 @@ func NewServer(config *Config) (*Server, error) {
 	// since it can fire events when leadership is obtained.
 	go s.monitorLeadership()
 
-	// Start ACL replication.
-	if s.IsACLReplicationEnabled() {
+	// Start ACL replication. This waits for a replication token if one
+	// hasn't been set yet.
+	if s.isACLReplicationConfigured() {
 		go s.runACLReplication()
 	}
 
@@ -873,6 +896,12 @@ func (s *Server) Encrypted() bool {
 	return s.serfLAN.EncryptionEnabled() && s.serfWAN.EncryptionEnabled()
 }
 
+// ReloadTLS re-reads the configured CA, certificate and key files. New
+// connections use the reloaded material while existing ones are left alone.
+func (s *Server) ReloadTLS() error {
+	return s.tlsConfigurator.Reload()
+}
+
 // inmemCodec is used to do an RPC call without going over a network
 type inmemCodec struct {
 	method string
@@ -168,6 +168,34 @@ func (l *serverList) cycleServer() (servers []*agent.Server) {
 	return newServers
 }
 
+// deferServer returns a new list of servers with the server at the given
+// index moved to the end of the list.  deferServer assumes the caller is
+// holding the listLock.
+func (l *serverList) deferServer(idx int) (servers []*agent.Server) {
+	numServers := len(l.servers)
+	if numServers < 2 || idx < 0 || idx >= numServers {
+		return l.servers // No action required
+	}
+
+	newServers := make([]*agent.Server, 0, numServers)
+	newServers = append(newServers, l.servers[:idx]...)
+	newServers = append(newServers, l.servers[idx+1:]...)
+	newServers = append(newServers, l.servers[idx])
+
+	return newServers
+}
+
+// readServerIndex returns the index of the first read replica in the list,
+// or -1 if there are none.
+func (l *serverList) readServerIndex() int {
+	for i, s := range l.servers {
+		if s.NonVoter {
+			return i
+		}
+	}
+	return -1
+}
+
 // removeServerByKey performs an inline removal of the first matching server
 func (l *serverList) removeServerByKey(targetKey *agent.Key) {
 	for i, s := range l.servers {
@@ -216,6 +244,19 @@ func (m *Manager) FindServer() *agent.Server {
 	return l.servers[0]
 }
 
+// FindReadServer is like FindServer, but prefers read replicas, which are
+// servers that hold a full copy of the state but never vote.  This is used
+// for stale reads, which any server can answer locally, in order to take
+// load off the voting servers.  If there are no read replicas, this returns
+// the same server as FindServer.
+func (m *Manager) FindReadServer() *agent.Server {
+	l := m.getServerList()
+	if idx := l.readServerIndex(); idx >= 0 {
+		return l.servers[idx]
+	}
+	return m.FindServer()
+}
+
 // getServerList is a convenience method which hides the locking semantics
 // of atomic.Value from the caller.
 func (m *Manager) getServerList() serverList {
@@ -250,17 +291,17 @@ func (m *Manager) NotifyFailedServer(s *agent.Server) {
 	l := m.getServerList()
 
 	// If the server being failed is not the first server on the list,
-	// this is a noop.  If, however, the server is failed and first on
-	// the list, acquire the lock, retest, and take the penalty of moving
-	// the server to the end of the list.
+	// or the first read replica, this is a noop.  If, however, the server
+	// is failed and is one of those, acquire the lock, retest, and take
+	// the penalty of moving the server to the end of the list.
 
 	// Only rotate the server list when there is more than one server
-	if len(l.servers) > 1 && l.servers[0] == s &&
+	if len(l.servers) > 1 && l.isSelected(s) &&
 		// Use atomic.CAS to emulate a TryLock().
 		atomic.CompareAndSwapInt32(&m.notifyFailedBarrier, 0, 1) {
 		defer atomic.StoreInt32(&m.notifyFailedBarrier, 0)
 
-		// Grab a lock, retest, and take the hit of cycling the first
+		// Grab a lock, retest, and take the hit of cycling the
 		// server to the end.
 		m.listLock.Lock()
 		defer m.listLock.Unlock()
@@ -269,10 +310,23 @@ func (m *Manager) NotifyFailedServer(s *agent.Server) {
 		if len(l.servers) > 1 && l.servers[0] == s {
 			l.servers = l.cycleServer()
 			m.saveServerList(l)
+		} else if idx := l.readServerIndex(); idx > 0 && l.servers[idx] == s {
+			l.servers = l.deferServer(idx)
+			m.saveServerList(l)
 		}
 	}
 }
 
+// isSelected returns true if the given server is one that FindServer or
+// FindReadServer would currently return.
+func (l *serverList) isSelected(s *agent.Server) bool {
+	if l.servers[0] == s {
+		return true
+	}
+	idx := l.readServerIndex()
+	return idx > 0 && l.servers[idx] == s
+}
+
 // NumServers takes out an internal "read lock" and returns the number of
 // servers.  numServers includes both healthy and unhealthy servers.
 func (m *Manager) NumServers() int {
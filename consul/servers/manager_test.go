@@ -406,3 +406,50 @@ func TestManager_RemoveServer(t *testing.T) {
 }
 
 // func (m *Manager) Start() {
+
+// func (m *Manager) FindReadServer() (server *agent.Server) {
+func TestServers_FindReadServer(t *testing.T) {
+	m := testManager()
+
+	if m.FindReadServer() != nil {
+		t.Fatalf("Expected nil return")
+	}
+
+	// With no read replicas we should get the same server as FindServer.
+	m.AddServer(&agent.Server{Name: "s1"})
+	m.AddServer(&agent.Server{Name: "s2"})
+	s1 := m.FindReadServer()
+	if s1 == nil || s1.Name != "s1" {
+		t.Fatalf("Expected s1 server")
+	}
+
+	// Read replicas should be preferred, without changing the server used
+	// for everything else.
+	m.AddServer(&agent.Server{Name: "r1", NonVoter: true})
+	m.AddServer(&agent.Server{Name: "r2", NonVoter: true})
+	r1 := m.FindReadServer()
+	if r1 == nil || r1.Name != "r1" {
+		t.Fatalf("Expected r1 server")
+	}
+	s1 = m.FindServer()
+	if s1 == nil || s1.Name != "s1" {
+		t.Fatalf("Expected s1 server (still)")
+	}
+
+	// A failed read replica should be moved to the back of the line.
+	m.NotifyFailedServer(r1)
+	r2 := m.FindReadServer()
+	if r2 == nil || r2.Name != "r2" {
+		t.Fatalf("Expected r2 server")
+	}
+	s1 = m.FindServer()
+	if s1 == nil || s1.Name != "s1" {
+		t.Fatalf("Expected s1 server (still)")
+	}
+
+	m.NotifyFailedServer(r2)
+	r1 = m.FindReadServer()
+	if r1 == nil || r1.Name != "r1" {
+		t.Fatalf("Expected r1 server")
+	}
+}
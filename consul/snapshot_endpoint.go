@@ -77,7 +77,7 @@ func (s *Server) dispatchSnapshotRequest(args *structs.SnapshotRequest, in io.Re
 		s.setQueryMeta(&reply.QueryMeta)
 
 		// Take the snapshot and capture the index.
-		snap, err := snapshot.New(s.logger, s.raft)
+		snap, err := snapshot.New(s.logger, s.raft, s.config.SnapshotEncryptionKey)
 		reply.Index = snap.Index()
 		return snap, err
 
@@ -87,7 +87,7 @@ func (s *Server) dispatchSnapshotRequest(args *structs.SnapshotRequest, in io.Re
 		}
 
 		// Restore the snapshot.
-		if err := snapshot.Restore(s.logger, in, s.raft); err != nil {
+		if err := snapshot.Restore(s.logger, in, s.raft, s.config.SnapshotEncryptionKey); err != nil {
 			return nil, err
 		}
 
@@ -2,12 +2,15 @@ package consul
 
 import (
 	"bytes"
+	"crypto/rand"
+	"io"
 	"os"
 	"strings"
 	"testing"
 
 	"github.com/hashicorp/consul/api"
 	"github.com/hashicorp/consul/consul/structs"
+	"github.com/hashicorp/consul/snapshot"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/consul/testutil/retry"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
@@ -155,6 +158,46 @@ func TestSnapshot(t *testing.T) {
 	verifySnapshot(t, s1, "dc1", "")
 }
 
+func TestSnapshot_Encrypted(t *testing.T) {
+	key := make([]byte, snapshot.EncryptKeySize)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	dir1, s1 := testServerWithConfig(t, func(c *Config) {
+		c.SnapshotEncryptionKey = key
+	})
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	// Snapshots should come out encrypted.
+	args := structs.SnapshotRequest{
+		Datacenter: "dc1",
+		Op:         structs.SnapshotSave,
+	}
+	var reply structs.SnapshotResponse
+	snap, err := SnapshotRPC(s1.connPool, s1.config.Datacenter, s1.config.RPCAddr, false,
+		&args, bytes.NewReader([]byte("")), &reply)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer snap.Close()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, snap); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := snapshot.Verify(bytes.NewReader(buf.Bytes()), nil); err != snapshot.ErrEncrypted {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := snapshot.Verify(bytes.NewReader(buf.Bytes()), key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Make sure they round trip.
+	verifySnapshot(t, s1, "dc1", "")
+}
+
 func TestSnapshot_LeaderState(t *testing.T) {
 	dir1, s1 := testServer(t)
 	defer os.RemoveAll(dir1)
@@ -0,0 +1,211 @@
+package state
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hashicorp/consul/consul/structs"
+	"github.com/hashicorp/go-memdb"
+)
+
+// DurableEvents is used to pull all the durable events from the snapshot.
+func (s *Snapshot) DurableEvents() (structs.DurableEvents, error) {
+	iter, err := s.tx.Get("durable-events", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	var ret structs.DurableEvents
+	for event := iter.Next(); event != nil; event = iter.Next() {
+		ret = append(ret, event.(*structs.DurableEvent))
+	}
+	return ret, nil
+}
+
+// DurableEventAcks is used to pull all the durable event acknowledgements
+// from the snapshot.
+func (s *Snapshot) DurableEventAcks() (structs.DurableEventAcks, error) {
+	iter, err := s.tx.Get("durable-event-acks", "id")
+	if err != nil {
+		return nil, err
+	}
+
+	var ret structs.DurableEventAcks
+	for ack := iter.Next(); ack != nil; ack = iter.Next() {
+		ret = append(ret, ack.(*structs.DurableEventAck))
+	}
+	return ret, nil
+}
+
+// DurableEvent is used when restoring from a snapshot. For general inserts,
+// use DurableEventCreate.
+func (s *Restore) DurableEvent(event *structs.DurableEvent) error {
+	if err := s.tx.Insert("durable-events", event); err != nil {
+		return fmt.Errorf("failed restoring durable event: %s", err)
+	}
+	if err := indexUpdateMaxTxn(s.tx, event.ModifyIndex, "durable-events"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
+// DurableEventAck is used when restoring from a snapshot. For general
+// inserts, use Store.DurableEventAck.
+func (s *Restore) DurableEventAck(ack *structs.DurableEventAck) error {
+	if err := s.tx.Insert("durable-event-acks", ack); err != nil {
+		return fmt.Errorf("failed restoring durable event ack: %s", err)
+	}
+	if err := indexUpdateMaxTxn(s.tx, ack.ModifyIndex, "durable-event-acks"); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+	return nil
+}
+
+// DurableEventCreate records a new durable event. Any events whose retention
+// window ended before this one was created are pruned, along with their
+// acknowledgements and any large payload stored in the KV store. This uses
+// the times recorded in the event so that all the servers prune the same
+// events.
+func (s *Store) DurableEventCreate(idx uint64, event *structs.DurableEvent) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	// Check that the ID is set and isn't already in use.
+	if event.ID == "" {
+		return fmt.Errorf("Missing durable event ID")
+	}
+	existing, err := tx.First("durable-events", "id", event.ID)
+	if err != nil {
+		return fmt.Errorf("failed durable event lookup: %s", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("Durable event %q already exists", event.ID)
+	}
+
+	// Prune the expired events.
+	iter, err := tx.Get("durable-events", "id")
+	if err != nil {
+		return fmt.Errorf("failed durable event lookup: %s", err)
+	}
+	var expired []*structs.DurableEvent
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		old := raw.(*structs.DurableEvent)
+		if old.Expires.Before(event.CreateTime) {
+			expired = append(expired, old)
+		}
+	}
+	var prunedAcks bool
+	for _, old := range expired {
+		if err := tx.Delete("durable-events", old); err != nil {
+			return fmt.Errorf("failed deleting durable event: %s", err)
+		}
+		n, err := tx.DeleteAll("durable-event-acks", "event", old.ID)
+		if err != nil {
+			return fmt.Errorf("failed deleting durable event acks: %s", err)
+		}
+		if n > 0 {
+			prunedAcks = true
+		}
+		if err := s.kvsDeleteTxn(tx, idx, structs.EventPayloadPrefix+old.ID); err != nil {
+			return err
+		}
+	}
+	if prunedAcks {
+		if err := tx.Insert("index", &IndexEntry{"durable-event-acks", idx}); err != nil {
+			return fmt.Errorf("failed updating index: %s", err)
+		}
+	}
+
+	// Insert the new event.
+	event.CreateIndex = idx
+	event.ModifyIndex = idx
+	if err := tx.Insert("durable-events", event); err != nil {
+		return fmt.Errorf("failed inserting durable event: %s", err)
+	}
+	if err := tx.Insert("index", &IndexEntry{"durable-events", idx}); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+
+	tx.Commit()
+	return nil
+}
+
+// DurableEventAck records that a node has processed a durable event. Acks for
+// events we don't know about, which may have been pruned, are silently
+// dropped, and repeated acks are a no-op.
+func (s *Store) DurableEventAck(idx uint64, ack *structs.DurableEventAck) error {
+	tx := s.db.Txn(true)
+	defer tx.Abort()
+
+	event, err := tx.First("durable-events", "id", ack.EventID)
+	if err != nil {
+		return fmt.Errorf("failed durable event lookup: %s", err)
+	}
+	if event == nil {
+		return nil
+	}
+	existing, err := tx.First("durable-event-acks", "id", ack.EventID, ack.Node)
+	if err != nil {
+		return fmt.Errorf("failed durable event ack lookup: %s", err)
+	}
+	if existing != nil {
+		return nil
+	}
+
+	ack.CreateIndex = idx
+	ack.ModifyIndex = idx
+	if err := tx.Insert("durable-event-acks", ack); err != nil {
+		return fmt.Errorf("failed inserting durable event ack: %s", err)
+	}
+	if err := tx.Insert("index", &IndexEntry{"durable-event-acks", idx}); err != nil {
+		return fmt.Errorf("failed updating index: %s", err)
+	}
+
+	tx.Commit()
+	return nil
+}
+
+// DurableEvents returns all the durable events, in the order they were
+// created, along with all their acknowledgements.
+func (s *Store) DurableEvents(ws memdb.WatchSet) (uint64, structs.DurableEvents, structs.DurableEventAcks, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	// Get the table index.
+	idx := maxIndexTxn(tx, "durable-events", "durable-event-acks")
+
+	// Pull all the events.
+	iter, err := tx.Get("durable-events", "id")
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed durable event lookup: %s", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	var events structs.DurableEvents
+	for event := iter.Next(); event != nil; event = iter.Next() {
+		events = append(events, event.(*structs.DurableEvent))
+	}
+	sort.Sort(durableEventsByIndex(events))
+
+	// Pull all the acks.
+	iter, err = tx.Get("durable-event-acks", "id")
+	if err != nil {
+		return 0, nil, nil, fmt.Errorf("failed durable event ack lookup: %s", err)
+	}
+	ws.Add(iter.WatchCh())
+
+	var acks structs.DurableEventAcks
+	for ack := iter.Next(); ack != nil; ack = iter.Next() {
+		acks = append(acks, ack.(*structs.DurableEventAck))
+	}
+	return idx, events, acks, nil
+}
+
+// durableEventsByIndex sorts durable events by the order they were created.
+type durableEventsByIndex structs.DurableEvents
+
+func (s durableEventsByIndex) Len() int      { return len(s) }
+func (s durableEventsByIndex) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s durableEventsByIndex) Less(i, j int) bool {
+	return s[i].CreateIndex < s[j].CreateIndex
+}
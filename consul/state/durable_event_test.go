@@ -0,0 +1,220 @@
+package state
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/consul/structs"
+	"github.com/hashicorp/go-memdb"
+)
+
+func testDurableEvent(name string, created time.Time, retention time.Duration) *structs.DurableEvent {
+	return &structs.DurableEvent{
+		ID:         testUUID(),
+		Name:       name,
+		Payload:    []byte(name),
+		CreateTime: created,
+		Expires:    created.Add(retention),
+	}
+}
+
+func TestStateStore_DurableEvents(t *testing.T) {
+	s := testStateStore(t)
+
+	// Querying with no results returns nil.
+	ws := memdb.NewWatchSet()
+	idx, events, acks, err := s.DurableEvents(ws)
+	if idx != 0 || events != nil || acks != nil || err != nil {
+		t.Fatalf("bad: %d %v %v %v", idx, events, acks, err)
+	}
+
+	// Events need an ID.
+	now := time.Now()
+	bad := testDurableEvent("nope", now, time.Hour)
+	bad.ID = ""
+	if err := s.DurableEventCreate(1, bad); err == nil ||
+		!strings.Contains(err.Error(), "Missing durable event ID") {
+		t.Fatalf("bad: %v", err)
+	}
+	if watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	// Create a couple of events.
+	e1 := testDurableEvent("deploy", now, time.Hour)
+	if err := s.DurableEventCreate(2, e1); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+	e2 := testDurableEvent("restart", now.Add(time.Minute), time.Hour)
+	if err := s.DurableEventCreate(3, e2); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Creating the same event again should fail.
+	if err := s.DurableEventCreate(4, e1); err == nil ||
+		!strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("bad: %v", err)
+	}
+
+	// Ack the first event, twice.
+	ws = memdb.NewWatchSet()
+	if _, _, _, err := s.DurableEvents(ws); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.DurableEventAck(5, &structs.DurableEventAck{EventID: e1.ID, Node: "node1"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+	if err := s.DurableEventAck(6, &structs.DurableEventAck{EventID: e1.ID, Node: "node1"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Acks for unknown events are dropped.
+	if err := s.DurableEventAck(7, &structs.DurableEventAck{EventID: testUUID(), Node: "node1"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Read everything back.
+	idx, events, acks, err = s.DurableEvents(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if idx != 5 {
+		t.Fatalf("bad index: %d", idx)
+	}
+	if len(events) != 2 || events[0].ID != e1.ID || events[1].ID != e2.ID ||
+		events[0].CreateIndex != 2 || events[1].CreateIndex != 3 {
+		t.Fatalf("bad: %v", events)
+	}
+	expected := structs.DurableEventAcks{
+		&structs.DurableEventAck{
+			EventID: e1.ID,
+			Node:    "node1",
+			RaftIndex: structs.RaftIndex{
+				CreateIndex: 5,
+				ModifyIndex: 5,
+			},
+		},
+	}
+	if !reflect.DeepEqual(acks, expected) {
+		t.Fatalf("bad: %v", acks)
+	}
+
+	// Store a large payload for the first event, and one for an event
+	// that isn't durable.
+	for _, key := range []string{structs.EventPayloadPrefix + e1.ID, structs.EventPayloadPrefix + "other"} {
+		if err := s.KVSSet(7, &structs.DirEntry{Key: key, Value: []byte("payload")}); err != nil {
+			t.Fatalf("err: %s", err)
+		}
+	}
+
+	// An event created after the first two expire should prune them,
+	// along with their acks and payloads.
+	e3 := testDurableEvent("deploy", now.Add(2*time.Hour), time.Hour)
+	if err := s.DurableEventCreate(8, e3); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	idx, events, acks, err = s.DurableEvents(nil)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if idx != 8 {
+		t.Fatalf("bad index: %d", idx)
+	}
+	if len(events) != 1 || events[0].ID != e3.ID || len(acks) != 0 {
+		t.Fatalf("bad: %v %v", events, acks)
+	}
+	if idx := s.maxIndex("durable-event-acks"); idx != 8 {
+		t.Fatalf("bad index: %d", idx)
+	}
+	if _, entry, err := s.KVSGet(nil, structs.EventPayloadPrefix+e1.ID); err != nil || entry != nil {
+		t.Fatalf("bad: %v %v", entry, err)
+	}
+	if _, entry, err := s.KVSGet(nil, structs.EventPayloadPrefix+"other"); err != nil || entry == nil {
+		t.Fatalf("bad: %v %v", entry, err)
+	}
+	if idx := s.maxIndex("kvs"); idx != 8 {
+		t.Fatalf("bad index: %d", idx)
+	}
+}
+
+func TestStateStore_DurableEvent_Snapshot_Restore(t *testing.T) {
+	s := testStateStore(t)
+
+	// Create an event with an ack.
+	now := time.Now()
+	e1 := testDurableEvent("deploy", now, time.Hour)
+	if err := s.DurableEventCreate(1, e1); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if err := s.DurableEventAck(2, &structs.DurableEventAck{EventID: e1.ID, Node: "node1"}); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Snapshot the events.
+	snap := s.Snapshot()
+	defer snap.Close()
+
+	// Alter the real state store.
+	if err := s.DurableEventCreate(3, testDurableEvent("restart", now, time.Hour)); err != nil {
+		t.Fatalf("err: %s", err)
+	}
+
+	// Verify the snapshot.
+	if idx := snap.LastIndex(); idx != 2 {
+		t.Fatalf("bad index: %d", idx)
+	}
+	events, err := snap.DurableEvents()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(events) != 1 || events[0].ID != e1.ID {
+		t.Fatalf("bad: %v", events)
+	}
+	acks, err := snap.DurableEventAcks()
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if len(acks) != 1 || acks[0].EventID != e1.ID || acks[0].Node != "node1" {
+		t.Fatalf("bad: %v", acks)
+	}
+
+	// Restore the values into a new state store.
+	func() {
+		s := testStateStore(t)
+		restore := s.Restore()
+		for _, event := range events {
+			if err := restore.DurableEvent(event); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+		}
+		for _, ack := range acks {
+			if err := restore.DurableEventAck(ack); err != nil {
+				t.Fatalf("err: %s", err)
+			}
+		}
+		restore.Commit()
+
+		// Read the restored events back out and verify that they match.
+		idx, res, resAcks, err := s.DurableEvents(nil)
+		if err != nil {
+			t.Fatalf("err: %s", err)
+		}
+		if idx != 2 {
+			t.Fatalf("bad index: %d", idx)
+		}
+		if !reflect.DeepEqual(res, events) {
+			t.Fatalf("bad: %#v", res)
+		}
+		if !reflect.DeepEqual(resAcks, acks) {
+			t.Fatalf("bad: %#v", resAcks)
+		}
+	}()
+}
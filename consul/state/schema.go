@@ -32,6 +32,8 @@ func stateStoreSchema() *memdb.DBSchema {
 		coordinatesTableSchema,
 		preparedQueriesTableSchema,
 		autopilotConfigTableSchema,
+		durableEventsTableSchema,
+		durableEventAcksTableSchema,
 	}
 
 	// Add the tables to the root schema
@@ -459,3 +461,55 @@ func autopilotConfigTableSchema() *memdb.TableSchema {
 		},
 	}
 }
+
+// durableEventsTableSchema returns a new table schema used for storing durable
+// user events.
+func durableEventsTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "durable-events",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "ID",
+				},
+			},
+		},
+	}
+}
+
+// durableEventAcksTableSchema returns a new table schema used for storing
+// acknowledgements of durable user events.
+func durableEventAcksTableSchema() *memdb.TableSchema {
+	return &memdb.TableSchema{
+		Name: "durable-event-acks",
+		Indexes: map[string]*memdb.IndexSchema{
+			"id": &memdb.IndexSchema{
+				Name:         "id",
+				AllowMissing: false,
+				Unique:       true,
+				Indexer: &memdb.CompoundIndex{
+					Indexes: []memdb.Indexer{
+						&memdb.UUIDFieldIndex{
+							Field: "EventID",
+						},
+						&memdb.StringFieldIndex{
+							Field:     "Node",
+							Lowercase: true,
+						},
+					},
+				},
+			},
+			"event": &memdb.IndexSchema{
+				Name:         "event",
+				AllowMissing: false,
+				Unique:       false,
+				Indexer: &memdb.UUIDFieldIndex{
+					Field: "EventID",
+				},
+			},
+		},
+	}
+}
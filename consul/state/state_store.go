@@ -175,6 +175,29 @@ func (s *Store) maxIndex(tables ...string) uint64 {
 	return maxIndexTxn(tx, tables...)
 }
 
+// LatestIndex returns the highest index that has been applied to any table in
+// the state store. If a watch set is given, it will fire when this changes.
+func (s *Store) LatestIndex(ws memdb.WatchSet) (uint64, error) {
+	tx := s.db.Txn(false)
+	defer tx.Abort()
+
+	iter, err := tx.Get("index", "id")
+	if err != nil {
+		return 0, fmt.Errorf("failed index lookup: %s", err)
+	}
+	if ws != nil {
+		ws.Add(iter.WatchCh())
+	}
+
+	var latest uint64
+	for raw := iter.Next(); raw != nil; raw = iter.Next() {
+		if idx := raw.(*IndexEntry).Value; idx > latest {
+			latest = idx
+		}
+	}
+	return latest, nil
+}
+
 // maxIndexTxn is a helper used to retrieve the highest known index
 // amongst a set of tables in the db.
 func maxIndexTxn(tx *memdb.Txn, tables ...string) uint64 {
@@ -187,6 +187,43 @@ func TestStateStore_maxIndex(t *testing.T) {
 	}
 }
 
+func TestStateStore_LatestIndex(t *testing.T) {
+	s := testStateStore(t)
+
+	ws := memdb.NewWatchSet()
+	idx, err := s.LatestIndex(ws)
+	if err != nil {
+		t.Fatalf("err: %s", err)
+	}
+	if idx != 0 {
+		t.Fatalf("bad index: %d", idx)
+	}
+
+	// Writes to any table should fire the watch and move the index.
+	testRegisterNode(t, s, 1, "foo")
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	ws = memdb.NewWatchSet()
+	testRegisterService(t, s, 2, "foo", "consul")
+	testSetKey(t, s, 3, "foo", "bar")
+	if idx, err := s.LatestIndex(ws); err != nil || idx != 3 {
+		t.Fatalf("bad index: %d, %v", idx, err)
+	}
+	if watchFired(ws) {
+		t.Fatalf("bad")
+	}
+
+	testRegisterNode(t, s, 4, "bar")
+	if !watchFired(ws) {
+		t.Fatalf("bad")
+	}
+	if idx, err := s.LatestIndex(nil); err != nil || idx != 4 {
+		t.Fatalf("bad index: %d, %v", idx, err)
+	}
+}
+
 func TestStateStore_indexUpdateMaxTxn(t *testing.T) {
 	s := testStateStore(t)
 
@@ -41,6 +41,28 @@ func (s *Status) Peers(args struct{}, reply *[]string) error {
 	return nil
 }
 
+// ReadIndex is used by followers to serve reads with the same consistency as
+// the leader. The leader captures the latest index in its state store and
+// then confirms it's still the leader before handing it out, so any follower
+// that has caught up to this index will see every write that completed before
+// the read started.
+func (s *Status) ReadIndex(args struct{}, reply *uint64) error {
+	if !s.server.IsLeader() {
+		return structs.ErrNoLeader
+	}
+
+	index, err := s.server.fsm.State().LatestIndex(nil)
+	if err != nil {
+		return err
+	}
+	if err := s.server.raft.VerifyLeader().Error(); err != nil {
+		return err
+	}
+
+	*reply = index
+	return nil
+}
+
 // Used by Autopilot to query the raft stats of the local server.
 func (s *Status) RaftStats(args struct{}, reply *structs.ServerStats) error {
 	stats := s.server.raft.Stats()
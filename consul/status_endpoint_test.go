@@ -7,6 +7,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/hashicorp/consul/consul/structs"
 	"github.com/hashicorp/consul/testrpc"
 	"github.com/hashicorp/net-rpc-msgpackrpc"
 )
@@ -65,3 +66,49 @@ func TestStatusPeers(t *testing.T) {
 		t.Fatalf("no peers: %v", peers)
 	}
 }
+
+func TestStatusReadIndex(t *testing.T) {
+	dir1, s1 := testServer(t)
+	defer os.RemoveAll(dir1)
+	defer s1.Shutdown()
+	codec1 := rpcClient(t, s1)
+	defer codec1.Close()
+
+	dir2, s2 := testServerDCBootstrap(t, "dc1", false)
+	defer os.RemoveAll(dir2)
+	defer s2.Shutdown()
+	codec2 := rpcClient(t, s2)
+	defer codec2.Close()
+
+	joinLAN(t, s2, s1)
+	testrpc.WaitForLeader(t, s1.RPC, "dc1")
+
+	arg := structs.RegisterRequest{
+		Datacenter: "dc1",
+		Node:       "foo",
+		Address:    "127.0.0.1",
+	}
+	var out struct{}
+	if err := msgpackrpc.CallWithCodec(codec1, "Catalog.Register", &arg, &out); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	_, node, err := s1.fsm.State().GetNode("foo")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// The leader should hand out an index that covers the write.
+	var index uint64
+	if err := msgpackrpc.CallWithCodec(codec1, "Status.ReadIndex", struct{}{}, &index); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if index < node.ModifyIndex {
+		t.Fatalf("bad: %d < %d", index, node.ModifyIndex)
+	}
+
+	// Followers don't hand out read indexes.
+	err = msgpackrpc.CallWithCodec(codec2, "Status.ReadIndex", struct{}{}, &index)
+	if err == nil || err.Error() != structs.ErrNoLeader.Error() {
+		t.Fatalf("bad: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+package structs
+
+import (
+	"time"
+)
+
+// EventPayloadPrefix is the KV prefix where agents store the payloads of
+// user events that are too large to gossip, keyed by the event ID. The
+// payload of a durable event is deleted when the event is pruned.
+const EventPayloadPrefix = "_event/"
+
+// DurableEvent is a user event that's recorded in the state store in addition
+// to being gossiped, so that agents that miss it can catch up on it later, and
+// so the sender can see which nodes have received it.
+type DurableEvent struct {
+	// ID is the event's ID, which is generated by the agent that fired it.
+	ID string
+
+	// Name is the name of the event.
+	Name string
+
+	// Payload is the encoded event, exactly as it was gossiped.
+	Payload []byte
+
+	// CreateTime is when the event was fired, and Expires is when its
+	// retention window ends. These are set by the leader so that pruning
+	// old events is deterministic across servers.
+	CreateTime time.Time
+	Expires    time.Time
+
+	RaftIndex
+}
+
+// DurableEvents is a list of durable events.
+type DurableEvents []*DurableEvent
+
+// DurableEventAck records that a node has processed a durable event.
+type DurableEventAck struct {
+	// EventID is the ID of the event being acknowledged.
+	EventID string
+
+	// Node is the name of the node that processed the event.
+	Node string
+
+	RaftIndex
+}
+
+// DurableEventAcks is a list of durable event acknowledgements.
+type DurableEventAcks []*DurableEventAck
+
+// DurableEventRequest is used to record a new durable event.
+type DurableEventRequest struct {
+	// Datacenter is the target this request is intended for.
+	Datacenter string
+
+	// Event is the event to record.
+	Event *DurableEvent
+
+	// WriteRequest holds the ACL token to go along with this request.
+	WriteRequest
+}
+
+// RequestDatacenter returns the datacenter for a given request.
+func (r *DurableEventRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// DurableEventAckRequest is used to acknowledge a durable event.
+type DurableEventAckRequest struct {
+	// Datacenter is the target this request is intended for.
+	Datacenter string
+
+	// Ack is the acknowledgement to record.
+	Ack DurableEventAck
+
+	// WriteRequest holds the ACL token to go along with this request.
+	WriteRequest
+}
+
+// RequestDatacenter returns the datacenter for a given request.
+func (r *DurableEventAckRequest) RequestDatacenter() string {
+	return r.Datacenter
+}
+
+// IndexedDurableEvents has the durable events that haven't expired yet, along
+// with the acknowledgements for them.
+type IndexedDurableEvents struct {
+	Events DurableEvents
+	Acks   DurableEventAcks
+	QueryMeta
+}
@@ -26,11 +26,11 @@ type AutopilotConfig struct {
 	// applicable with Raft protocol version 3 or higher.
 	ServerStabilizationTime time.Duration
 
-	// (Enterprise-only) RedundancyZoneTag is the node tag to use for separating
+	// RedundancyZoneTag is the node tag to use for separating
 	// servers into zones for redundancy. If left blank, this feature will be disabled.
 	RedundancyZoneTag string
 
-	// (Enterprise-only) DisableUpgradeMigration will disable Autopilot's upgrade migration
+	// DisableUpgradeMigration will disable Autopilot's upgrade migration
 	// strategy of waiting until enough newer-versioned servers have been added to the
 	// cluster before promoting them to voters.
 	DisableUpgradeMigration bool
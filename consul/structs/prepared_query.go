@@ -1,7 +1,7 @@
 package structs
 
-// QueryDatacenterOptions sets options about how we fail over if there are no
-// healthy nodes in the local datacenter.
+// QueryDatacenterOptions sets options about how we fail over if there are not
+// enough healthy nodes in the local datacenter.
 type QueryDatacenterOptions struct {
 	// NearestN is set to the number of remote datacenters to try, based on
 	// network coordinates.
@@ -11,6 +11,54 @@ type QueryDatacenterOptions struct {
 	// never try a datacenter multiple times, so those are subtracted from
 	// this list before proceeding.
 	Datacenters []string
+
+	// Targets is an explicit, ordered list of places to fail over to. Each
+	// target can name a datacenter, an alternative service, or both, so
+	// this can't be combined with NearestN or Datacenters.
+	Targets []QueryFailoverTarget
+
+	// MinHealthy is the number of healthy nodes a result needs before we
+	// stop failing over. If this is 0 then we only fail over when there
+	// are no healthy nodes at all.
+	MinHealthy int
+}
+
+// HealthyThreshold returns the number of healthy nodes a result must have in
+// order to satisfy the query, which is always at least one.
+func (o *QueryDatacenterOptions) HealthyThreshold() int {
+	if o.MinHealthy > 1 {
+		return o.MinHealthy
+	}
+	return 1
+}
+
+// QueryFailoverTarget is a single place to try when failing over.
+type QueryFailoverTarget struct {
+	// Datacenter is the datacenter to query. If this is blank then the
+	// local datacenter is used.
+	Datacenter string
+
+	// Service is an alternative service to query. If this is blank then
+	// the query's service is used.
+	Service string
+}
+
+// QueryTargetExplanation records what happened when a prepared query tried a
+// given target during failover.
+type QueryTargetExplanation struct {
+	// Datacenter and Service identify the target that was tried.
+	Datacenter string
+	Service    string
+
+	// Healthy is the number of healthy nodes the target returned.
+	Healthy int
+
+	// Chosen is set for the target whose results were returned.
+	Chosen bool
+
+	// Reason is a human-readable description of why the target was or
+	// wasn't chosen.
+	Reason string
 }
 
 // QueryDNSOptions controls settings when query results are served over DNS.
@@ -25,8 +73,8 @@ type ServiceQuery struct {
 	// Service is the service to query.
 	Service string
 
-	// Failover controls what we do if there are no healthy nodes in the
-	// local datacenter.
+	// Failover controls what we do if there are not enough healthy nodes
+	// in the local datacenter.
 	Failover QueryDatacenterOptions
 
 	// If OnlyPassing is true then we will only include nodes with passing
@@ -252,6 +300,10 @@ type PreparedQueryExplainResponse struct {
 	// Query has the fully-rendered query.
 	Query PreparedQuery
 
+	// Targets has the local datacenter followed by any failover targets
+	// that were tried when the query was executed for this explanation.
+	Targets []QueryTargetExplanation
+
 	// QueryMeta has freshness information about the query.
 	QueryMeta
 }
@@ -43,6 +43,8 @@ const (
 	TxnRequestType
 	AutopilotRequestType
 	AreaRequestType
+	DurableEventRequestType
+	DurableEventAckRequestType
 )
 
 const (
@@ -118,6 +120,12 @@ type QueryOptions struct {
 	// If set, the leader must verify leadership prior to
 	// servicing the request. Prevents a stale read.
 	RequireConsistent bool
+
+	// readIndexChecked is set when a follower serving the read has
+	// already caught up with the leader's read index, so the consistency
+	// check doesn't need to be repeated. It's local to the server and is
+	// never encoded.
+	readIndexChecked bool
 }
 
 // IsRead is always true for QueryOption.
@@ -133,6 +141,18 @@ func (q QueryOptions) ACLToken() string {
 	return q.Token
 }
 
+// SetReadIndexChecked records whether the server has caught up with the
+// leader's read index for this request.
+func (q *QueryOptions) SetReadIndexChecked(checked bool) {
+	q.readIndexChecked = checked
+}
+
+// ReadIndexChecked returns true if the server has caught up with the
+// leader's read index for this request.
+func (q QueryOptions) ReadIndexChecked() bool {
+	return q.readIndexChecked
+}
+
 type WriteRequest struct {
 	// Token is the ACL token ID. If not provided, the 'anonymous'
 	// token is assumed for backwards compatibility.
@@ -900,6 +920,12 @@ type EventFireRequest struct {
 	Name       string
 	Payload    []byte
 
+	// Durable asks the servers to also record the event in the state
+	// store, under the given ID, so agents can catch up on it later. This
+	// needs to be served by the leader, so AllowStale must not be set.
+	Durable bool
+	ID      string
+
 	// Not using WriteRequest so that any server can process
 	// the request. It is a bit unusual...
 	QueryOptions
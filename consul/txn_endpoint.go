@@ -93,10 +93,8 @@ func (t *Txn) Read(args *structs.TxnReadRequest, reply *structs.TxnReadResponse)
 
 	// We have to do this ourselves since we are not doing a blocking RPC.
 	t.srv.setQueryMeta(&reply.QueryMeta)
-	if args.RequireConsistent {
-		if err := t.srv.consistentRead(); err != nil {
-			return err
-		}
+	if err := t.srv.checkConsistentRead(&args.QueryOptions); err != nil {
+		return err
 	}
 
 	// Run the pre-checks before we perform the read.
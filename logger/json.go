@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// logTimeFormat is the timestamp format used by the standard library's
+// log.Logger with the log.LstdFlags flags, which all of our loggers use.
+const logTimeFormat = "2006/01/02 15:04:05"
+
+var (
+	// fieldRe matches a trailing "key=value" pair on a log message, such as
+	// the "from=<addr>" that's added to messages about remote requests.
+	fieldRe = regexp.MustCompile(`^([a-z_]+)=(\S+)$`)
+
+	// checkRe and serviceRe match the check and service IDs that log
+	// messages refer to, quoted either with single quotes or with %q.
+	checkRe   = regexp.MustCompile(`(?:^|\s)[Cc]heck (?:'([^']*)'|("(?:[^"\\]|\\.)*"))`)
+	serviceRe = regexp.MustCompile(`(?:^|\s)[Ss]ervice (?:'([^']*)'|("(?:[^"\\]|\\.)*"))`)
+)
+
+// JSONWriter is an io.Writer that converts each log line written to it into
+// a JSON object, using FormatJSON, before passing it to the underlying
+// writer.
+type JSONWriter struct {
+	Writer io.Writer
+}
+
+// Write is used to implement io.Writer
+func (w *JSONWriter) Write(p []byte) (int, error) {
+	line := append(FormatJSON(p), '\n')
+	if _, err := w.Writer.Write(line); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// FormatJSON converts a log line of the form
+//
+//	2017/06/01 12:00:00 [INFO] agent: Synced service 'redis' from=127.0.0.1
+//
+// into a JSON object with "@timestamp", "@level", "@module" and "@message"
+// keys. Trailing "key=value" pairs are moved out of the message into keys
+// of their own, and the check and service IDs mentioned in the message are
+// added as "check_id" and "service_id". Parts of the line that are missing
+// are left out, except for the timestamp, which defaults to the current
+// time.
+func FormatJSON(p []byte) []byte {
+	msg := strings.TrimRight(string(p), "\n")
+	entry := make(map[string]string)
+
+	// Pull off the timestamp.
+	ts := time.Now()
+	if len(msg) > len(logTimeFormat) {
+		if t, err := time.ParseInLocation(logTimeFormat, msg[:len(logTimeFormat)], time.Local); err == nil {
+			ts = t
+			msg = msg[len(logTimeFormat)+1:]
+		}
+	}
+	entry["@timestamp"] = ts.Format(time.RFC3339)
+
+	// Pull off the level.
+	if strings.HasPrefix(msg, "[") {
+		if i := strings.Index(msg, "] "); i > 0 {
+			entry["@level"] = strings.ToLower(msg[1:i])
+			msg = msg[i+2:]
+		}
+	}
+
+	// Pull off the subsystem, which is a single word ahead of a colon.
+	if i := strings.Index(msg, ": "); i > 0 && !strings.ContainsAny(msg[:i], " \t") {
+		entry["@module"] = msg[:i]
+		msg = msg[i+2:]
+	}
+
+	// Move any trailing key=value pairs into fields.
+	for {
+		i := strings.LastIndexAny(msg, " \t")
+		if i < 0 {
+			break
+		}
+		m := fieldRe.FindStringSubmatch(msg[i+1:])
+		if m == nil {
+			break
+		}
+		if _, ok := entry[m[1]]; !ok {
+			entry[m[1]] = m[2]
+		}
+		msg = msg[:i]
+	}
+
+	// Add the check and service that the message is about.
+	if id, ok := quotedID(checkRe, msg); ok {
+		entry["check_id"] = id
+	}
+	if id, ok := quotedID(serviceRe, msg); ok {
+		entry["service_id"] = id
+	}
+	entry["@message"] = msg
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entry); err != nil {
+		// This can't happen since the entry only holds strings.
+		return p
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n")
+}
+
+// quotedID returns the first ID in the message matched by re, unquoting it
+// if it was quoted with %q.
+func quotedID(re *regexp.Regexp, msg string) (string, bool) {
+	m := re.FindStringSubmatch(msg)
+	if m == nil {
+		return "", false
+	}
+	if m[2] != "" {
+		id, err := strconv.Unquote(m[2])
+		if err != nil {
+			return "", false
+		}
+		return id, true
+	}
+	return m[1], true
+}
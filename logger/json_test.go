@@ -0,0 +1,116 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFormatJSON(t *testing.T) {
+	ts := time.Date(2017, 6, 1, 12, 30, 45, 0, time.Local)
+	stamp := ts.Format(logTimeFormat)
+	want := ts.Format(time.RFC3339)
+
+	tests := []struct {
+		in   string
+		want map[string]string
+	}{
+		{
+			stamp + " [INFO] agent: Synced node info\n",
+			map[string]string{
+				"@timestamp": want,
+				"@level":     "info",
+				"@module":    "agent",
+				"@message":   "Synced node info",
+			},
+		},
+		{
+			stamp + " [WARN] agent: Check 'web:ping' is now critical",
+			map[string]string{
+				"@timestamp": want,
+				"@level":     "warn",
+				"@module":    "agent",
+				"@message":   "Check 'web:ping' is now critical",
+				"check_id":   "web:ping",
+			},
+		},
+		{
+			stamp + ` [INFO] agent: Check "redis \"a\"" for service "redis" has been critical for too long; deregistered service`,
+			map[string]string{
+				"@timestamp": want,
+				"@level":     "info",
+				"@module":    "agent",
+				"@message":   `Check "redis \"a\"" for service "redis" has been critical for too long; deregistered service`,
+				"check_id":   `redis "a"`,
+				"service_id": "redis",
+			},
+		},
+		{
+			stamp + " [DEBUG] http: Request GET /v1/kv/foo?index=5 (1.2ms) from=127.0.0.1:52345",
+			map[string]string{
+				"@timestamp": want,
+				"@level":     "debug",
+				"@module":    "http",
+				"@message":   "Request GET /v1/kv/foo?index=5 (1.2ms)",
+				"from":       "127.0.0.1:52345",
+			},
+		},
+		{
+			stamp + " [ERR] a message with no module: really",
+			map[string]string{
+				"@timestamp": want,
+				"@level":     "err",
+				"@message":   "a message with no module: really",
+			},
+		},
+	}
+	for _, tt := range tests {
+		var got map[string]string
+		if err := json.Unmarshal(FormatJSON([]byte(tt.in)), &got); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("got %#v want %#v", got, tt.want)
+		}
+	}
+}
+
+func TestFormatJSON_NoTimestamp(t *testing.T) {
+	var got map[string]string
+	if err := json.Unmarshal(FormatJSON([]byte("plain")), &got); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got["@message"] != "plain" {
+		t.Fatalf("bad: %#v", got)
+	}
+	if _, err := time.Parse(time.RFC3339, got["@timestamp"]); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestJSONWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := &JSONWriter{Writer: &buf}
+
+	line := []byte("[INFO] agent: hello\n")
+	n, err := w.Write(line)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if n != len(line) {
+		t.Fatalf("bad: %d", n)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if got["@message"] != "hello" || got["@module"] != "agent" {
+		t.Fatalf("bad: %#v", got)
+	}
+	if !bytes.HasSuffix(buf.Bytes(), []byte("}\n")) {
+		t.Fatalf("bad: %q", buf.String())
+	}
+}
@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rotatedTimeFormat is added to the name of rotated log files. It sorts in
+// time order, so the oldest files can be found by name.
+const rotatedTimeFormat = "20060102T150405.000000000"
+
+// LogFile is an io.Writer that writes logs to a file, rotating it once it
+// reaches a given size. Rotated files are kept alongside the log file with a
+// timestamp added to their name, and are removed once there are too many of
+// them or they get too old.
+type LogFile struct {
+	// Path is the file to write logs to.
+	Path string
+
+	// MaxBytes is the size at which the file is rotated. Zero disables
+	// rotation.
+	MaxBytes int64
+
+	// MaxAge is how long to keep rotated files for. Zero keeps them
+	// regardless of age.
+	MaxAge time.Duration
+
+	// MaxFiles is how many rotated files to keep. Zero keeps them all.
+	MaxFiles int
+
+	file *os.File
+	size int64
+	l    sync.Mutex
+}
+
+// Open opens the log file for appending, creating it if needed.
+func (f *LogFile) Open() error {
+	f.l.Lock()
+	defer f.l.Unlock()
+	return f.open()
+}
+
+// Reopen closes and opens the log file again. This picks up a new file if
+// the old one was moved aside, such as by an external log rotation tool.
+func (f *LogFile) Reopen() error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if f.file != nil {
+		f.file.Close()
+		f.file = nil
+	}
+	return f.open()
+}
+
+// Close closes the log file.
+func (f *LogFile) Close() error {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Write is used to implement io.Writer
+func (f *LogFile) Write(p []byte) (int, error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if f.MaxBytes > 0 && f.size > 0 && f.size+int64(len(p)) > f.MaxBytes {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// open opens the log file and prunes old rotated files. The lock must be
+// held.
+func (f *LogFile) open() error {
+	file, err := os.OpenFile(f.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	f.prune()
+	return nil
+}
+
+// rotate moves the current log file aside and opens a new one. The lock
+// must be held.
+func (f *LogFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	prefix, ext := f.splitPath()
+	rotated := prefix + "-" + time.Now().UTC().Format(rotatedTimeFormat) + ext
+	if err := os.Rename(f.Path, rotated); err != nil {
+		return err
+	}
+	return f.open()
+}
+
+// prune removes rotated files beyond MaxFiles or older than MaxAge. Errors
+// are ignored since there's nowhere to log them, and pruning is tried again
+// on the next rotation.
+func (f *LogFile) prune() {
+	if f.MaxFiles <= 0 && f.MaxAge <= 0 {
+		return
+	}
+
+	prefix, ext := f.splitPath()
+	matches, err := filepath.Glob(prefix + "-*" + ext)
+	if err != nil {
+		return
+	}
+	var rotated []string
+	for _, match := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(match, prefix+"-"), ext)
+		if _, err := time.Parse(rotatedTimeFormat, stamp); err == nil {
+			rotated = append(rotated, match)
+		}
+	}
+
+	// Newest first, so everything past MaxFiles is the oldest.
+	sort.Sort(sort.Reverse(sort.StringSlice(rotated)))
+	for i, path := range rotated {
+		if f.MaxFiles > 0 && i >= f.MaxFiles {
+			os.Remove(path)
+			continue
+		}
+		if f.MaxAge > 0 {
+			if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > f.MaxAge {
+				os.Remove(path)
+			}
+		}
+	}
+}
+
+// splitPath splits the log file's path into the part before the extension
+// and the extension, which rotated files are named around.
+func (f *LogFile) splitPath() (string, string) {
+	ext := filepath.Ext(f.Path)
+	return strings.TrimSuffix(f.Path, ext), ext
+}
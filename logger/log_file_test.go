@@ -0,0 +1,155 @@
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/testutil"
+)
+
+func rotatedFiles(t *testing.T, dir string) []string {
+	matches, err := filepath.Glob(filepath.Join(dir, "consul-*.log"))
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return matches
+}
+
+func TestLogFile_Write(t *testing.T) {
+	dir := testutil.TempDir(t, "logfile")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "consul.log")
+	f := &LogFile{Path: path}
+	if err := f.Open(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	f.Write([]byte("one\n"))
+	f.Write([]byte("two\n"))
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(got) != "one\ntwo\n" {
+		t.Fatalf("bad: %q", got)
+	}
+	if files := rotatedFiles(t, dir); len(files) != 0 {
+		t.Fatalf("bad: %v", files)
+	}
+}
+
+func TestLogFile_Rotate(t *testing.T) {
+	dir := testutil.TempDir(t, "logfile")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "consul.log")
+	f := &LogFile{Path: path, MaxBytes: 10, MaxFiles: 2}
+	defer f.Close()
+
+	// Each write after the first goes over the limit, so it rotates.
+	for _, line := range []string{"aaaaaaaa\n", "bbbbbbbb\n", "cccccccc\n", "dddddddd\n"} {
+		if _, err := f.Write([]byte(line)); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(got) != "dddddddd\n" {
+		t.Fatalf("bad: %q", got)
+	}
+
+	// Only the two newest rotated files should be kept.
+	files := rotatedFiles(t, dir)
+	if len(files) != 2 {
+		t.Fatalf("bad: %v", files)
+	}
+	for i, want := range []string{"bbbbbbbb\n", "cccccccc\n"} {
+		got, err := ioutil.ReadFile(files[i])
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("got %q want %q", got, want)
+		}
+	}
+}
+
+func TestLogFile_MaxAge(t *testing.T) {
+	dir := testutil.TempDir(t, "logfile")
+	defer os.RemoveAll(dir)
+
+	// Make a rotated file that's too old, and an unrelated file that
+	// shouldn't be touched.
+	old := filepath.Join(dir, "consul-20170101T000000.000000000.log")
+	other := filepath.Join(dir, "consul-other.log")
+	for _, p := range []string{old, other} {
+		if err := ioutil.WriteFile(p, []byte("old\n"), 0600); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		stamp := time.Now().Add(-2 * time.Hour)
+		if err := os.Chtimes(p, stamp, stamp); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+
+	f := &LogFile{Path: filepath.Join(dir, "consul.log"), MaxAge: time.Hour}
+	if err := f.Open(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+
+	if _, err := os.Stat(old); !os.IsNotExist(err) {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
+
+func TestLogFile_Reopen(t *testing.T) {
+	dir := testutil.TempDir(t, "logfile")
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "consul.log")
+	f := &LogFile{Path: path}
+	if err := f.Open(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer f.Close()
+	f.Write([]byte("one\n"))
+
+	// Move the file aside like an external rotation tool would, and make
+	// sure new logs end up in a new file after a reopen.
+	moved := filepath.Join(dir, "consul.log.1")
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := f.Reopen(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	f.Write([]byte("two\n"))
+
+	got, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(got) != "two\n" {
+		t.Fatalf("bad: %q", got)
+	}
+	got, err = ioutil.ReadFile(moved)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if string(got) != "one\n" {
+		t.Fatalf("bad: %q", got)
+	}
+}
@@ -21,6 +21,14 @@ type Config struct {
 
 	// SyslogFacility is the destination for syslog forwarding.
 	SyslogFacility string
+
+	// LogJSON controls whether logs are written out as JSON objects rather
+	// than plain text. This doesn't apply to syslog.
+	LogJSON bool
+
+	// LogFile, if given, also gets the logs that pass the level filter.
+	// It's opened during setup.
+	LogFile *LogFile
 }
 
 // Setup is used to perform setup of several logging objects:
@@ -31,6 +39,8 @@ type Config struct {
 //   destinations.
 // * A LogWriter provides a mean to temporarily hook logs, such as for running
 //   a command like "consul monitor".
+// * A LogFile, if configured, gets the same logs as the output after the
+//   level filter is applied.
 // * An io.Writer is provided as the sink for all logs to flow to.
 //
 // The provided ui object will get any log messages related to setting up
@@ -53,6 +63,19 @@ func Setup(config *Config, ui cli.Ui) (*logutils.LevelFilter, *GatedWriter, *Log
 		return nil, nil, nil, nil, false
 	}
 
+	// Set up the log file if one is given, and the JSON format if it's
+	// enabled. Both of these sit behind the level filter.
+	if config.LogFile != nil {
+		if err := config.LogFile.Open(); err != nil {
+			ui.Error(fmt.Sprintf("Failed to open log file: %v", err))
+			return nil, nil, nil, nil, false
+		}
+		logFilter.Writer = io.MultiWriter(logGate, config.LogFile)
+	}
+	if config.LogJSON {
+		logFilter.Writer = &JSONWriter{Writer: logFilter.Writer}
+	}
+
 	// Set up syslog if it's enabled.
 	var syslog io.Writer
 	if config.EnableSyslog {
@@ -0,0 +1,450 @@
+// The encryption utilities manage an optional envelope around the compressed
+// snapshot archive. An encrypted snapshot has the following format:
+//
+// magic      - The 8 bytes in encryptMagic, which can never start a gzip stream
+// header len - 2-byte big-endian length of the header
+// header     - JSON-encoded encryptHeader recording the cipher and base nonce
+// frames     - A sequence of sealed frames, described below
+//
+// The compressed archive is split into chunks of at most encryptChunkSize
+// bytes, each of which is sealed with AES-256-GCM into a frame:
+//
+// frame len  - 4-byte big-endian plaintext length, with the high bit set on the
+//              final frame
+// ciphertext - The sealed chunk, which is the plaintext length plus the GCM tag
+//
+// Each frame uses a nonce derived from the base nonce and the frame's sequence
+// number, and authenticates the header and its own length as additional data,
+// so reordered, truncated, or altered frames all fail to open.
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+const (
+	// encryptMagic identifies an encrypted snapshot. Unencrypted snapshots
+	// are gzip streams, which always start with 0x1f 0x8b.
+	encryptMagic = "CSNAPENC"
+
+	// encryptCipher is the only cipher we currently support.
+	encryptCipher = "aes-256-gcm"
+
+	// encryptChunkSize is the largest amount of plaintext sealed in a single
+	// frame.
+	encryptChunkSize = 64 * 1024
+
+	// encryptFinalFlag is set in the frame length of the last frame.
+	encryptFinalFlag = uint32(1 << 31)
+
+	// aesGCMTagSize is the size of the tag that AES-GCM adds to each sealed
+	// frame.
+	aesGCMTagSize = 16
+
+	// EncryptKeySize is the size of the key, in bytes, used to encrypt
+	// snapshots.
+	EncryptKeySize = 32
+)
+
+// ErrEncrypted is returned when trying to read an encrypted snapshot without
+// supplying a key.
+var ErrEncrypted = errors.New("snapshot is encrypted, a key is required to read it")
+
+// encryptHeader records how an encrypted snapshot was sealed.
+type encryptHeader struct {
+	Cipher string
+	Nonce  []byte
+}
+
+// newAEAD validates the key and returns the cipher used to seal frames.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != EncryptKeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", EncryptKeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// frameNonce returns the nonce for the frame with the given sequence number.
+func frameNonce(base []byte, seq uint64) []byte {
+	nonce := make([]byte, len(base))
+	copy(nonce, base)
+	var ctr [8]byte
+	binary.BigEndian.PutUint64(ctr[:], seq)
+	for i := range ctr {
+		nonce[len(nonce)-8+i] ^= ctr[i]
+	}
+	return nonce
+}
+
+// frameAD returns the additional data authenticated with a frame.
+func frameAD(header []byte, frameLen uint32) []byte {
+	ad := make([]byte, len(header)+4)
+	copy(ad, header)
+	binary.BigEndian.PutUint32(ad[len(header):], frameLen)
+	return ad
+}
+
+// encryptWriter seals everything written to it into frames on the
+// underlying writer. You must call Close() to write the final frame.
+type encryptWriter struct {
+	out    io.Writer
+	aead   cipher.AEAD
+	header []byte
+	nonce  []byte
+	seq    uint64
+	buf    []byte
+}
+
+// newEncryptWriter writes the envelope header to the given writer and returns
+// a writer that encrypts into it with the given key.
+func newEncryptWriter(out io.Writer, key []byte) (*encryptWriter, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	header, err := json.Marshal(&encryptHeader{
+		Cipher: encryptCipher,
+		Nonce:  nonce,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode encryption header: %v", err)
+	}
+
+	var prefix bytes.Buffer
+	prefix.WriteString(encryptMagic)
+	binary.Write(&prefix, binary.BigEndian, uint16(len(header)))
+	prefix.Write(header)
+	if _, err := out.Write(prefix.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to write encryption header: %v", err)
+	}
+
+	return &encryptWriter{
+		out:    out,
+		aead:   aead,
+		header: header,
+		nonce:  nonce,
+		buf:    make([]byte, 0, encryptChunkSize),
+	}, nil
+}
+
+// Write buffers the given data, sealing frames as chunks fill up.
+func (w *encryptWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+
+		// Only flush once there's more data coming, so the last chunk is
+		// always left for Close() to mark as final.
+		if len(w.buf) == cap(w.buf) && len(p) > 0 {
+			if err := w.flush(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+// Close seals the final frame. It does not close the underlying writer.
+func (w *encryptWriter) Close() error {
+	return w.flush(true)
+}
+
+// flush seals the buffered data into a frame.
+func (w *encryptWriter) flush(final bool) error {
+	frameLen := uint32(len(w.buf))
+	if final {
+		frameLen |= encryptFinalFlag
+	}
+
+	sealed := w.aead.Seal(nil, frameNonce(w.nonce, w.seq), w.buf, frameAD(w.header, frameLen))
+	w.seq++
+	w.buf = w.buf[:0]
+
+	var lenBuf [4]byte
+	binary.BigEndian.PutUint32(lenBuf[:], frameLen)
+	if _, err := w.out.Write(lenBuf[:]); err != nil {
+		return err
+	}
+	if _, err := w.out.Write(sealed); err != nil {
+		return err
+	}
+	return nil
+}
+
+// decryptReader opens the frames from an encrypted snapshot.
+type decryptReader struct {
+	in     io.Reader
+	aead   cipher.AEAD
+	header []byte
+	nonce  []byte
+	seq    uint64
+	buf    []byte
+	done   bool
+}
+
+// Read returns decrypted data, opening frames as needed.
+func (r *decryptReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.next(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+// next reads and opens the next frame.
+func (r *decryptReader) next() error {
+	var lenBuf [4]byte
+	if _, err := io.ReadFull(r.in, lenBuf[:]); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("failed to read encrypted snapshot: %v", err)
+	}
+	frameLen := binary.BigEndian.Uint32(lenBuf[:])
+	size := int(frameLen &^ encryptFinalFlag)
+	if size > encryptChunkSize {
+		return fmt.Errorf("encrypted snapshot frame too large: %d", size)
+	}
+
+	sealed := make([]byte, size+r.aead.Overhead())
+	if _, err := io.ReadFull(r.in, sealed); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("failed to read encrypted snapshot: %v", err)
+	}
+
+	plain, err := r.aead.Open(sealed[:0], frameNonce(r.nonce, r.seq), sealed, frameAD(r.header, frameLen))
+	if err != nil {
+		return fmt.Errorf("failed to decrypt snapshot, the key may be wrong: %v", err)
+	}
+	r.seq++
+	r.buf = plain
+
+	if frameLen&encryptFinalFlag != 0 {
+		r.done = true
+	}
+	return nil
+}
+
+// decrypt returns a reader for the compressed archive in the given snapshot.
+// Unencrypted snapshots are passed through as-is, so this is safe to call on
+// any snapshot. If the snapshot is encrypted and no key is given, this will
+// return ErrEncrypted.
+func decrypt(in io.Reader, key []byte) (io.Reader, error) {
+	br := bufio.NewReader(in)
+	encrypted, err := isEncrypted(br)
+	if err != nil {
+		return nil, err
+	}
+	if !encrypted {
+		return br, nil
+	}
+	if key == nil {
+		return nil, ErrEncrypted
+	}
+
+	if _, err := br.Discard(len(encryptMagic)); err != nil {
+		return nil, err
+	}
+	var headerLen uint16
+	if err := binary.Read(br, binary.BigEndian, &headerLen); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %v", err)
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("failed to read encryption header: %v", err)
+	}
+	var h encryptHeader
+	if err := json.Unmarshal(header, &h); err != nil {
+		return nil, fmt.Errorf("failed to decode encryption header: %v", err)
+	}
+	if h.Cipher != encryptCipher {
+		return nil, fmt.Errorf("unsupported snapshot cipher %q", h.Cipher)
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(h.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("bad nonce size in encryption header: %d", len(h.Nonce))
+	}
+
+	return &decryptReader{
+		in:     br,
+		aead:   aead,
+		header: header,
+		nonce:  h.Nonce,
+	}, nil
+}
+
+// VerifyEnvelope checks the structure of an encrypted snapshot without
+// needing its key. The header must be readable and the frames must all be
+// present, ending with the final frame. This catches truncated or garbled
+// files, but the frames themselves can only be authenticated with the key,
+// using Verify.
+func VerifyEnvelope(in io.Reader) error {
+	br := bufio.NewReader(in)
+	encrypted, err := isEncrypted(br)
+	if err != nil {
+		return err
+	}
+	if !encrypted {
+		return fmt.Errorf("snapshot is not encrypted")
+	}
+
+	if _, err := br.Discard(len(encryptMagic)); err != nil {
+		return err
+	}
+	var headerLen uint16
+	if err := binary.Read(br, binary.BigEndian, &headerLen); err != nil {
+		return fmt.Errorf("failed to read encryption header: %v", err)
+	}
+	header := make([]byte, headerLen)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("failed to read encryption header: %v", err)
+	}
+	var h encryptHeader
+	if err := json.Unmarshal(header, &h); err != nil {
+		return fmt.Errorf("failed to decode encryption header: %v", err)
+	}
+	if h.Cipher != encryptCipher {
+		return fmt.Errorf("unsupported snapshot cipher %q", h.Cipher)
+	}
+
+	// Walk the frames, skipping over their contents. GCM adds a fixed size
+	// tag to each one.
+	overhead := int64(aesGCMTagSize)
+	for {
+		var lenBuf [4]byte
+		if _, err := io.ReadFull(br, lenBuf[:]); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("failed to read encrypted snapshot: %v", err)
+		}
+		frameLen := binary.BigEndian.Uint32(lenBuf[:])
+		size := int64(frameLen &^ encryptFinalFlag)
+		if size > encryptChunkSize {
+			return fmt.Errorf("encrypted snapshot frame too large: %d", size)
+		}
+		n, err := io.CopyN(ioutil.Discard, br, size+overhead)
+		if err != nil {
+			if n < size+overhead {
+				err = io.ErrUnexpectedEOF
+			}
+			return fmt.Errorf("failed to read encrypted snapshot: %v", err)
+		}
+		if frameLen&encryptFinalFlag != 0 {
+			break
+		}
+	}
+
+	// Nothing should follow the final frame.
+	if _, err := br.ReadByte(); err != io.EOF {
+		return fmt.Errorf("unexpected data after final encrypted snapshot frame")
+	}
+	return nil
+}
+
+// isEncrypted peeks at the start of the given reader to see if it holds an
+// encrypted snapshot.
+func isEncrypted(br *bufio.Reader) (bool, error) {
+	// A short stream can't be encrypted, so let the caller run into the
+	// error when it tries to decompress it.
+	magic, err := br.Peek(len(encryptMagic))
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return string(magic) == encryptMagic, nil
+}
+
+// Encrypt copies the unencrypted snapshot from the reader to the writer,
+// encrypting it with the given key. Snapshots which are already encrypted
+// are copied as-is.
+func Encrypt(out io.Writer, in io.Reader, key []byte) error {
+	br := bufio.NewReader(in)
+	encrypted, err := isEncrypted(br)
+	if err != nil {
+		return err
+	}
+	if encrypted {
+		_, err := io.Copy(out, br)
+		return err
+	}
+
+	enc, err := newEncryptWriter(out, key)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(enc, br); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// Decrypt copies the snapshot from the reader to the writer, decrypting it
+// with the given key. Snapshots which are not encrypted are copied as-is.
+func Decrypt(out io.Writer, in io.Reader, key []byte) error {
+	dec, err := decrypt(in, key)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(out, dec)
+	return err
+}
+
+// DecodeKey decodes a base64-encoded snapshot encryption key and makes sure
+// it's the right size.
+func DecodeKey(encoded string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot encryption key: %v", err)
+	}
+	if len(key) != EncryptKeySize {
+		return nil, fmt.Errorf("snapshot encryption key must be %d bytes, got %d", EncryptKeySize, len(key))
+	}
+	return key, nil
+}
+
+// ReadKeyFile reads a base64-encoded snapshot encryption key from the given
+// file.
+func ReadKeyFile(path string) ([]byte, error) {
+	encoded, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot encryption key file: %v", err)
+	}
+	return DecodeKey(string(encoded))
+}
@@ -0,0 +1,251 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/testutil"
+)
+
+func testKey(t *testing.T) []byte {
+	key := make([]byte, EncryptKeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	return key
+}
+
+func TestEncrypt_RoundTrip(t *testing.T) {
+	key := testKey(t)
+
+	// Cover the edge cases around the chunk size.
+	for _, size := range []int{
+		0,
+		1,
+		encryptChunkSize - 1,
+		encryptChunkSize,
+		encryptChunkSize + 1,
+		3 * encryptChunkSize,
+	} {
+		data := make([]byte, size)
+		if _, err := io.ReadFull(rand.Reader, data); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+
+		var sealed bytes.Buffer
+		if err := Encrypt(&sealed, bytes.NewReader(data), key); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !strings.HasPrefix(sealed.String(), encryptMagic) {
+			t.Fatalf("size %d: missing magic", size)
+		}
+
+		// Encrypting again should leave it alone.
+		var again bytes.Buffer
+		if err := Encrypt(&again, bytes.NewReader(sealed.Bytes()), key); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !bytes.Equal(again.Bytes(), sealed.Bytes()) {
+			t.Fatalf("size %d: encrypted twice", size)
+		}
+
+		var opened bytes.Buffer
+		if err := Decrypt(&opened, &sealed, key); err != nil {
+			t.Fatalf("size %d: err: %v", size, err)
+		}
+		if !bytes.Equal(opened.Bytes(), data) {
+			t.Fatalf("size %d: data doesn't match", size)
+		}
+	}
+}
+
+func TestEncrypt_Unencrypted(t *testing.T) {
+	// Unencrypted data should pass through, with or without a key.
+	data := []byte("hello world")
+	for _, key := range [][]byte{nil, testKey(t)} {
+		var out bytes.Buffer
+		if err := Decrypt(&out, bytes.NewReader(data), key); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if !bytes.Equal(out.Bytes(), data) {
+			t.Fatalf("bad: %q", out.Bytes())
+		}
+	}
+}
+
+func TestEncrypt_Bad(t *testing.T) {
+	key := testKey(t)
+	data := make([]byte, 2*encryptChunkSize+100)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var sealed bytes.Buffer
+	if err := Encrypt(&sealed, bytes.NewReader(data), key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Bad key sizes are rejected.
+	if err := Encrypt(&bytes.Buffer{}, bytes.NewReader(data), []byte("short")); err == nil ||
+		!strings.Contains(err.Error(), "key must be") {
+		t.Fatalf("err: %v", err)
+	}
+
+	cases := map[string]struct {
+		in  []byte
+		key []byte
+		err string
+	}{
+		"no key": {
+			sealed.Bytes(),
+			nil,
+			ErrEncrypted.Error(),
+		},
+		"wrong key": {
+			sealed.Bytes(),
+			testKey(t),
+			"failed to decrypt",
+		},
+		"truncated": {
+			sealed.Bytes()[:sealed.Len()-100],
+			key,
+			"unexpected EOF",
+		},
+		"missing final frame": {
+			sealed.Bytes()[:sealed.Len()-(100+4+16)],
+			key,
+			"unexpected EOF",
+		},
+		"altered": {
+			func() []byte {
+				b := append([]byte{}, sealed.Bytes()...)
+				b[len(b)/2] ^= 0xff
+				return b
+			}(),
+			key,
+			"failed to decrypt",
+		},
+	}
+	for name, tc := range cases {
+		err := Decrypt(&bytes.Buffer{}, bytes.NewReader(tc.in), tc.key)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: err: %v", name, err)
+		}
+	}
+}
+
+func TestVerifyEnvelope(t *testing.T) {
+	data := make([]byte, 2*encryptChunkSize+100)
+	if _, err := io.ReadFull(rand.Reader, data); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	var sealed bytes.Buffer
+	if err := Encrypt(&sealed, bytes.NewReader(data), testKey(t)); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// An intact envelope checks out without the key.
+	if err := VerifyEnvelope(bytes.NewReader(sealed.Bytes())); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	cases := map[string]struct {
+		in  []byte
+		err string
+	}{
+		"unencrypted": {
+			data,
+			"not encrypted",
+		},
+		"truncated": {
+			sealed.Bytes()[:sealed.Len()-100],
+			"unexpected EOF",
+		},
+		"missing final frame": {
+			sealed.Bytes()[:sealed.Len()-(100+4+16)],
+			"unexpected EOF",
+		},
+		"trailing data": {
+			append(append([]byte{}, sealed.Bytes()...), 0),
+			"after final",
+		},
+	}
+	for name, tc := range cases {
+		err := VerifyEnvelope(bytes.NewReader(tc.in))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Fatalf("%s: err: %v", name, err)
+		}
+	}
+}
+
+func TestSnapshot_Encrypted(t *testing.T) {
+	dir := testutil.TempDir(t, "snapshot")
+	defer os.RemoveAll(dir)
+
+	// Make a Raft and populate it with some data.
+	before, _ := makeRaft(t, path.Join(dir, "before"))
+	defer before.Shutdown()
+	var expected []bytes.Buffer
+	for i := 0; i < 1024; i++ {
+		var log bytes.Buffer
+		var copy bytes.Buffer
+		both := io.MultiWriter(&log, &copy)
+		if _, err := io.CopyN(both, rand.Reader, 256); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		future := before.Apply(log.Bytes(), time.Second)
+		if err := future.Error(); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		expected = append(expected, copy)
+	}
+
+	// Take an encrypted snapshot.
+	key := testKey(t)
+	logger := log.New(os.Stdout, "", 0)
+	snap, err := New(logger, before, key)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer snap.Close()
+
+	// It can't be verified without the key.
+	if _, err := Verify(snap, nil); err != ErrEncrypted {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := snap.file.Seek(0, 0); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// It can be verified with the key.
+	if _, err := Verify(snap, key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if _, err := snap.file.Seek(0, 0); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	// Restore it to a new Raft.
+	after, fsm := makeRaft(t, path.Join(dir, "after"))
+	defer after.Shutdown()
+	if err := Restore(logger, snap, after, key); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	fsm.Lock()
+	defer fsm.Unlock()
+	if len(fsm.logs) != len(expected) {
+		t.Fatalf("bad: %d vs. %d", len(fsm.logs), len(expected))
+	}
+	for i := range fsm.logs {
+		if !bytes.Equal(fsm.logs[i], expected[i].Bytes()) {
+			t.Fatalf("bad: log %d doesn't match", i)
+		}
+	}
+}
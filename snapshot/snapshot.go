@@ -1,6 +1,7 @@
 // snapshot manages the interactions between Consul and Raft in order to take
 // and restore snapshots for disaster recovery. The internal format of a
-// snapshot is simply a tar file, as described in archive.go.
+// snapshot is simply a tar file, as described in archive.go, which may be
+// wrapped in an encryption envelope, as described in encrypt.go.
 package snapshot
 
 import (
@@ -26,8 +27,8 @@ type Snapshot struct {
 // New takes a state snapshot of the given Raft instance into a temporary file
 // and returns an object that gives access to the file as an io.Reader. You must
 // arrange to call Close() on the returned object or else you will leak a
-// temporary file.
-func New(logger *log.Logger, r *raft.Raft) (*Snapshot, error) {
+// temporary file. If a key is given, the snapshot will be encrypted with it.
+func New(logger *log.Logger, r *raft.Raft, key []byte) (*Snapshot, error) {
 	// Take the snapshot.
 	future := r.Snapshot()
 	if err := future.Error(); err != nil {
@@ -66,8 +67,17 @@ func New(logger *log.Logger, r *raft.Raft) (*Snapshot, error) {
 		}
 	}()
 
-	// Wrap the file writer in a gzip compressor.
-	compressor := gzip.NewWriter(archive)
+	// Wrap the file writer in an encrypter if we were given a key, and then
+	// in a gzip compressor.
+	var out io.Writer = archive
+	var encrypter *encryptWriter
+	if key != nil {
+		if encrypter, err = newEncryptWriter(archive, key); err != nil {
+			return nil, fmt.Errorf("failed to encrypt snapshot file: %v", err)
+		}
+		out = encrypter
+	}
+	compressor := gzip.NewWriter(out)
 
 	// Write the archive.
 	if err := write(compressor, metadata, snap); err != nil {
@@ -79,6 +89,13 @@ func New(logger *log.Logger, r *raft.Raft) (*Snapshot, error) {
 		return nil, fmt.Errorf("failed to compress snapshot file: %v", err)
 	}
 
+	// Finish the encrypted stream.
+	if encrypter != nil {
+		if err := encrypter.Close(); err != nil {
+			return nil, fmt.Errorf("failed to encrypt snapshot file: %v", err)
+		}
+	}
+
 	// Sync the compressed file and rewind it so it's ready to be streamed
 	// out by the caller.
 	if err := archive.Sync(); err != nil {
@@ -124,10 +141,15 @@ func (s *Snapshot) Close() error {
 	return os.Remove(s.file.Name())
 }
 
-// Verify takes the snapshot from the reader and verifies its contents.
-func Verify(in io.Reader) (*raft.SnapshotMeta, error) {
-	// Wrap the reader in a gzip decompressor.
-	decomp, err := gzip.NewReader(in)
+// Verify takes the snapshot from the reader and verifies its contents. A key
+// must be given if the snapshot is encrypted.
+func Verify(in io.Reader, key []byte) (*raft.SnapshotMeta, error) {
+	// Wrap the reader in a decrypter and then in a gzip decompressor.
+	plain, err := decrypt(in, key)
+	if err != nil {
+		return nil, err
+	}
+	decomp, err := gzip.NewReader(plain)
 	if err != nil {
 		return nil, fmt.Errorf("failed to decompress snapshot: %v", err)
 	}
@@ -141,13 +163,20 @@ func Verify(in io.Reader) (*raft.SnapshotMeta, error) {
 	return &metadata, nil
 }
 
-// Restore takes the snapshot from the reader and attempts to apply it to the
-// given Raft instance.
-func Restore(logger *log.Logger, in io.Reader, r *raft.Raft) error {
-	// Wrap the reader in a gzip decompressor.
-	decomp, err := gzip.NewReader(in)
+// Read takes the snapshot from the reader, verifies its contents, and writes
+// the Raft snapshot data into a temporary file which is rewound and returned
+// along with the snapshot metadata. You must arrange to close and remove the
+// returned file or else you will leak a temporary file. A key must be given if
+// the snapshot is encrypted.
+func Read(logger *log.Logger, in io.Reader, key []byte) (*os.File, *raft.SnapshotMeta, error) {
+	// Wrap the reader in a decrypter and then in a gzip decompressor.
+	plain, err := decrypt(in, key)
 	if err != nil {
-		return fmt.Errorf("failed to decompress snapshot: %v", err)
+		return nil, nil, err
+	}
+	decomp, err := gzip.NewReader(plain)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decompress snapshot: %v", err)
 	}
 	defer func() {
 		if err := decomp.Close(); err != nil {
@@ -159,9 +188,17 @@ func Restore(logger *log.Logger, in io.Reader, r *raft.Raft) error {
 	// we can avoid buffering in memory.
 	snap, err := ioutil.TempFile("", "snapshot")
 	if err != nil {
-		return fmt.Errorf("failed to create temp snapshot file: %v", err)
+		return nil, nil, fmt.Errorf("failed to create temp snapshot file: %v", err)
 	}
+
+	// If anything goes wrong after this point, we will attempt to clean up
+	// the temp file. The happy path will disarm this.
+	var keep bool
 	defer func() {
+		if keep {
+			return
+		}
+
 		if err := snap.Close(); err != nil {
 			logger.Printf("[ERR] snapshot: Failed to close temp snapshot: %v", err)
 		}
@@ -173,19 +210,39 @@ func Restore(logger *log.Logger, in io.Reader, r *raft.Raft) error {
 	// Read the archive.
 	var metadata raft.SnapshotMeta
 	if err := read(decomp, &metadata, snap); err != nil {
-		return fmt.Errorf("failed to read snapshot file: %v", err)
+		return nil, nil, fmt.Errorf("failed to read snapshot file: %v", err)
 	}
 
 	// Sync and rewind the file so it's ready to be read again.
 	if err := snap.Sync(); err != nil {
-		return fmt.Errorf("failed to sync temp snapshot: %v", err)
+		return nil, nil, fmt.Errorf("failed to sync temp snapshot: %v", err)
 	}
 	if _, err := snap.Seek(0, 0); err != nil {
-		return fmt.Errorf("failed to rewind temp snapshot: %v", err)
+		return nil, nil, fmt.Errorf("failed to rewind temp snapshot: %v", err)
+	}
+
+	keep = true
+	return snap, &metadata, nil
+}
+
+// Restore takes the snapshot from the reader and attempts to apply it to the
+// given Raft instance. A key must be given if the snapshot is encrypted.
+func Restore(logger *log.Logger, in io.Reader, r *raft.Raft, key []byte) error {
+	snap, metadata, err := Read(logger, in, key)
+	if err != nil {
+		return err
 	}
+	defer func() {
+		if err := snap.Close(); err != nil {
+			logger.Printf("[ERR] snapshot: Failed to close temp snapshot: %v", err)
+		}
+		if err := os.Remove(snap.Name()); err != nil {
+			logger.Printf("[ERR] snapshot: Failed to clean up temp snapshot: %v", err)
+		}
+	}()
 
 	// Feed the snapshot into Raft.
-	if err := r.Restore(&metadata, snap, 60*time.Second); err != nil {
+	if err := r.Restore(metadata, snap, 60*time.Second); err != nil {
 		return fmt.Errorf("Raft error when restoring snapshot: %v", err)
 	}
 
@@ -151,14 +151,14 @@ func TestSnapshot(t *testing.T) {
 
 	// Take a snapshot.
 	logger := log.New(os.Stdout, "", 0)
-	snap, err := New(logger, before)
+	snap, err := New(logger, before, nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
 	defer snap.Close()
 
 	// Verify the snapshot. We have to rewind it after for the restore.
-	metadata, err := Verify(snap)
+	metadata, err := Verify(snap, nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -192,7 +192,7 @@ func TestSnapshot(t *testing.T) {
 	}
 
 	// Restore the snapshot.
-	if err := Restore(logger, snap, after); err != nil {
+	if err := Restore(logger, snap, after, nil); err != nil {
 		t.Fatalf("err: %v", err)
 	}
 
@@ -228,7 +228,7 @@ func TestSnapshot_Nil(t *testing.T) {
 
 func TestSnapshot_BadVerify(t *testing.T) {
 	buf := bytes.NewBuffer([]byte("nope"))
-	_, err := Verify(buf)
+	_, err := Verify(buf, nil)
 	if err == nil || !strings.Contains(err.Error(), "unexpected EOF") {
 		t.Fatalf("err: %v", err)
 	}
@@ -254,7 +254,7 @@ func TestSnapshot_BadRestore(t *testing.T) {
 
 	// Take a snapshot.
 	logger := log.New(os.Stdout, "", 0)
-	snap, err := New(logger, before)
+	snap, err := New(logger, before, nil)
 	if err != nil {
 		t.Fatalf("err: %v", err)
 	}
@@ -282,7 +282,7 @@ func TestSnapshot_BadRestore(t *testing.T) {
 
 	// Attempt to restore a truncated version of the snapshot. This is
 	// expected to fail.
-	err = Restore(logger, io.LimitReader(snap, 512), after)
+	err = Restore(logger, io.LimitReader(snap, 512), after, nil)
 	if err == nil || !strings.Contains(err.Error(), "unexpected EOF") {
 		t.Fatalf("err: %v", err)
 	}
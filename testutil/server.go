@@ -78,6 +78,7 @@ type TestServerConfig struct {
 	ACLDatacenter       string                 `json:"acl_datacenter,omitempty"`
 	ACLDefaultPolicy    string                 `json:"acl_default_policy,omitempty"`
 	ACLEnforceVersion8  bool                   `json:"acl_enforce_version_8"`
+	EnableDebug         bool                   `json:"enable_debug,omitempty"`
 	Encrypt             string                 `json:"encrypt,omitempty"`
 	CAFile              string                 `json:"ca_file,omitempty"`
 	CertFile            string                 `json:"cert_file,omitempty"`
@@ -235,23 +235,29 @@ func (c *Config) OutgoingTLSWrapper() (DCWrapper, error) {
 		return nil, nil
 	}
 
+	return c.wrapper(func() *tls.Config { return tlsConfig }), nil
+}
+
+// wrapper returns a DCWrapper that wraps connections using the TLS
+// configuration returned by getConfig at the time each connection is made.
+func (c *Config) wrapper(getConfig func() *tls.Config) DCWrapper {
 	// Strip the trailing '.' from the domain if any
 	domain := strings.TrimSuffix(c.Domain, ".")
 
 	wrapper := func(dc string, c net.Conn) (net.Conn, error) {
-		return WrapTLSClient(c, tlsConfig)
+		return WrapTLSClient(c, getConfig())
 	}
 
 	// Generate the wrapper based on hostname verification
 	if c.VerifyServerHostname {
 		wrapper = func(dc string, conn net.Conn) (net.Conn, error) {
-			conf := clone(tlsConfig)
+			conf := clone(getConfig())
 			conf.ServerName = "server." + dc + "." + domain
 			return WrapTLSClient(conn, conf)
 		}
 	}
 
-	return wrapper, nil
+	return wrapper
 }
 
 // SpecificDC is used to invoke a static datacenter
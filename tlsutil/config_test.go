@@ -221,13 +221,17 @@ func TestConfig_OutgoingTLS_TLSMinVersion(t *testing.T) {
 }
 
 func startTLSServer(config *Config) (net.Conn, chan error) {
-	errc := make(chan error, 1)
-
 	tlsConfigServer, err := config.IncomingTLSConfig()
 	if err != nil {
+		errc := make(chan error, 1)
 		errc <- err
 		return nil, errc
 	}
+	return startTLSServerWithConfig(tlsConfigServer)
+}
+
+func startTLSServerWithConfig(tlsConfigServer *tls.Config) (net.Conn, chan error) {
+	errc := make(chan error, 1)
 
 	client, server := net.Pipe()
 
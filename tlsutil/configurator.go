@@ -0,0 +1,82 @@
+package tlsutil
+
+import (
+	"crypto/tls"
+	"sync"
+	"sync/atomic"
+)
+
+// Configurator hands out TLS configurations and wrappers built from a
+// Config that always use the most recently loaded certificates and CAs.
+// Calling Reload re-reads the files referenced by the Config, so rotated
+// material is picked up by new handshakes while connections that are
+// already established are left alone.
+type Configurator struct {
+	config *Config
+
+	// incoming and outgoing hold the *tls.Config built from the last
+	// successful load. outgoing holds a nil *tls.Config if outgoing TLS
+	// is disabled.
+	incoming atomic.Value
+	outgoing atomic.Value
+
+	// reloadLock serializes calls to Reload.
+	reloadLock sync.Mutex
+}
+
+// NewConfigurator loads the TLS material referenced by the given config
+// and returns a Configurator serving it.
+func NewConfigurator(config *Config) (*Configurator, error) {
+	c := &Configurator{config: config}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload re-reads the CA, certificate and key files. If any of them fail
+// to load then the previously loaded material stays in use and an error
+// is returned.
+func (c *Configurator) Reload() error {
+	c.reloadLock.Lock()
+	defer c.reloadLock.Unlock()
+
+	incoming, err := c.config.IncomingTLSConfig()
+	if err != nil {
+		return err
+	}
+	outgoing, err := c.config.OutgoingTLSConfig()
+	if err != nil {
+		return err
+	}
+
+	c.incoming.Store(incoming)
+	c.outgoing.Store(outgoing)
+	return nil
+}
+
+// IncomingTLSConfig returns a TLS configuration for incoming requests that
+// looks up the current certificate and client CAs on every handshake.
+func (c *Configurator) IncomingTLSConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return c.incoming.Load().(*tls.Config), nil
+		},
+	}
+}
+
+// OutgoingTLSConfig returns the TLS configuration currently used for
+// outgoing requests, or nil if outgoing TLS is disabled.
+func (c *Configurator) OutgoingTLSConfig() *tls.Config {
+	return c.outgoing.Load().(*tls.Config)
+}
+
+// OutgoingTLSWrapper returns a DCWrapper that wraps each new connection
+// using the current outgoing configuration. It returns nil if outgoing
+// TLS is disabled.
+func (c *Configurator) OutgoingTLSWrapper() DCWrapper {
+	if c.OutgoingTLSConfig() == nil {
+		return nil
+	}
+	return c.config.wrapper(c.OutgoingTLSConfig)
+}
@@ -0,0 +1,142 @@
+package tlsutil
+
+import (
+	"bytes"
+	"crypto/tls"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// copyTLSFiles copies the given CA, certificate and key into dir using
+// fixed names, so a Config pointing at dir can be reloaded in place.
+func copyTLSFiles(t *testing.T, dir, ca, cert, key string) {
+	for src, dst := range map[string]string{
+		ca:   "ca.pem",
+		cert: "cert.pem",
+		key:  "key.pem",
+	} {
+		data, err := ioutil.ReadFile(src)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, dst), data, 0600); err != nil {
+			t.Fatalf("err: %v", err)
+		}
+	}
+}
+
+func TestConfigurator_Reload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsutil")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	copyTLSFiles(t, dir, "../test/ca/root.cer", "../test/key/ourdomain.cer", "../test/key/ourdomain.key")
+	conf := &Config{
+		VerifyIncoming: true,
+		VerifyOutgoing: true,
+		CAFile:         filepath.Join(dir, "ca.pem"),
+		CertFile:       filepath.Join(dir, "cert.pem"),
+		KeyFile:        filepath.Join(dir, "key.pem"),
+	}
+	c, err := NewConfigurator(conf)
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+
+	current := func() *tls.Config {
+		tlsConf, err := c.IncomingTLSConfig().GetConfigForClient(nil)
+		if err != nil {
+			t.Fatalf("err: %v", err)
+		}
+		return tlsConf
+	}
+	before := current()
+	outBefore := c.OutgoingTLSConfig()
+	if outBefore == nil {
+		t.Fatalf("expected outgoing config")
+	}
+
+	// Rotate the files on disk and reload.
+	copyTLSFiles(t, dir, "../test/hostname/CertAuth.crt", "../test/hostname/Alice.crt", "../test/hostname/Alice.key")
+	if err := c.Reload(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	after := current()
+	if bytes.Equal(before.Certificates[0].Certificate[0], after.Certificates[0].Certificate[0]) {
+		t.Fatalf("incoming certificate was not reloaded")
+	}
+	if bytes.Equal(before.ClientCAs.Subjects()[0], after.ClientCAs.Subjects()[0]) {
+		t.Fatalf("client CAs were not reloaded")
+	}
+	outAfter := c.OutgoingTLSConfig()
+	if bytes.Equal(outBefore.RootCAs.Subjects()[0], outAfter.RootCAs.Subjects()[0]) {
+		t.Fatalf("root CAs were not reloaded")
+	}
+
+	// A broken key should fail the reload and keep the old material.
+	if err := ioutil.WriteFile(conf.KeyFile, []byte("nope"), 0600); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := c.Reload(); err == nil {
+		t.Fatalf("expected error")
+	}
+	if current() != after || c.OutgoingTLSConfig() != outAfter {
+		t.Fatalf("failed reload should keep the previous config")
+	}
+}
+
+func TestConfigurator_Reload_Handshake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tlsutil")
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Start out with a certificate that isn't valid for the server name.
+	copyTLSFiles(t, dir, "../test/hostname/CertAuth.crt", "../test/key/ourdomain.cer", "../test/key/ourdomain.key")
+	c, err := NewConfigurator(&Config{
+		CAFile:               filepath.Join(dir, "ca.pem"),
+		CertFile:             filepath.Join(dir, "cert.pem"),
+		KeyFile:              filepath.Join(dir, "key.pem"),
+		VerifyServerHostname: true,
+		Domain:               "consul",
+	})
+	if err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	incoming := c.IncomingTLSConfig()
+	wrap := c.OutgoingTLSWrapper()
+	if wrap == nil {
+		t.Fatalf("expected wrapper")
+	}
+
+	handshake := func() error {
+		client, errc := startTLSServerWithConfig(incoming)
+		tlsClient, err := wrap("dc1", client)
+		if err != nil {
+			return err
+		}
+		defer tlsClient.Close()
+		if err := tlsClient.(*tls.Conn).Handshake(); err != nil {
+			return err
+		}
+		return <-errc
+	}
+	if err := handshake(); err == nil {
+		t.Fatalf("expected handshake error")
+	}
+
+	// After rotating in a valid certificate the same listener config and
+	// wrapper should succeed.
+	copyTLSFiles(t, dir, "../test/hostname/CertAuth.crt", "../test/hostname/Alice.crt", "../test/hostname/Alice.key")
+	if err := c.Reload(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+	if err := handshake(); err != nil {
+		t.Fatalf("err: %v", err)
+	}
+}
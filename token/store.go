@@ -0,0 +1,74 @@
+package token
+
+import (
+	"sync"
+)
+
+// Store holds the ACL tokens used by an agent. The tokens can be updated
+// while the agent is running, so the store should be passed around and
+// consulted whenever a token is needed rather than saving the results.
+type Store struct {
+	// userToken is used for requests that don't supply a token of their
+	// own, such as DNS queries and HTTP requests without a token.
+	userToken string
+
+	// agentToken is used for the agent's own internal operations, such
+	// as registering itself with the catalog and anti-entropy syncs.
+	agentToken string
+
+	// aclReplicationToken is used by servers to replicate ACLs from the
+	// ACL datacenter.
+	aclReplicationToken string
+
+	l sync.RWMutex
+}
+
+// UpdateUserToken replaces the current user token in the store.
+func (t *Store) UpdateUserToken(token string) {
+	t.l.Lock()
+	t.userToken = token
+	t.l.Unlock()
+}
+
+// UpdateAgentToken replaces the current agent token in the store.
+func (t *Store) UpdateAgentToken(token string) {
+	t.l.Lock()
+	t.agentToken = token
+	t.l.Unlock()
+}
+
+// UpdateACLReplicationToken replaces the current ACL replication token in
+// the store.
+func (t *Store) UpdateACLReplicationToken(token string) {
+	t.l.Lock()
+	t.aclReplicationToken = token
+	t.l.Unlock()
+}
+
+// UserToken returns the best token to use for user operations.
+func (t *Store) UserToken() string {
+	t.l.RLock()
+	defer t.l.RUnlock()
+
+	return t.userToken
+}
+
+// AgentToken returns the best token to use for internal agent operations,
+// falling back to the user token if no agent token is set.
+func (t *Store) AgentToken() string {
+	t.l.RLock()
+	defer t.l.RUnlock()
+
+	if t.agentToken != "" {
+		return t.agentToken
+	}
+	return t.userToken
+}
+
+// ACLReplicationToken returns the ACL replication token.
+func (t *Store) ACLReplicationToken() string {
+	t.l.RLock()
+	defer t.l.RUnlock()
+
+	return t.aclReplicationToken
+}
@@ -0,0 +1,53 @@
+package token
+
+import (
+	"testing"
+)
+
+func TestStore_RegularTokens(t *testing.T) {
+	s := new(Store)
+
+	// Everything should be empty to start.
+	if got := s.UserToken(); got != "" {
+		t.Fatalf("bad: %s", got)
+	}
+	if got := s.AgentToken(); got != "" {
+		t.Fatalf("bad: %s", got)
+	}
+	if got := s.ACLReplicationToken(); got != "" {
+		t.Fatalf("bad: %s", got)
+	}
+
+	// The replication token stands alone.
+	s.UpdateACLReplicationToken("repl")
+	if got := s.ACLReplicationToken(); got != "repl" {
+		t.Fatalf("bad: %s", got)
+	}
+	if got := s.AgentToken(); got != "" {
+		t.Fatalf("bad: %s", got)
+	}
+
+	// The agent token falls back to the user token.
+	s.UpdateUserToken("user")
+	if got := s.UserToken(); got != "user" {
+		t.Fatalf("bad: %s", got)
+	}
+	if got := s.AgentToken(); got != "user" {
+		t.Fatalf("bad: %s", got)
+	}
+
+	// An agent token takes over when it's set.
+	s.UpdateAgentToken("agent")
+	if got := s.UserToken(); got != "user" {
+		t.Fatalf("bad: %s", got)
+	}
+	if got := s.AgentToken(); got != "agent" {
+		t.Fatalf("bad: %s", got)
+	}
+
+	// Clearing the agent token goes back to the user token.
+	s.UpdateAgentToken("")
+	if got := s.AgentToken(); got != "user" {
+		t.Fatalf("bad: %s", got)
+	}
+}